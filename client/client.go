@@ -0,0 +1,318 @@
+// Package client is a Go client for the job queue HTTP API
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"job-queue/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Aliases for the wire types so callers outside this module can name them
+type (
+	Job              = models.Job
+	JobStatus        = models.JobStatus
+	JobAttempt       = models.JobAttempt
+	DeadLetterJob    = models.DeadLetterJob
+	CreateJobRequest = models.CreateJobRequest
+)
+
+// StringPayload returns a CreateJobRequest payload holding a plain string
+func StringPayload(payload string) json.RawMessage {
+	return models.StringPayload(payload)
+}
+
+var (
+	ErrDuplicateJob = errors.New("duplicate idempotency key")
+	ErrRateLimited  = errors.New("rate limit exceeded")
+	ErrJobNotFound  = errors.New("job not found")
+)
+
+// APIError is returned for any non-success response without a more specific error. Code is
+// the error code of a JSON error response, such as not_found, and empty for a plain text one.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("job queue API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// RateLimitError is returned on 429 and carries the server's Retry-After hint
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// Config holds client settings
+type Config struct {
+	// BaseURL is the API root, e.g. http://localhost:8080
+	BaseURL string
+	// HTTPClient is used for all requests; http.DefaultClient when nil
+	HTTPClient *http.Client
+	// APIKey is sent as a bearer token when set
+	APIKey string
+}
+
+// Client talks to the job queue HTTP API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+}
+
+// NewClient creates a new client for the API at baseURL
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	return NewClientWithConfig(Config{BaseURL: baseURL, HTTPClient: httpClient})
+}
+
+// NewClientWithConfig creates a new client with explicit settings
+func NewClientWithConfig(cfg Config) *Client {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		httpClient: httpClient,
+		apiKey:     cfg.APIKey,
+	}
+}
+
+// CreateJob submits a job. created is false when the idempotency key matched an existing job.
+func (c *Client) CreateJob(ctx context.Context, req *CreateJobRequest) (*Job, bool, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/jobs", nil, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+	case http.StatusConflict:
+		return nil, false, ErrDuplicateJob
+	default:
+		return nil, false, responseError(resp)
+	}
+
+	var job Job
+	if err := decode(resp, &job); err != nil {
+		return nil, false, err
+	}
+	return &job, resp.StatusCode == http.StatusCreated, nil
+}
+
+// GetJob fetches a job by ID
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrJobNotFound
+	default:
+		return nil, responseError(resp)
+	}
+
+	var job Job
+	if err := decode(resp, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelJob cancels a PENDING or RUNNING job and returns it. Cancelling a job that has
+// already finished returns an *APIError with status 409.
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+	resp, err := c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrJobNotFound
+	default:
+		return nil, responseError(resp)
+	}
+
+	var job Job
+	if err := decode(resp, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobsOptions filters ListJobs. At least one of Status, Statuses or Tag is required.
+type ListJobsOptions struct {
+	Status JobStatus
+	// Statuses lists jobs in any of several statuses, together with Status if set
+	Statuses []JobStatus
+	Tag      string
+	// Metadata keeps only jobs whose metadata has every given pair; it requires a status
+	Metadata map[string]string
+}
+
+// ListJobs lists jobs by status and/or tag
+func (c *Client) ListJobs(ctx context.Context, opts ListJobsOptions) ([]*Job, error) {
+	query := url.Values{}
+	if opts.Status != "" {
+		query.Add("status", string(opts.Status))
+	}
+	for _, status := range opts.Statuses {
+		query.Add("status", string(status))
+	}
+	if opts.Tag != "" {
+		query.Set("tag", opts.Tag)
+	}
+	for key, value := range opts.Metadata {
+		query.Set("meta."+key, value)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, "/jobs", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var jobs []*Job
+	if err := decode(resp, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ListDeadLetterOptions filters and pages ListDeadLetter. Zero values mean no filter and no limit.
+type ListDeadLetterOptions struct {
+	TenantID string
+	Limit    int
+	Offset   int
+}
+
+// ListDeadLetter lists dead letter entries and returns the total matching the filter
+func (c *Client) ListDeadLetter(ctx context.Context, opts ListDeadLetterOptions) ([]*DeadLetterJob, int, error) {
+	query := url.Values{}
+	if opts.TenantID != "" {
+		query.Set("tenant_id", opts.TenantID)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, "/dlq", query, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, responseError(resp)
+	}
+
+	var entries []*DeadLetterJob
+	if err := decode(resp, &entries); err != nil {
+		return nil, 0, err
+	}
+
+	total := len(entries)
+	if header := resp.Header.Get("X-Total-Count"); header != "" {
+		if n, err := strconv.Atoi(header); err == nil {
+			total = n
+		}
+	}
+	return entries, total, nil
+}
+
+// do sends a request to the API, adding the bearer token when configured
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", path, err)
+	}
+	return resp, nil
+}
+
+// decode reads a JSON response body into v
+func decode(resp *http.Response, v interface{}) error {
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// responseError converts an unexpected response into an error
+func responseError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := time.Duration(0)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(message)),
+	}
+
+	var envelope models.ErrorResponse
+	if json.Unmarshal(message, &envelope) == nil && envelope.Error.Code != "" {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+	}
+	return apiErr
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"job-queue/internal/handler"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"job-queue/internal/repository"
+	"job-queue/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestServer serves the job API routes backed by a temporary SQLite database
+func newTestServer(t *testing.T, limiter *service.RateLimiter) (*httptest.Server, *repository.SQLiteRepository) {
+	t.Helper()
+
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	metricsInstance := metrics.NewMetrics()
+	jobService := service.NewJobService(repo, limiter, metricsInstance)
+	jobHandler := handler.NewJobHandler(jobService, service.NewMetricsService(repo, repo, metricsInstance), repo)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			jobHandler.CreateJob(w, r)
+		} else {
+			jobHandler.ListJobs(w, r)
+		}
+	})
+	mux.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			jobHandler.CancelJob(w, r)
+		} else {
+			jobHandler.GetJob(w, r)
+		}
+	})
+	mux.HandleFunc("/dlq", jobHandler.GetDeadLetterQueue)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, repo
+}
+
+func TestClient_CreateAndGetJob(t *testing.T) {
+	server, _ := newTestServer(t, service.NewRateLimiter(10))
+	c := NewClient(server.URL, server.Client())
+	ctx := context.Background()
+
+	req := &CreateJobRequest{TenantID: "tenant-1", Payload: StringPayload("hello"), IdempotencyKey: "key-1", Tags: []string{"email"}, Metadata: map[string]string{"user_id": "42"}}
+	job, created, err := c.CreateJob(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if !created || job.Status != models.StatusPending {
+		t.Fatalf("expected a new pending job, got created=%v status=%s", created, job.Status)
+	}
+
+	again, created, err := c.CreateJob(ctx, req)
+	if err != nil {
+		t.Fatalf("idempotent CreateJob failed: %v", err)
+	}
+	if created || again.ID != job.ID {
+		t.Errorf("expected the existing job %s on an idempotent hit, got %s (created=%v)", job.ID, again.ID, created)
+	}
+
+	got, err := c.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if got.Payload != "hello" || len(got.Tags) != 1 || got.Tags[0] != "email" || got.Metadata["user_id"] != "42" {
+		t.Errorf("unexpected job: %+v", got)
+	}
+
+	if _, err := c.GetJob(ctx, "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+
+	jobs, err := c.ListJobs(ctx, ListJobsOptions{Tag: "email"})
+	if err != nil {
+		t.Fatalf("ListJobs failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("expected the tagged job, got %d jobs", len(jobs))
+	}
+
+	jobs, err = c.ListJobs(ctx, ListJobsOptions{Statuses: []JobStatus{models.StatusPending, models.StatusRunning}})
+	if err != nil {
+		t.Fatalf("ListJobs failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("expected the PENDING job, got %d jobs", len(jobs))
+	}
+
+	for value, want := range map[string]int{"42": 1, "43": 0} {
+		jobs, err = c.ListJobs(ctx, ListJobsOptions{Status: models.StatusPending, Metadata: map[string]string{"user_id": value}})
+		if err != nil {
+			t.Fatalf("ListJobs failed: %v", err)
+		}
+		if len(jobs) != want {
+			t.Errorf("user_id=%s: expected %d jobs, got %d", value, want, len(jobs))
+		}
+	}
+
+	var apiErr *APIError
+	if _, err := c.ListJobs(ctx, ListJobsOptions{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a 400 APIError without filters, got %v", err)
+	} else if apiErr.Code != "invalid_request" || apiErr.Message != "status or tag query parameter is required" {
+		t.Errorf("expected the decoded error envelope, got code %q message %q", apiErr.Code, apiErr.Message)
+	}
+}
+
+func TestClient_CancelJob(t *testing.T) {
+	server, _ := newTestServer(t, service.NewRateLimiter(10))
+	c := NewClient(server.URL, server.Client())
+	ctx := context.Background()
+
+	job, _, err := c.CreateJob(ctx, &CreateJobRequest{TenantID: "tenant-1", Payload: StringPayload("hello")})
+	if err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	cancelled, err := c.CancelJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("CancelJob failed: %v", err)
+	}
+	if cancelled.Status != models.StatusCancelled {
+		t.Errorf("expected status CANCELLED, got %s", cancelled.Status)
+	}
+
+	var apiErr *APIError
+	if _, err := c.CancelJob(ctx, job.ID); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("expected a 409 APIError when cancelling twice, got %v", err)
+	}
+	if _, err := c.CancelJob(ctx, "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestClient_RateLimited(t *testing.T) {
+	server, _ := newTestServer(t, service.NewRateLimiter(1))
+	c := NewClient(server.URL, server.Client())
+	ctx := context.Background()
+
+	if _, _, err := c.CreateJob(ctx, &CreateJobRequest{TenantID: "tenant-1", Payload: StringPayload("a")}); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	_, _, err := c.CreateJob(ctx, &CreateJobRequest{TenantID: "tenant-1", Payload: StringPayload("b")})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) || limitErr.RetryAfter < time.Second {
+		t.Errorf("expected a Retry-After of at least one second, got %v", err)
+	}
+}
+
+func TestClient_DuplicateJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "job creation failed: duplicate idempotency key", http.StatusConflict)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	_, _, err := c.CreateJob(context.Background(), &CreateJobRequest{TenantID: "tenant-1", Payload: StringPayload("a")})
+	if !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("expected ErrDuplicateJob, got %v", err)
+	}
+}
+
+func TestClient_ListDeadLetter(t *testing.T) {
+	server, repo := newTestServer(t, service.NewRateLimiter(10))
+	c := NewClientWithConfig(Config{BaseURL: server.URL + "/", HTTPClient: server.Client()})
+	ctx := context.Background()
+
+	for i, tenantID := range []string{"tenant-1", "tenant-1", "tenant-2"} {
+		job := &models.Job{
+			ID:         fmt.Sprintf("dlq-job-%d", i),
+			TenantID:   tenantID,
+			Payload:    "fail",
+			Status:     models.StatusFailed,
+			MaxRetries: 3,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if err := repo.MoveToDeadLetterQueue(ctx, job, "boom"); err != nil {
+			t.Fatalf("failed to move job to DLQ: %v", err)
+		}
+	}
+
+	entries, total, err := c.ListDeadLetter(ctx, ListDeadLetterOptions{TenantID: "tenant-1", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListDeadLetter failed: %v", err)
+	}
+	if len(entries) != 1 || total != 2 {
+		t.Errorf("expected 1 entry of 2, got %d of %d", len(entries), total)
+	}
+	if entries[0].TenantID != "tenant-1" || entries[0].FailureReason != "boom" {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+}
@@ -1,49 +1,152 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"job-queue/internal/config"
 	"job-queue/internal/handler"
 	"job-queue/internal/metrics"
+	"job-queue/internal/profiling"
 	"job-queue/internal/repository"
 	"job-queue/internal/service"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
 	dbPath := flag.String("db", "jobs.db", "path to SQLite database")
+	dbReadPath := flag.String("db-read", "", "path to a SQLite database opened query-only for job lookups, listings and counts, such as -db itself or a replica, empty reads from -db")
 	port := flag.String("port", "8080", "HTTP server port")
-	flag.Parse()
+	queryTimeout := flag.Duration("query-timeout", 10*time.Second, "how long a database call may take before it fails, 0 disables")
+	dbBusyTimeout := flag.Duration("db-busy-timeout", repository.DefaultBusyTimeout, "how long SQLite waits for another connection's lock before a query fails as locked")
+	dbJournalMode := flag.String("db-journal-mode", repository.DefaultJournalMode, "SQLite journal mode: "+strings.Join(repository.JournalModes, ", "))
+	dbBusyRetries := flag.Int("db-busy-retries", 3, "how many more times a write is tried when the database stays locked past the busy timeout")
+	maxPayloadBytes := flag.Int("max-payload-bytes", service.DefaultMaxPayloadBytes, "maximum job payload size in bytes")
+	compressPayloadBytes := flag.Int("compress-payload-bytes", 0, "gzip stored payloads larger than this many bytes, 0 disables")
+	idempotencyTTL := flag.Duration("idempotency-ttl", 0, "how long an idempotency key maps to its job before it can be reused, 0 keeps keys forever")
+	maxPending := flag.Int("max-pending", 0, "reject new jobs with 503 while this many jobs are PENDING across all tenants, 0 disables")
+	validateJSONPayloads := flag.Bool("validate-json-payloads", false, "reject string payloads whose content_type is JSON but that do not parse as JSON")
+	tenantLimitsPath := flag.String("tenant-limits", "", "path to a JSON file of per-tenant rate limit overrides")
+	rateLimitSweepInterval := flag.Duration("rate-limit-sweep-interval", 5*time.Minute, "how often to drop idle tenants' rate limit windows from memory, 0 disables")
+	apiKeysPath := flag.String("api-keys", "", "path to a JSON file mapping API keys to tenant IDs (empty disables authentication)")
+	privilegedKeysPath := flag.String("privileged-api-keys", "", "path to a JSON array of API keys, also listed in -api-keys, that may submit jobs with bypass_limits and pause or resume leasing")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 15*time.Second, "how long to wait for in-flight requests to finish on shutdown")
+	enableMetricsReset := flag.Bool("enable-metrics-reset", false, "serve POST /metrics/reset to zero the in-memory counters (for test environments only)")
+	corsOrigins := flag.String("cors-origins", "", "comma-separated origins allowed to call the API from a browser, * allows any (for development)")
+	snapshotInterval := flag.Duration("snapshot-interval", time.Minute, "how often to record a metrics snapshot (0 disables)")
+	pprofAddr := flag.String("pprof", "", "host:port to serve net/http/pprof on, such as localhost:6060, empty disables")
+	// Flags not given on the command line fall back to JOBQUEUE_* environment variables
+	if err := config.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	// Profiles are served on their own listener, never on the API port
+	if *pprofAddr != "" {
+		profiling.Serve(*pprofAddr)
+	}
+
+	// Initialize metrics
+	metricsInstance := metrics.NewMetrics()
 
 	// Initialize repository
-	repo, err := repository.NewSQLiteRepository(*dbPath)
+	repo, err := repository.NewSQLiteRepositoryWithOptions(*dbPath, repository.SQLiteOptions{
+		QueryTimeout:         *queryTimeout,
+		CompressPayloadBytes: *compressPayloadBytes,
+		BusyRetries:          *dbBusyRetries,
+		BusyTimeout:          *dbBusyTimeout,
+		JournalMode:          *dbJournalMode,
+		ReadPath:             *dbReadPath,
+		OnWriteUnavailable:   func(error) { metricsInstance.IncrementDBWriteErrors() },
+	})
 	if err != nil {
 		log.Fatalf("failed to initialize repository: %v", err)
 	}
 	defer repo.Close()
 
-	// Initialize metrics
-	metricsInstance := metrics.NewMetrics()
-
 	// Initialize rate limiter
-	rateLimiter := service.NewRateLimiter(5, 10) // 5 concurrent, 10 per minute
+	rateLimiter := service.NewRateLimiter(10) // 10 per minute
+	var tenantIDPrefixes map[string]string
+	if *tenantLimitsPath != "" {
+		tenantIDPrefixes, err = loadTenantLimits(*tenantLimitsPath, rateLimiter)
+		if err != nil {
+			log.Fatalf("failed to load tenant limits: %v", err)
+		}
+	}
 
 	// Initialize services
-	jobService := service.NewJobService(repo, rateLimiter, metricsInstance)
+	jobService := service.NewJobServiceWithConfig(repo, rateLimiter, metricsInstance, service.JobServiceConfig{
+		MaxPayloadBytes:      *maxPayloadBytes,
+		IdempotencyTTL:       *idempotencyTTL,
+		ValidateJSONPayloads: *validateJSONPayloads,
+		MaxPendingJobs:       *maxPending,
+		TenantIDPrefixes:     tenantIDPrefixes,
+	})
+	jobService.SetEventBus(service.NewEventBus())
+	schedulerService := service.NewSchedulerService(repo, metricsInstance)
+	metricsService := service.NewMetricsService(repo, repo, metricsInstance)
 
 	// Initialize handlers
-	jobHandler := handler.NewJobHandler(jobService, metricsInstance, repo)
+	jobHandler := handler.NewJobHandler(jobService, metricsService, repo)
+	scheduleHandler := handler.NewScheduleHandler(schedulerService)
+	healthHandler := handler.NewHealthHandler(repo)
+
+	// Authenticate requests with per-tenant API keys when a key file is configured
+	var keyStore handler.KeyStore
+	var privilegedKeys []string
+	if *apiKeysPath != "" {
+		store, err := handler.LoadKeyStore(*apiKeysPath)
+		if err != nil {
+			log.Fatalf("failed to load API keys: %v", err)
+		}
+		keyStore = store
+		log.Printf("API key authentication enabled for %d keys", len(store))
 
-	// CORS middleware - sets headers for all responses
+		if *privilegedKeysPath != "" {
+			privilegedKeys, err = handler.LoadPrivilegedKeys(*privilegedKeysPath)
+			if err != nil {
+				log.Fatalf("failed to load privileged API keys: %v", err)
+			}
+			for _, key := range privilegedKeys {
+				if _, ok := store.TenantForKey(key); !ok {
+					log.Fatalf("privileged API key is not listed in -api-keys")
+				}
+			}
+			log.Printf("%d API keys may submit jobs with bypass_limits", len(privilegedKeys))
+		}
+	} else if *privilegedKeysPath != "" {
+		log.Fatalf("-privileged-api-keys requires -api-keys")
+	}
+	authMiddleware := handler.NewAuthMiddlewareWithPrivilegedKeys(keyStore, privilegedKeys)
+
+	var allowedOrigins []string
+	for _, origin := range strings.Split(*corsOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			allowedOrigins = append(allowedOrigins, origin)
+		}
+	}
+	corsPolicy := handler.NewCORSPolicy(allowedOrigins)
+
+	// CORS middleware - sets headers for all responses and authenticates everything except preflight requests
 	corsMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
+		next = authMiddleware.Wrap(next)
 		return func(w http.ResponseWriter, r *http.Request) {
-			// Set CORS headers first
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			// Set CORS headers first; the origin is only echoed back when it is allowed
+			if origin := corsPolicy.AllowedOrigin(r.Header.Get("Origin")); origin != "" {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if !corsPolicy.AllowAll() {
+				w.Header().Add("Vary", "Origin")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, X-Next-Cursor, Retry-After")
 
 			// Handle preflight OPTIONS request
 			if r.Method == http.MethodOptions {
@@ -67,15 +170,87 @@ func main() {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))
-	mux.HandleFunc("/jobs/", corsMiddleware(jobHandler.GetJob))
+	mux.HandleFunc("/jobs/batch", corsMiddleware(jobHandler.CreateJobsBatch))
+	mux.HandleFunc("/jobs/search", corsMiddleware(jobHandler.SearchJobs))
+	mux.HandleFunc("/jobs/status", corsMiddleware(jobHandler.UpdateJobStatusBatch))
+	mux.HandleFunc("/jobs/", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			// The live SSE stream and the durable audit trail share a path; EventSource
+			// clients always ask for text/event-stream
+			if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				jobHandler.StreamJobEvents(w, r)
+			} else {
+				jobHandler.GetJobEvents(w, r)
+			}
+		} else if strings.HasSuffix(r.URL.Path, "/retries") {
+			jobHandler.GetJobRetries(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/position") {
+			jobHandler.GetJobPosition(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/cancel") {
+			jobHandler.CancelJob(w, r)
+		} else if r.Method == http.MethodDelete {
+			jobHandler.CancelJob(w, r)
+		} else if r.Method == http.MethodPatch {
+			jobHandler.UpdateJob(w, r)
+		} else {
+			jobHandler.GetJob(w, r)
+		}
+	}))
+	mux.HandleFunc("/tenants/", corsMiddleware(jobHandler.ListTenantIdempotencyKeys))
 	mux.HandleFunc("/metrics", corsMiddleware(jobHandler.GetMetrics))
+	mux.HandleFunc("/stats", corsMiddleware(jobHandler.GetStats))
+	mux.HandleFunc("/admin/pause", corsMiddleware(jobHandler.PauseQueue))
+	mux.HandleFunc("/admin/resume", corsMiddleware(jobHandler.ResumeQueue))
+	mux.HandleFunc("/metrics/history.csv", corsMiddleware(jobHandler.GetMetricsHistoryCSV))
+	if *enableMetricsReset {
+		mux.HandleFunc("/metrics/reset", corsMiddleware(jobHandler.ResetMetrics))
+	}
 	mux.HandleFunc("/dlq", corsMiddleware(jobHandler.GetDeadLetterQueue))
+	mux.HandleFunc("/dlq/summary", corsMiddleware(jobHandler.GetDeadLetterSummary))
+	mux.HandleFunc("/schedules", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			scheduleHandler.CreateSchedule(w, r)
+		} else if r.Method == http.MethodGet {
+			scheduleHandler.ListSchedules(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// The API description is public so client generators can fetch it without an API key
+	mux.HandleFunc("/openapi.json", handler.OpenAPI)
+
+	// Health probes are served without CORS since only the orchestrator calls them
+	mux.HandleFunc("/healthz", healthHandler.Healthz)
+	mux.HandleFunc("/readyz", healthHandler.Readyz)
 
 	// Start server
 	server := &http.Server{
 		Addr:    ":" + *port,
 		Handler: mux,
 	}
+	server.RegisterOnShutdown(jobHandler.CloseStreams)
+
+	// Record metrics history in the background
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if *snapshotInterval > 0 {
+		go func() {
+			if err := metricsService.Run(ctx, *snapshotInterval); err != nil && err != context.Canceled {
+				log.Printf("metrics snapshot error: %v", err)
+			}
+		}()
+	}
+
+	// Drop rate limit windows of tenants that stopped submitting, so they do not pile up in memory
+	if *rateLimitSweepInterval > 0 {
+		go func() {
+			if err := rateLimiter.RunSweeper(ctx, *rateLimitSweepInterval); err != nil && err != context.Canceled {
+				log.Printf("rate limiter sweep error: %v", err)
+			}
+		}()
+	}
 
 	// Graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -89,9 +264,58 @@ func main() {
 	}()
 
 	<-sigChan
-	log.Println("shutting down server...")
-	if err := server.Close(); err != nil {
-		log.Printf("error closing server: %v", err)
+	log.Printf("shutting down server, waiting up to %s for in-flight requests...", *shutdownTimeout)
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("graceful shutdown did not finish, closing remaining connections: %v", err)
+		if err := server.Close(); err != nil {
+			log.Printf("error closing server: %v", err)
+		}
 	}
 	log.Println("server stopped")
 }
+
+// tenantLimitsConfig is one tenant's entry in the -tenant-limits file.
+// The file is shared with the worker, which enforces max_concurrent when leasing jobs.
+type tenantLimitsConfig struct {
+	MaxConcurrent int    `json:"max_concurrent"`
+	MaxPerMinute  int    `json:"max_per_minute"`
+	IDPrefix      string `json:"id_prefix"`
+}
+
+// loadTenantLimits reads per-tenant submission rate overrides from a JSON file keyed by tenant ID,
+// and returns the job ID prefixes configured for tenants in the same file
+func loadTenantLimits(path string, rateLimiter *service.RateLimiter) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits map[string]tenantLimitsConfig
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, err
+	}
+
+	idPrefixes := make(map[string]string)
+	for tenantID, l := range limits {
+		if l.MaxConcurrent < 0 || l.MaxPerMinute < 0 {
+			return nil, fmt.Errorf("tenant %s: max_concurrent and max_per_minute must not be negative", tenantID)
+		}
+		if l.MaxPerMinute > 0 {
+			rateLimiter.SetTenantLimit(tenantID, l.MaxPerMinute)
+		}
+		if l.IDPrefix != "" {
+			if err := service.ValidateIDPrefix(l.IDPrefix); err != nil {
+				return nil, fmt.Errorf("tenant %s: %w", tenantID, err)
+			}
+			idPrefixes[tenantID] = l.IDPrefix
+		}
+	}
+
+	log.Printf("loaded rate limit overrides for %d tenants, job id prefixes for %d", len(limits), len(idPrefixes))
+	return idPrefixes, nil
+}
@@ -0,0 +1,14 @@
+//go:build chaos
+
+package main
+
+import (
+	"job-queue/internal/service"
+	"log"
+)
+
+// newChaosHandler builds the failure-injection handler selected with -handler chaos
+func newChaosHandler() (service.Handler, error) {
+	log.Printf("chaos handler enabled: jobs fail as their payload directs")
+	return service.ChaosHandler{}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !chaos
+
+package main
+
+import (
+	"fmt"
+	"job-queue/internal/service"
+)
+
+// newChaosHandler refuses -handler chaos, which is left out of builds without the chaos tag
+func newChaosHandler() (service.Handler, error) {
+	return nil, fmt.Errorf("the chaos handler is only available in worker builds with -tags chaos")
+}
@@ -2,33 +2,150 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"job-queue/internal/config"
+	"job-queue/internal/handler"
 	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"job-queue/internal/profiling"
 	"job-queue/internal/repository"
 	"job-queue/internal/service"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
 
 func main() {
 	dbPath := flag.String("db", "jobs.db", "path to SQLite database")
-	flag.Parse()
+	queryTimeout := flag.Duration("query-timeout", 10*time.Second, "how long a database call may take before it fails, 0 disables")
+	dbBusyTimeout := flag.Duration("db-busy-timeout", repository.DefaultBusyTimeout, "how long SQLite waits for another connection's lock before a query fails as locked")
+	dbJournalMode := flag.String("db-journal-mode", repository.DefaultJournalMode, "SQLite journal mode: "+strings.Join(repository.JournalModes, ", "))
+	dbBusyRetries := flag.Int("db-busy-retries", 3, "how many more times a write is tried when the database stays locked past the busy timeout")
+	keepFailedJobs := flag.Bool("keep-failed-jobs", false, "soft delete jobs that fail for good: keep them as tombstoned FAILED rows linked to their dead letter entry instead of deleting them")
+	workerID := flag.String("worker-id", "", "name recorded as leased_by on the jobs this worker leases, defaults to hostname:pid")
+	queue := flag.String("queue", models.DefaultQueue, "queue to lease jobs from")
+	tenant := flag.String("tenant", "", "only lease jobs of this tenant, empty serves every tenant")
+	leaseDuration := flag.Duration("lease", service.DefaultLeaseDuration, "how long a leased job is held before it can be reclaimed")
+	pollInterval := flag.Duration("poll", service.DefaultPollInterval, "how long to wait before polling again when no job is available")
+	longPoll := flag.Bool("long-poll", false, "wait up to -poll for jobs this process makes available, such as scheduled jobs and retries, and lease them at once")
+	reclaimInterval := flag.Duration("reclaim-interval", 30*time.Second, "how often to return jobs with expired leases to PENDING, 0 disables")
+	maxConcurrent := flag.Int("max-concurrent", service.DefaultMaxRunningPerTenant, "maximum RUNNING jobs per tenant across all workers, 0 disables")
+	tenantLimitsPath := flag.String("tenant-limits", "", "path to a JSON file of per-tenant limit overrides (max_concurrent is used)")
+	concurrency := flag.Int("concurrency", 1, "how many jobs to process at once; free slots are leased in one transaction")
+	maxConcurrentHandlers := flag.Int("max-concurrent-handlers", 0, "maximum handler calls running at once in this worker, to protect a downstream system, 0 disables")
+	fair := flag.Bool("fair", false, "lease round-robin across tenants instead of oldest job first")
+	handlerName := flag.String("handler", "noop", "how jobs are processed: noop, exec, or chaos in builds with -tags chaos")
+	simulateDelay := flag.Duration("simulate-delay", 0, "make the noop handler sleep this long per job, for demos")
+	simulateFailures := flag.Bool("simulate-failures", false, "make the noop handler fail jobs whose payload is \"fail\", for testing")
+	execAllow := flag.String("exec-allow", "", "comma-separated commands the exec handler may run")
+	jobTimeout := flag.Duration("job-timeout", 0, "maximum time per job attempt, defaults to the lease duration")
+	leaseTimeout := flag.Duration("lease-timeout", 0, "maximum time per lease attempt, after which the worker gives up and polls again, defaults to the lease duration")
+	runScheduler := flag.Bool("scheduler", false, "fire recurring schedules from this worker")
+	scheduleInterval := flag.Duration("schedule-interval", 10*time.Second, "how often to check for due schedules, 0 disables")
+	retention := flag.Duration("retention", 7*24*time.Hour, "how long to keep completed jobs, 0 disables cleanup")
+	retentionInterval := flag.Duration("retention-interval", time.Hour, "how often to fail expired jobs and purge completed and dead letter jobs past retention, 0 disables")
+	dlqRetention := flag.Duration("dlq-retention", 0, "how long to keep dead letter jobs, 0 keeps them forever")
+	dlqArchive := flag.String("dlq-archive", "", "file to append purged dead letter jobs to as JSON lines before deleting them")
+	expiredToDLQ := flag.Bool("expired-to-dlq", false, "move jobs that expire before running to the dead letter queue instead of only marking them FAILED")
+	statsdAddr := flag.String("statsd-addr", "", "StatsD host:port to push job counters to, empty disables")
+	statsdPrefix := flag.String("statsd-prefix", "jobqueue", "prefix for metric names pushed to StatsD")
+	publishResults := flag.String("publish-results", "", "file to append the outcome of every finished job to as JSON lines, a stand-in for a Kafka or NATS publisher, empty disables")
+	publishBuffer := flag.Int("publish-buffer", 100, "how many outcomes may wait to be published before workers block")
+	publishTimeout := flag.Duration("publish-timeout", service.DefaultPublishTimeout, "how long a worker blocks on a full publish buffer before giving up on the outcome")
+	adminAddr := flag.String("admin-addr", "", "host:port to serve GET /status on, reporting the jobs this worker is processing, empty disables")
+	pprofAddr := flag.String("pprof", "", "host:port to serve net/http/pprof on, such as localhost:6060, empty disables")
+	// Flags not given on the command line fall back to JOBQUEUE_* environment variables
+	if err := config.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	// Profiles are served on their own listener, never on the API port
+	if *pprofAddr != "" {
+		profiling.Serve(*pprofAddr)
+	}
+
+	// Initialize metrics
+	metricsInstance := metrics.NewMetrics()
+	if *statsdAddr != "" {
+		emitter, err := metrics.NewStatsDEmitter(*statsdAddr, *statsdPrefix)
+		if err != nil {
+			log.Fatalf("failed to configure statsd: %v", err)
+		}
+		defer emitter.Close()
+		metricsInstance.SetEmitter(emitter)
+		log.Printf("pushing metrics to statsd at %s", *statsdAddr)
+	}
 
 	// Initialize repository
-	repo, err := repository.NewSQLiteRepository(*dbPath)
+	repo, err := repository.NewSQLiteRepositoryWithOptions(*dbPath, repository.SQLiteOptions{
+		QueryTimeout:       *queryTimeout,
+		BusyRetries:        *dbBusyRetries,
+		BusyTimeout:        *dbBusyTimeout,
+		JournalMode:        *dbJournalMode,
+		KeepFailedJobs:     *keepFailedJobs,
+		OnWriteUnavailable: func(error) { metricsInstance.IncrementDBWriteErrors() },
+	})
 	if err != nil {
 		log.Fatalf("failed to initialize repository: %v", err)
 	}
 	defer repo.Close()
 
-	// Initialize metrics
-	metricsInstance := metrics.NewMetrics()
+	var tenantMaxRunning map[string]int
+	if *tenantLimitsPath != "" {
+		tenantMaxRunning, err = loadTenantMaxRunning(*tenantLimitsPath)
+		if err != nil {
+			log.Fatalf("failed to load tenant limits: %v", err)
+		}
+	}
+
+	handler, err := newHandler(*handlerName, *execAllow, *simulateDelay, *simulateFailures)
+	if err != nil {
+		log.Fatalf("failed to configure handler: %v", err)
+	}
+
+	// Publish job outcomes; when the buffer is full, workers wait for room instead of dropping them
+	var publisher service.ResultPublisher
+	if *publishResults != "" {
+		file, err := os.OpenFile(*publishResults, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatalf("failed to open -publish-results file: %v", err)
+		}
+		defer file.Close()
+		buffered := service.NewBufferedPublisher(service.NewJSONLinesPublisher(file), *publishBuffer, *publishTimeout)
+		defer buffered.Close()
+		publisher = buffered
+		log.Printf("publishing job outcomes to %s (buffer %d, timeout %s)", *publishResults, *publishBuffer, *publishTimeout)
+	}
 
 	// Initialize worker service
-	workerService := service.NewWorkerService(repo, metricsInstance)
+	workerService := service.NewWorkerServiceWithConfig(repo, metricsInstance, service.WorkerConfig{
+		Queue:                 *queue,
+		LeaseDuration:         *leaseDuration,
+		PollInterval:          *pollInterval,
+		MaxRunningPerTenant:   *maxConcurrent,
+		TenantMaxRunning:      tenantMaxRunning,
+		FairScheduling:        *fair,
+		Concurrency:           *concurrency,
+		MaxConcurrentHandlers: *maxConcurrentHandlers,
+		Handler:               handler,
+		JobTimeout:            *jobTimeout,
+		LeaseTimeout:          *leaseTimeout,
+		WorkerID:              *workerID,
+		TenantID:              *tenant,
+		LongPoll:              *longPoll,
+		Publisher:             publisher,
+	})
+
+	// Report the jobs in progress on the admin port, to diagnose a worker stuck on a job
+	if *adminAddr != "" {
+		serveAdmin(*adminAddr, workerService)
+	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -44,13 +161,134 @@ func main() {
 		cancel()
 	}()
 
+	// SIGUSR1 takes the worker out of rotation: jobs in progress finish and nothing new is
+	// leased, while the reclaimer, scheduler and janitor keep running until SIGTERM
+	drainChan := make(chan os.Signal, 1)
+	signal.Notify(drainChan, syscall.SIGUSR1)
+
+	go func() {
+		<-drainChan
+		log.Println("draining worker...")
+		workerService.Drain()
+	}()
+
+	// Return jobs orphaned by a crashed worker to PENDING before leasing, so they are not
+	// counted as RUNNING until the first reclaim tick
+	if _, err := workerService.ReclaimExpiredLeases(ctx); err != nil {
+		log.Printf("error reclaiming expired leases at startup: %v", err)
+	}
+
+	// Housekeeping runs off the lease loop, each task in its own goroutine on its own interval
+	tasks := service.NewBackgroundTasks()
+
+	// Recover jobs whose worker died mid-lease even when the lease loop is not reaching them
+	tasks.Add("reclaimer", *reclaimInterval, workerService.RunReclaimer)
+
+	// Fire recurring schedules from this worker
+	if *runScheduler {
+		schedulerService := service.NewSchedulerService(repo, metricsInstance)
+		tasks.Add("scheduler", *scheduleInterval, schedulerService.Run)
+	}
+
+	// Fail jobs that expired before running, and purge completed and dead letter jobs past their retention TTL
+	janitorService := service.NewJanitorServiceWithConfig(repo, service.JanitorConfig{
+		DoneTTL:             *retention,
+		DeadLetterTTL:       *dlqRetention,
+		DeadLetterArchive:   *dlqArchive,
+		ExpiredToDeadLetter: *expiredToDLQ,
+	})
+	log.Printf("janitor retention: completed=%s dead_letter=%s", *retention, *dlqRetention)
+	tasks.Add("janitor", *retentionInterval, janitorService.Run)
+
+	tasks.Start(ctx)
+
 	// Start processing jobs
-	leaseDuration := 30 * time.Second
-	log.Println("worker started, polling for jobs...")
+	if *tenant != "" {
+		log.Printf("dedicated worker: only leasing jobs of tenant %q", *tenant)
+	}
+	log.Printf("worker started, polling for jobs on queue %q every %s (lease %s, concurrency %d)...", *queue, *pollInterval, *leaseDuration, *concurrency)
 	
-	if err := workerService.ProcessJobs(ctx, leaseDuration); err != nil && err != context.Canceled {
+	if err := workerService.ProcessJobs(ctx); err != nil && err != context.Canceled {
 		log.Fatalf("worker error: %v", err)
 	}
 
-	log.Println("worker stopped")
+	// Let housekeeping finish its current pass before the database is closed
+	tasks.Wait()
+
+	snapshot := metricsInstance.GetSnapshot()
+	log.Printf("worker stopped, empty_leases=%d lease_errors=%d", snapshot["empty_leases"], snapshot["lease_errors"])
+}
+
+// serveAdmin serves the worker's status on addr in the background. A listener that fails is
+// logged rather than fatal, since the status is never worth stopping the worker for.
+func serveAdmin(addr string, workerService *service.WorkerService) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", handler.NewWorkerStatusHandler(workerService).Status)
+
+	go func() {
+		log.Printf("serving worker status on http://%s/status", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("admin server error: %v", err)
+		}
+	}()
+}
+
+// newHandler builds the job handler selected with -handler
+func newHandler(name, execAllow string, simulateDelay time.Duration, simulateFailures bool) (service.Handler, error) {
+	switch name {
+	case "noop":
+		if simulateDelay > 0 || simulateFailures {
+			log.Printf("simulating work: delay=%s, failures=%t", simulateDelay, simulateFailures)
+			return &service.SimulatedHandler{Delay: simulateDelay, FailOnPayload: simulateFailures}, nil
+		}
+		return service.NoopHandler{}, nil
+	case "exec":
+		var allowed []string
+		for _, command := range strings.Split(execAllow, ",") {
+			if command = strings.TrimSpace(command); command != "" {
+				allowed = append(allowed, command)
+			}
+		}
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("the exec handler requires -exec-allow")
+		}
+		log.Printf("exec handler enabled for commands: %s", strings.Join(allowed, ", "))
+		return service.NewExecHandler(allowed), nil
+	case "chaos":
+		return newChaosHandler()
+	default:
+		return nil, fmt.Errorf("unknown handler %q", name)
+	}
+}
+
+// tenantLimitsConfig is one tenant's entry in the -tenant-limits file shared with the API server
+type tenantLimitsConfig struct {
+	MaxConcurrent int `json:"max_concurrent"`
+	MaxPerMinute  int `json:"max_per_minute"`
+}
+
+// loadTenantMaxRunning reads per-tenant concurrent running overrides from a JSON file keyed by tenant ID
+func loadTenantMaxRunning(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits map[string]tenantLimitsConfig
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, err
+	}
+
+	maxRunning := make(map[string]int)
+	for tenantID, l := range limits {
+		if l.MaxConcurrent < 0 {
+			return nil, fmt.Errorf("tenant %s: max_concurrent must not be negative", tenantID)
+		}
+		if l.MaxConcurrent > 0 {
+			maxRunning[tenantID] = l.MaxConcurrent
+		}
+	}
+
+	log.Printf("loaded concurrent running overrides for %d tenants", len(maxRunning))
+	return maxRunning, nil
 }
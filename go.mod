@@ -5,4 +5,5 @@ go 1.21
 require (
 	github.com/google/uuid v1.5.0
 	github.com/mattn/go-sqlite3 v1.14.18
+	github.com/robfig/cron/v3 v3.0.1
 )
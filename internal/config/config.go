@@ -0,0 +1,51 @@
+// Package config lets every command-line flag of the job queue binaries also be set
+// through an environment variable, which is easier to manage in containers
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrefix is prepended to the upper-cased flag name to form its environment variable
+const EnvPrefix = "JOBQUEUE_"
+
+// EnvName returns the environment variable for a flag, e.g. JOBQUEUE_MAX_CONCURRENT for -max-concurrent
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Parse parses the command line into fs and then fills every flag that was not given on
+// the command line from its environment variable, so flags take precedence over the
+// environment and the environment over the defaults. Each flag's usage names its variable.
+func Parse(fs *flag.FlagSet, args []string) error {
+	fs.VisitAll(func(f *flag.Flag) {
+		f.Usage = fmt.Sprintf("%s (env %s)", f.Usage, EnvName(f.Name))
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(EnvName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, EnvName(f.Name), setErr)
+		}
+	})
+	return err
+}
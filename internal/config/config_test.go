@@ -0,0 +1,56 @@
+package config
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("max-concurrent"); got != "JOBQUEUE_MAX_CONCURRENT" {
+		t.Errorf("expected JOBQUEUE_MAX_CONCURRENT, got %s", got)
+	}
+}
+
+func TestParse_Precedence(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	db := fs.String("db", "jobs.db", "path to SQLite database")
+	port := fs.String("port", "8080", "HTTP server port")
+	lease := fs.Duration("lease", 30*time.Second, "lease duration")
+	queue := fs.String("queue", "default", "queue to lease jobs from")
+
+	t.Setenv("JOBQUEUE_DB", "/data/env.db")
+	t.Setenv("JOBQUEUE_PORT", "9090")
+	t.Setenv("JOBQUEUE_LEASE", "2m")
+
+	if err := Parse(fs, []string{"-port", "7070"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if *db != "/data/env.db" {
+		t.Errorf("expected the environment to override the default, got %s", *db)
+	}
+	if *port != "7070" {
+		t.Errorf("expected the flag to override the environment, got %s", *port)
+	}
+	if *lease != 2*time.Minute {
+		t.Errorf("expected lease 2m from the environment, got %s", *lease)
+	}
+	if *queue != "default" {
+		t.Errorf("expected the default without flag or environment, got %s", *queue)
+	}
+	if usage := fs.Lookup("db").Usage; usage != "path to SQLite database (env JOBQUEUE_DB)" {
+		t.Errorf("expected the usage to name the variable, got %q", usage)
+	}
+}
+
+func TestParse_InvalidEnv(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Duration("lease", 30*time.Second, "lease duration")
+
+	t.Setenv("JOBQUEUE_LEASE", "soon")
+
+	if err := Parse(fs, nil); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// KeyStore maps API keys to the tenant they authenticate
+type KeyStore interface {
+	TenantForKey(key string) (string, bool)
+}
+
+// StaticKeyStore is a KeyStore backed by a fixed key to tenant map
+type StaticKeyStore map[string]string
+
+// TenantForKey returns the tenant the key belongs to
+func (s StaticKeyStore) TenantForKey(key string) (string, bool) {
+	tenantID, ok := s[key]
+	return tenantID, ok
+}
+
+// LoadKeyStore reads a JSON object of API key to tenant ID from a file
+func LoadKeyStore(path string) (StaticKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	var store StaticKeyStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+
+	for key, tenantID := range store {
+		if key == "" || tenantID == "" {
+			return nil, fmt.Errorf("API keys file contains an empty key or tenant")
+		}
+	}
+
+	return store, nil
+}
+
+// LoadPrivilegedKeys reads a JSON array of the API keys allowed to submit jobs with bypass_limits
+func LoadPrivilegedKeys(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read privileged API keys: %w", err)
+	}
+
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse privileged API keys: %w", err)
+	}
+
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("privileged API keys file contains an empty key")
+		}
+	}
+
+	return keys, nil
+}
+
+type tenantContextKey struct{}
+
+type privilegedContextKey struct{}
+
+// WithTenant returns a copy of ctx carrying the authenticated tenant
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the authenticated tenant, if the request was authenticated
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenantID, ok
+}
+
+// WithPrivileged returns a copy of ctx marking the request as made with a privileged API key
+func WithPrivileged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, privilegedContextKey{}, true)
+}
+
+// IsPrivileged reports whether the request was authenticated with a privileged API key
+func IsPrivileged(ctx context.Context) bool {
+	privileged, _ := ctx.Value(privilegedContextKey{}).(bool)
+	return privileged
+}
+
+// AuthMiddleware authenticates requests with a bearer API key
+type AuthMiddleware struct {
+	store      KeyStore
+	privileged map[string]bool
+}
+
+// NewAuthMiddleware creates a new auth middleware. A nil store disables authentication.
+func NewAuthMiddleware(store KeyStore) *AuthMiddleware {
+	return NewAuthMiddlewareWithPrivilegedKeys(store, nil)
+}
+
+// NewAuthMiddlewareWithPrivilegedKeys creates an auth middleware that also marks requests
+// made with one of the privileged keys, which must be in store as well
+func NewAuthMiddlewareWithPrivilegedKeys(store KeyStore, privilegedKeys []string) *AuthMiddleware {
+	privileged := make(map[string]bool, len(privilegedKeys))
+	for _, key := range privilegedKeys {
+		privileged[key] = true
+	}
+
+	return &AuthMiddleware{
+		store:      store,
+		privileged: privileged,
+	}
+}
+
+// Wrap rejects requests without a valid API key and injects the tenant into the request context
+func (m *AuthMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	if m.store == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing API key", http.StatusUnauthorized)
+			return
+		}
+
+		tenantID, ok := m.store.TenantForKey(key)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := WithTenant(r.Context(), tenantID)
+		if m.privileged[key] {
+			ctx = WithPrivileged(ctx)
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// authorizeTenant fills in the tenant from the authenticated context and rejects a mismatch.
+// It returns false after writing the error response.
+func authorizeTenant(w http.ResponseWriter, r *http.Request, tenantID *string) bool {
+	authTenant, ok := TenantFromContext(r.Context())
+	if !ok {
+		return true
+	}
+
+	if *tenantID == "" {
+		*tenantID = authTenant
+		return true
+	}
+
+	if *tenantID != authTenant {
+		writeJSONError(w, http.StatusForbidden, codeForbidden, "tenant_id does not match the authenticated tenant")
+		return false
+	}
+
+	return true
+}
+
+// authorizeBypassLimits rejects bypass_limits unless the request was made with a privileged
+// API key. It returns false after writing the error response.
+func authorizeBypassLimits(w http.ResponseWriter, r *http.Request, bypassLimits bool) bool {
+	if !bypassLimits || IsPrivileged(r.Context()) {
+		return true
+	}
+
+	writeJSONError(w, http.StatusForbidden, codeForbidden, "bypass_limits requires a privileged API key")
+	return false
+}
+
+// authorizeAdmin rejects authenticated requests not made with a privileged API key, for
+// endpoints that affect every tenant. It returns false after writing the error response.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := TenantFromContext(r.Context()); !ok || IsPrivileged(r.Context()) {
+		return true
+	}
+
+	writeJSONError(w, http.StatusForbidden, codeForbidden, "this endpoint requires a privileged API key")
+	return false
+}
@@ -0,0 +1,426 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"job-queue/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthMiddleware_Wrap(t *testing.T) {
+	auth := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1"})
+
+	var gotTenant string
+	next := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant, _ = TenantFromContext(r.Context())
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		expected int
+	}{
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic key-1", http.StatusUnauthorized},
+		{"unknown key", "Bearer nope", http.StatusUnauthorized},
+		{"valid key", "Bearer key-1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			next(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	if gotTenant != "tenant-1" {
+		t.Errorf("expected tenant-1 in context, got %q", gotTenant)
+	}
+}
+
+func TestJobHandler_CreateJob_UsesAuthenticatedTenant(t *testing.T) {
+	h, _ := newTestHandler(t)
+	create := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1"}).Wrap(h.CreateJob)
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"payload":"hello"}`))
+	req.Header.Set("Authorization", "Bearer key-1")
+	rec := httptest.NewRecorder()
+	create(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var job struct {
+		TenantID string `json:"tenant_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	if job.TenantID != "tenant-1" {
+		t.Errorf("expected tenant-1, got %s", job.TenantID)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tenant_id":"tenant-2","payload":"hello"}`))
+	req.Header.Set("Authorization", "Bearer key-1")
+	rec = httptest.NewRecorder()
+	create(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a mismatched tenant, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_CreateJob_BypassLimitsRequiresPrivilegedKey(t *testing.T) {
+	h, _ := newTestHandler(t)
+	store := StaticKeyStore{"key-1": "tenant-1", "ops-key": "tenant-1"}
+	create := NewAuthMiddlewareWithPrivilegedKeys(store, []string{"ops-key"}).Wrap(h.CreateJob)
+	createBatch := NewAuthMiddlewareWithPrivilegedKeys(store, []string{"ops-key"}).Wrap(h.CreateJobsBatch)
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		key      string
+		body     string
+		expected int
+	}{
+		{"regular key", create, "key-1", `{"payload":"urgent","bypass_limits":true}`, http.StatusForbidden},
+		{"regular key in batch", createBatch, "key-1", `[{"payload":"urgent","bypass_limits":true}]`, http.StatusForbidden},
+		{"regular key without bypass", create, "key-1", `{"payload":"routine"}`, http.StatusCreated},
+		{"privileged key", create, "ops-key", `{"payload":"urgent","bypass_limits":true}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+			if rec.Code == http.StatusCreated && strings.Contains(tt.body, "bypass_limits") {
+				var job models.Job
+				if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+					t.Fatalf("failed to decode job: %v", err)
+				}
+				if !job.BypassLimits {
+					t.Error("expected the job to be marked bypass_limits")
+				}
+			}
+		})
+	}
+
+	// Without authentication there is no privileged key, so bypass_limits is refused
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tenant_id":"tenant-1","payload":"urgent","bypass_limits":true}`))
+	rec := httptest.NewRecorder()
+	h.CreateJob(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without authentication, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_GetJob_OtherTenant(t *testing.T) {
+	h, repo := newTestHandler(t)
+	if err := repo.CreateJob(context.Background(), &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "secret", Status: models.StatusPending}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	get := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1", "key-2": "tenant-2"}).Wrap(h.GetJob)
+
+	getAs := func(key, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/jobs/"+id, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		get(rec, req)
+		return rec
+	}
+
+	if rec := getAs("key-1", "job-1"); rec.Code != http.StatusOK {
+		t.Errorf("expected the owning tenant to get its job, got %d", rec.Code)
+	}
+
+	// Another tenant's job must look exactly like a missing one
+	denied, missing := getAs("key-2", "job-1"), getAs("key-2", "missing")
+	if denied.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for another tenant's job, got %d", denied.Code)
+	}
+	if strings.Contains(denied.Body.String(), "secret") || denied.Body.String() != missing.Body.String() {
+		t.Errorf("expected the same body as for a missing job, got %q and %q", denied.Body.String(), missing.Body.String())
+	}
+}
+
+func TestJobHandler_StreamJobEvents_OtherTenant(t *testing.T) {
+	h, repo := newTestHandler(t)
+	// A finished job keeps the owner's stream from blocking the test
+	if err := repo.CreateJob(context.Background(), &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "secret", Status: models.StatusDone}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	stream := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1", "key-2": "tenant-2"}).Wrap(h.StreamJobEvents)
+
+	streamAs := func(key, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/jobs/"+id+"/events", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		stream(rec, req)
+		return rec
+	}
+
+	if rec := streamAs("key-1", "job-1"); rec.Code != http.StatusOK {
+		t.Errorf("expected the owning tenant to stream its job, got %d", rec.Code)
+	}
+
+	denied, missing := streamAs("key-2", "job-1"), streamAs("key-2", "missing")
+	if denied.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for another tenant's job, got %d", denied.Code)
+	}
+	if denied.Header().Get("Content-Type") == "text/event-stream" || denied.Body.String() != missing.Body.String() {
+		t.Errorf("expected the same response as for a missing job, got %q and %q", denied.Body.String(), missing.Body.String())
+	}
+}
+
+func TestJobHandler_SearchJobs_OtherTenant(t *testing.T) {
+	h, repo := newTestHandler(t)
+	if err := repo.CreateJob(context.Background(), &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "secret invoice", Status: models.StatusPending}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	search := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1", "key-2": "tenant-2"}).Wrap(h.SearchJobs)
+
+	searchAs := func(key string) []models.Job {
+		req := httptest.NewRequest(http.MethodGet, "/jobs/search?q=invoice", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		search(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var jobs []models.Job
+		if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return jobs
+	}
+
+	if jobs := searchAs("key-1"); len(jobs) != 1 || jobs[0].ID != "job-1" {
+		t.Errorf("expected the owning tenant to find job-1, got %d jobs", len(jobs))
+	}
+	if jobs := searchAs("key-2"); len(jobs) != 0 {
+		t.Errorf("expected another tenant to find no jobs, got %d", len(jobs))
+	}
+}
+
+func TestJobHandler_ListTenantIdempotencyKeys_OtherTenant(t *testing.T) {
+	h, repo := newTestHandler(t)
+	if err := repo.CreateJob(context.Background(), &models.Job{ID: "job-1", TenantID: "tenant-1", IdempotencyKey: "order-42", Payload: "work", Status: models.StatusPending}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	list := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1", "key-2": "tenant-2"}).Wrap(h.ListTenantIdempotencyKeys)
+
+	listAs := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/tenants/tenant-1/idempotency-keys", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		list(rec, req)
+		return rec
+	}
+
+	rec := listAs("key-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the owning tenant to list its keys, got %d", rec.Code)
+	}
+	var entries []models.IdempotencyKeyEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(entries) != 1 || entries[0].IdempotencyKey != "order-42" {
+		t.Errorf("expected order-42, got %+v", entries)
+	}
+
+	if rec := listAs("key-2"); rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "order-42") {
+		t.Errorf("expected status 404 for another tenant's keys, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJobHandler_GetDeadLetterQueue_OtherTenant(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "mine", Status: models.StatusPending},
+		{ID: "job-2", TenantID: "tenant-2", Payload: "secret", Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if err := repo.MoveToDeadLetterQueue(ctx, job, "max retries exceeded"); err != nil {
+			t.Fatalf("failed to move job to DLQ: %v", err)
+		}
+	}
+	list := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1"}).Wrap(h.GetDeadLetterQueue)
+
+	listAs := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer key-1")
+		rec := httptest.NewRecorder()
+		list(rec, req)
+		return rec
+	}
+
+	// Without tenant_id the listing is restricted to the key's tenant
+	for _, target := range []string{"/dlq", "/dlq?tenant_id=tenant-1"} {
+		rec := listAs(target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", target, rec.Code)
+		}
+		var dlqJobs []models.DeadLetterJob
+		if err := json.NewDecoder(rec.Body).Decode(&dlqJobs); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(dlqJobs) != 1 || dlqJobs[0].JobID != "job-1" || rec.Header().Get("X-Total-Count") != "1" {
+			t.Errorf("%s: expected only tenant-1's entry, got %+v", target, dlqJobs)
+		}
+	}
+
+	if rec := listAs("/dlq?tenant_id=tenant-2"); rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("expected status 403 for another tenant's entries, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJobHandler_GetDeadLetterSummary_OtherTenant(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "mine", Status: models.StatusPending},
+		{ID: "job-2", TenantID: "tenant-2", Payload: "secret", Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if err := repo.MoveToDeadLetterQueue(ctx, job, "permanent failure: "+job.TenantID); err != nil {
+			t.Fatalf("failed to move job to DLQ: %v", err)
+		}
+	}
+	summarize := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1"}).Wrap(h.GetDeadLetterSummary)
+
+	req := httptest.NewRequest(http.MethodGet, "/dlq/summary", nil)
+	req.Header.Set("Authorization", "Bearer key-1")
+	rec := httptest.NewRecorder()
+	summarize(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var counts map[string]int
+	if err := json.NewDecoder(rec.Body).Decode(&counts); err != nil {
+		t.Fatalf("failed to decode summary: %v", err)
+	}
+	if len(counts) != 1 || counts["permanent failure"] != 1 {
+		t.Errorf("expected only tenant-1's failure to be counted, got %v", counts)
+	}
+}
+
+func TestJobHandler_ListJobs_OtherTenant(t *testing.T) {
+	h, repo := newTestHandler(t)
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "mine", Tags: []string{"email"}, Status: models.StatusPending},
+		{ID: "job-2", TenantID: "tenant-2", Payload: "secret", Tags: []string{"email"}, Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(context.Background(), job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+	list := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1"}).Wrap(h.ListJobs)
+
+	for _, target := range []string{"/jobs?status=PENDING", "/jobs?tag=email"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer key-1")
+		rec := httptest.NewRecorder()
+		list(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", target, rec.Code)
+		}
+		var jobs []models.Job
+		if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(jobs) != 1 || jobs[0].ID != "job-1" {
+			t.Errorf("%s: expected only tenant-1's job, got %+v", target, jobs)
+		}
+	}
+}
+
+func TestScheduleHandler_ListSchedules_OtherTenant(t *testing.T) {
+	_, repo := newTestHandler(t)
+	h := NewScheduleHandler(service.NewSchedulerService(repo, metrics.NewMetrics()))
+	for _, schedule := range []*models.Schedule{
+		{ID: "schedule-1", TenantID: "tenant-1", CronExpr: "* * * * *", Payload: "mine", NextFireAt: time.Now()},
+		{ID: "schedule-2", TenantID: "tenant-2", CronExpr: "* * * * *", Payload: "secret", NextFireAt: time.Now()},
+	} {
+		if err := repo.CreateSchedule(context.Background(), schedule); err != nil {
+			t.Fatalf("failed to create schedule: %v", err)
+		}
+	}
+	list := NewAuthMiddleware(StaticKeyStore{"key-1": "tenant-1"}).Wrap(h.ListSchedules)
+
+	req := httptest.NewRequest(http.MethodGet, "/schedules", nil)
+	req.Header.Set("Authorization", "Bearer key-1")
+	rec := httptest.NewRecorder()
+	list(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var schedules []models.Schedule
+	if err := json.NewDecoder(rec.Body).Decode(&schedules); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(schedules) != 1 || schedules[0].ID != "schedule-1" {
+		t.Errorf("expected only tenant-1's schedule, got %+v", schedules)
+	}
+}
+
+func TestJobHandler_PauseQueue_RequiresPrivilegedKey(t *testing.T) {
+	h, repo := newTestHandler(t)
+	store := StaticKeyStore{"key-1": "tenant-1", "ops-key": "tenant-1"}
+	auth := NewAuthMiddlewareWithPrivilegedKeys(store, []string{"ops-key"})
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		path     string
+		key      string
+		expected int
+	}{
+		{"pause with a privileged key", auth.Wrap(h.PauseQueue), "/admin/pause", "ops-key", http.StatusNoContent},
+		{"pause with a tenant key", auth.Wrap(h.PauseQueue), "/admin/pause", "key-1", http.StatusForbidden},
+		{"resume with a tenant key", auth.Wrap(h.ResumeQueue), "/admin/resume", "key-1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	// The forbidden resume left the privileged pause in place
+	if paused, err := repo.IsPaused(context.Background()); err != nil || !paused {
+		t.Errorf("expected leasing to stay paused, got %t (err %v)", paused, err)
+	}
+}
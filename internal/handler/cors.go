@@ -0,0 +1,38 @@
+package handler
+
+// CORSPolicy decides which browser origins may call the API
+type CORSPolicy struct {
+	allowAll bool
+	origins  map[string]bool
+}
+
+// NewCORSPolicy allows the given origins, such as https://dashboard.example.com. The origin
+// "*" allows every origin and is meant for development. No origins disallows cross-origin calls.
+func NewCORSPolicy(origins []string) *CORSPolicy {
+	p := &CORSPolicy{origins: make(map[string]bool)}
+	for _, origin := range origins {
+		if origin == "*" {
+			p.allowAll = true
+			continue
+		}
+		p.origins[origin] = true
+	}
+	return p
+}
+
+// AllowAll reports whether every origin is allowed, in which case responses do not vary by Origin
+func (p *CORSPolicy) AllowAll() bool {
+	return p.allowAll
+}
+
+// AllowedOrigin returns the Access-Control-Allow-Origin value for a request's Origin header,
+// or "" when the header must be omitted
+func (p *CORSPolicy) AllowedOrigin(origin string) string {
+	if p.allowAll {
+		return "*"
+	}
+	if origin != "" && p.origins[origin] {
+		return origin
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package handler
+
+import "testing"
+
+func TestCORSPolicy_AllowedOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		origins  []string
+		origin   string
+		expected string
+	}{
+		{"listed origin is echoed", []string{"https://a.example.com", "https://b.example.com"}, "https://b.example.com", "https://b.example.com"},
+		{"unlisted origin is omitted", []string{"https://a.example.com"}, "https://evil.example.com", ""},
+		{"missing origin is omitted", []string{"https://a.example.com"}, "", ""},
+		{"no origins allows none", nil, "https://a.example.com", ""},
+		{"wildcard allows any", []string{"*"}, "https://evil.example.com", "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewCORSPolicy(tt.origins).AllowedOrigin(tt.origin); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
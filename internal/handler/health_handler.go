@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"job-queue/internal/repository"
+	"log"
+	"net/http"
+	"time"
+)
+
+// readyCheckTimeout bounds how long /readyz waits for the database
+const readyCheckTimeout = 2 * time.Second
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	repo repository.JobRepository
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(repo repository.JobRepository) *HealthHandler {
+	return &HealthHandler{
+		repo: repo,
+	}
+}
+
+// Healthz handles GET /healthz and reports that the process is running
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
+
+// Readyz handles GET /readyz and reports whether the database is reachable
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	if err := h.repo.Ping(ctx); err != nil {
+		log.Printf("readiness check failed: %v", err)
+		http.Error(w, "database unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
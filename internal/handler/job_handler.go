@@ -1,71 +1,126 @@
 package handler
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
-	"job-queue/internal/metrics"
+	"fmt"
 	"job-queue/internal/models"
 	"job-queue/internal/repository"
 	"job-queue/internal/service"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // JobHandler handles HTTP requests for jobs
 type JobHandler struct {
-	jobService *service.JobService
-	metrics    *metrics.Metrics
-	repo       repository.JobRepository
+	jobService     *service.JobService
+	metricsService *service.MetricsService
+	repo           repository.JobRepository
+
+	// streamsDone is closed to end open event streams when the server shuts down
+	streamsDone      chan struct{}
+	closeStreamsOnce sync.Once
 }
 
 // NewJobHandler creates a new job handler
-func NewJobHandler(jobService *service.JobService, metrics *metrics.Metrics, repo repository.JobRepository) *JobHandler {
+func NewJobHandler(jobService *service.JobService, metricsService *service.MetricsService, repo repository.JobRepository) *JobHandler {
 	return &JobHandler{
-		jobService: jobService,
-		metrics:    metrics,
-		repo:       repo,
+		jobService:     jobService,
+		metricsService: metricsService,
+		repo:           repo,
+		streamsDone:    make(chan struct{}),
 	}
 }
 
+// CloseStreams ends all open event streams so a graceful shutdown does not wait on them
+func (h *JobHandler) CloseStreams() {
+	h.closeStreamsOnce.Do(func() {
+		close(h.streamsDone)
+	})
+}
+
 // CreateJob handles POST /jobs
 func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var req models.CreateJobRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "invalid request body")
 		return
 	}
 
-	if req.TenantID == "" {
-		http.Error(w, "tenant_id is required", http.StatusBadRequest)
+	// Trust the authenticated tenant over the body when auth is enabled
+	if !authorizeTenant(w, r, &req.TenantID) {
+		return
+	}
+	if !authorizeBypassLimits(w, r, req.BypassLimits) {
 		return
 	}
 
-	if req.Payload == "" {
-		http.Error(w, "payload is required", http.StatusBadRequest)
+	if errs := h.jobService.ValidateCreateJobRequest(&req); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	job, err := h.jobService.CreateJob(r.Context(), &req)
+	job, created, err := h.jobService.CreateJob(r.Context(), &req)
 	if err != nil {
 		// Log full error for debugging
 		log.Printf("error creating job: %v (type: %T)", err, err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
 
 		// Check for specific error types first
-		if err == service.ErrRateLimitExceeded {
-			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		if errors.Is(err, service.ErrRateLimitExceeded) {
+			var limitErr *service.RateLimitError
+			if errors.As(err, &limitErr) {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(limitErr.RetryAfter)))
+			}
+			writeJSONError(w, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded")
+			return
+		}
+
+		if errors.Is(err, service.ErrQueueFull) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(service.QueueFullRetryAfter)))
+			writeJSONError(w, http.StatusServiceUnavailable, codeQueueFull, "queue is full, retry later")
+			return
+		}
+
+		var sizeErr *service.ErrPayloadTooLarge
+		if errors.As(err, &sizeErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, sizeErr.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidTags) || errors.Is(err, service.ErrInvalidMetadata) || errors.Is(err, service.ErrInvalidJobID) ||
+			errors.Is(err, service.ErrInvalidDependencies) || errors.Is(err, service.ErrDependencyCycle) ||
+			errors.Is(err, service.ErrMalformedJSON) || errors.Is(err, service.ErrInvalidExpiresAt) ||
+			errors.Is(err, service.ErrInvalidEncoding) || errors.Is(err, service.ErrInvalidBase64) ||
+			errors.Is(err, service.ErrInvalidDedupWindow) {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+
+		var idErr *repository.ErrDuplicateJobID
+		if errors.As(err, &idErr) {
+			writeJSONError(w, http.StatusConflict, codeDuplicateJobID, "job creation failed: "+idErr.Error())
 			return
 		}
 
 		// Check for repository duplicate error type (unwrapped)
 		var dupErr *repository.ErrDuplicateIdempotencyKey
 		if errors.As(err, &dupErr) {
-			http.Error(w, "job creation failed: duplicate idempotency key", http.StatusConflict)
+			writeJSONError(w, http.StatusConflict, codeDuplicateIdempotencyKey, "job creation failed: duplicate idempotency key")
 			return
 		}
 
@@ -73,7 +128,7 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 		unwrappedErr := err
 		for unwrappedErr != nil {
 			if _, ok := unwrappedErr.(*repository.ErrDuplicateIdempotencyKey); ok {
-				http.Error(w, "job creation failed: duplicate idempotency key", http.StatusConflict)
+				writeJSONError(w, http.StatusConflict, codeDuplicateIdempotencyKey, "job creation failed: duplicate idempotency key")
 				return
 			}
 			unwrappedErr = errors.Unwrap(unwrappedErr)
@@ -84,59 +139,209 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(errMsg, "UNIQUE constraint") ||
 			strings.Contains(errMsg, "unique constraint") ||
 			strings.Contains(errMsg, "duplicate idempotency key") {
-			http.Error(w, "job creation failed: duplicate idempotency key", http.StatusConflict)
+			writeJSONError(w, http.StatusConflict, codeDuplicateIdempotencyKey, "job creation failed: duplicate idempotency key")
 		} else if strings.Contains(errMsg, "failed to create job") {
 			if strings.Contains(errMsg, "database") || strings.Contains(errMsg, "connection") {
-				http.Error(w, "job creation failed: database error", http.StatusInternalServerError)
+				writeJSONError(w, http.StatusInternalServerError, codeInternalError, "job creation failed: database error")
 			} else {
 				// Return the actual error message for better debugging
-				http.Error(w, "job creation failed: "+errMsg, http.StatusInternalServerError)
+				writeJSONError(w, http.StatusInternalServerError, codeInternalError, "job creation failed: "+errMsg)
 			}
 		} else if strings.Contains(errMsg, "failed to check idempotency") {
-			http.Error(w, "job creation failed: idempotency check error", http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "job creation failed: idempotency check error")
 		} else if strings.Contains(errMsg, "failed to get running jobs count") {
-			http.Error(w, "job creation failed: rate limit check error", http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "job creation failed: rate limit check error")
 		} else {
 			// Return the actual error message
-			http.Error(w, "job creation failed: "+errMsg, http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "job creation failed: "+errMsg)
 		}
 		return
 	}
 
+	// An idempotency key or dedup_window hit returns the existing job rather than creating one
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+		w.Header().Set("Idempotent-Replay", "true")
+	}
+	if job.IdempotencyKey != "" {
+		w.Header().Set("Idempotency-Key", job.IdempotencyKey)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(job); err != nil {
 		log.Printf("error encoding response: %v", err)
 	}
 }
 
+// CreateJobsBatch handles POST /jobs/batch
+func (h *JobHandler) CreateJobsBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqs []*models.CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(reqs) == 0 {
+		http.Error(w, "at least one job is required", http.StatusBadRequest)
+		return
+	}
+
+	for _, req := range reqs {
+		if req == nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !authorizeTenant(w, r, &req.TenantID) {
+			return
+		}
+		if !authorizeBypassLimits(w, r, req.BypassLimits) {
+			return
+		}
+	}
+
+	results, err := h.jobService.CreateJobsBatch(r.Context(), reqs)
+	if err != nil {
+		if err == service.ErrBatchTooLarge {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("error creating job batch: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "batch job creation failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
 // GetJob handles GET /jobs/{id}
 func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	path := strings.TrimPrefix(r.URL.Path, "/jobs/")
 	if path == "" || path == r.URL.Path {
-		http.Error(w, "job id is required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "job id is required")
 		return
 	}
 
 	job, err := h.jobService.GetJob(r.Context(), path)
 	if err != nil {
 		if err == service.ErrJobNotFound {
-			http.Error(w, "job not found", http.StatusNotFound)
+			writeJSONError(w, http.StatusNotFound, codeNotFound, "job not found")
 			return
 		}
 		log.Printf("error getting job: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
 
 		// Provide more descriptive error messages
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "database") || strings.Contains(errMsg, "connection") {
-			http.Error(w, "failed to retrieve job: database error", http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "failed to retrieve job: database error")
 		} else {
-			http.Error(w, "failed to retrieve job: "+errMsg, http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "failed to retrieve job: "+errMsg)
+		}
+		return
+	}
+
+	// Other tenants' jobs are indistinguishable from missing ones, so IDs cannot be probed
+	if authTenant, ok := TenantFromContext(r.Context()); ok && authTenant != job.TenantID {
+		writeJSONError(w, http.StatusNotFound, codeNotFound, "job not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.JobDetail{Job: job}); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// writeQueryTimeout responds with 503 when err is a database query that ran past the
+// repository's query timeout and reports whether it did
+func writeQueryTimeout(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	writeJSONError(w, http.StatusServiceUnavailable, codeQueryTimeout, "database query timed out")
+	return true
+}
+
+// authorizeJob reports whether the authenticated tenant may change the job, writing a 404
+// otherwise so other tenants' jobs are indistinguishable from missing ones. Without auth
+// every job may be changed.
+func (h *JobHandler) authorizeJob(w http.ResponseWriter, r *http.Request, id string) bool {
+	authTenant, ok := TenantFromContext(r.Context())
+	if !ok {
+		return true
+	}
+
+	job, err := h.jobService.GetJob(r.Context(), id)
+	if err != nil && err != service.ErrJobNotFound {
+		log.Printf("error getting job: %v", err)
+		if writeQueryTimeout(w, err) {
+			return false
+		}
+		http.Error(w, "failed to get job: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if err == service.ErrJobNotFound || job.TenantID != authTenant {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// CancelJob handles POST /jobs/{id}/cancel and its alias DELETE /jobs/{id}, for clients
+// that cannot send DELETE
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(id, "/cancel"):
+		id = strings.TrimSuffix(id, "/cancel")
+	case r.Method == http.MethodDelete && !strings.HasSuffix(id, "/cancel"):
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if id == "" || id == r.URL.Path || strings.Contains(id, "/") {
+		http.Error(w, "job id is required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeJob(w, r, id) {
+		return
+	}
+
+	job, err := h.jobService.CancelJob(r.Context(), id)
+	if err != nil {
+		switch err {
+		case service.ErrJobNotFound:
+			http.Error(w, "job not found", http.StatusNotFound)
+		case service.ErrJobNotCancellable:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("error cancelling job: %v", err)
+			if writeQueryTimeout(w, err) {
+				return
+			}
+			http.Error(w, "failed to cancel job: "+err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -147,122 +352,776 @@ func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ListJobs handles GET /jobs?status=
+// UpdateJobStatusBatch handles POST /jobs/status, which moves several jobs to CANCELLED or
+// back to PENDING in one transaction and reports the outcome per job
+func (h *JobHandler) UpdateJobStatusBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.UpdateJobStatusBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		http.Error(w, "at least one job id is required", http.StatusBadRequest)
+		return
+	}
+
+	// With auth, other tenants' jobs are reported as not found instead of being changed
+	authTenant, _ := TenantFromContext(r.Context())
+
+	results, err := h.jobService.UpdateJobStatusBatch(r.Context(), authTenant, req.IDs, req.Status)
+	if err != nil {
+		if err == service.ErrInvalidTargetStatus || err == service.ErrBatchTooLarge {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("error updating job statuses: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to update job statuses: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// UpdateJob handles PATCH /jobs/{id}, which may only change max_retries and the payload of a PENDING job
+func (h *JobHandler) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	if id == "" || id == r.URL.Path || strings.Contains(id, "/") {
+		http.Error(w, "job id is required", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MaxRetries == nil && req.Payload == nil {
+		http.Error(w, "max_retries or payload is required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeJob(w, r, id) {
+		return
+	}
+
+	var job *models.Job
+	var err error
+	if req.Payload != nil {
+		job, err = h.jobService.UpdatePayload(r.Context(), id, req.Payload)
+	}
+	if err == nil && req.MaxRetries != nil {
+		job, err = h.jobService.UpdateMaxRetries(r.Context(), id, *req.MaxRetries)
+	}
+	if err != nil {
+		var sizeErr *service.ErrPayloadTooLarge
+		switch {
+		case errors.Is(err, service.ErrPayloadRequired):
+			// The same validation error as a job submitted without a payload
+			writeValidationErrors(w, []models.FieldError{{Field: "payload", Message: err.Error()}})
+		case errors.Is(err, service.ErrInvalidMaxRetries), errors.Is(err, models.ErrInvalidPayload),
+			errors.Is(err, service.ErrInvalidBase64):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.As(err, &sizeErr):
+			http.Error(w, sizeErr.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, service.ErrJobNotFound):
+			http.Error(w, "job not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrJobNotPending):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("error updating job: %v", err)
+			if writeQueryTimeout(w, err) {
+				return
+			}
+			http.Error(w, "failed to update job: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// GetJobRetries handles GET /jobs/{id}/retries, returning the job's failed attempts in order.
+// Jobs already moved to the dead letter queue are included.
+func (h *JobHandler) GetJobRetries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/retries")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "job id is required", http.StatusBadRequest)
+		return
+	}
+
+	tenantID, attempts, err := h.jobService.GetJobAttempts(r.Context(), id)
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error getting job attempts: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to get job attempts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Other tenants' jobs are indistinguishable from missing ones
+	if authTenant, ok := TenantFromContext(r.Context()); ok && authTenant != tenantID {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(attempts); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// GetJobPosition handles GET /jobs/{id}/position
+func (h *JobHandler) GetJobPosition(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/position")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "job id is required", http.StatusBadRequest)
+		return
+	}
+
+	position, err := h.jobService.GetJobPosition(r.Context(), id)
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error getting job position: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to get job position: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Other tenants' jobs are indistinguishable from missing ones
+	if authTenant, ok := TenantFromContext(r.Context()); ok && authTenant != position.TenantID {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(position); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// GetJobEvents handles GET /jobs/{id}/events without Accept: text/event-stream, returning the
+// job's durable audit trail of status changes in order
+func (h *JobHandler) GetJobEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/events")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "job id is required", http.StatusBadRequest)
+		return
+	}
+
+	tenantID, transitions, err := h.jobService.GetJobTransitions(r.Context(), id)
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error getting job events: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to get job events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Other tenants' jobs are indistinguishable from missing ones
+	if authTenant, ok := TenantFromContext(r.Context()); ok && authTenant != tenantID {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(transitions); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// StreamJobEvents handles GET /jobs/{id}/events as a Server-Sent Events stream of status transitions
+func (h *JobHandler) StreamJobEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/events")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "job id is required", http.StatusBadRequest)
+		return
+	}
+
+	// Other tenants' jobs are indistinguishable from missing ones
+	if !h.authorizeJob(w, r, id) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// The stream ends when the job reaches a terminal status, the client disconnects, or the server shuts down
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.streamsDone:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	events, err := h.jobService.WatchJob(ctx, id)
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error watching job: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to watch job: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("error encoding event: %v", err)
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// ListJobs handles GET /jobs?status=, where status may list several statuses
 func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		w.Write([]byte("method not allowed"))
+		writeJSONError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	statusStr := r.URL.Query().Get("status")
-	if statusStr == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("status query parameter is required"))
+	// status may be repeated or comma-separated to list several statuses at once
+	var statuses []models.JobStatus
+	seen := make(map[models.JobStatus]bool)
+	for _, value := range r.URL.Query()["status"] {
+		for _, name := range strings.Split(value, ",") {
+			status := models.JobStatus(strings.TrimSpace(name))
+			if status == "" || seen[status] {
+				continue
+			}
+			if !status.IsValid() {
+				writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "invalid status "+strconv.Quote(string(status)))
+				return
+			}
+			seen[status] = true
+			statuses = append(statuses, status)
+		}
+	}
+
+	query := r.URL.Query()
+	tag := query.Get("tag")
+	if len(statuses) == 0 && tag == "" {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "status or tag query parameter is required")
 		return
 	}
 
-	status := models.JobStatus(statusStr)
-	if status != models.StatusPending && status != models.StatusRunning &&
-		status != models.StatusDone && status != models.StatusFailed {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("invalid status"))
+	// A status listing is paged with limit and either a cursor (after) or an offset
+	var page repository.JobPage
+	var err error
+	if page.Limit, err = parseNonNegativeInt(query.Get("limit")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "limit must be a non-negative integer")
+		return
+	}
+	if page.Offset, err = parseNonNegativeInt(query.Get("offset")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "offset must be a non-negative integer")
+		return
+	}
+	if after := query.Get("after"); after != "" {
+		cursor, err := repository.ParseJobCursor(after)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "after must be a cursor from the X-Next-Cursor header")
+			return
+		}
+		page.After = &cursor
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		page.Descending = true
+	default:
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "order must be asc or desc")
+		return
+	}
+	// meta.<key>=<value> keeps only the jobs whose metadata has that pair
+	for name, values := range query {
+		key, ok := strings.CutPrefix(name, "meta.")
+		if !ok {
+			continue
+		}
+		if key == "" || len(values) != 1 {
+			writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "metadata filters must be given once each as meta.<key>=<value>")
+			return
+		}
+		if page.Metadata == nil {
+			page.Metadata = make(map[string]string)
+		}
+		page.Metadata[key] = values[0]
+	}
+	if len(page.Metadata) > service.MaxMetadataKeys {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("at most %d metadata filters are allowed", service.MaxMetadataKeys))
+		return
+	}
+	if tag != "" && (page.After != nil || page.Offset != 0 || page.Limit != 0 || page.Descending || page.Metadata != nil) {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "limit, offset, after, order=desc and metadata filters are only supported when listing by status")
 		return
 	}
 
-	jobs, err := h.jobService.ListJobsByStatus(r.Context(), status)
+	// An authenticated caller only lists its own tenant's jobs
+	authTenant, _ := TenantFromContext(r.Context())
+	page.TenantID = authTenant
+
+	var jobs []*models.Job
+	var next *repository.JobCursor
+	if tag != "" {
+		jobs, err = h.jobService.ListJobsByTag(r.Context(), authTenant, tag, statuses...)
+	} else {
+		jobs, next, err = h.jobService.ListJobsByStatusPage(r.Context(), page, statuses...)
+	}
 	if err != nil {
 		log.Printf("error listing jobs: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
 
 		// Provide more descriptive error messages
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "database") || strings.Contains(errMsg, "connection") {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("failed to list jobs: database error"))
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "failed to list jobs: database error")
 		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("failed to list jobs: " + errMsg))
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "failed to list jobs: "+errMsg)
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if next != nil {
+		w.Header().Set("X-Next-Cursor", next.String())
+	}
 	if err := json.NewEncoder(w).Encode(jobs); err != nil {
 		log.Printf("error encoding response: %v", err)
 	}
 }
 
-// GetMetrics handles GET /metrics
-func (h *JobHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+// SearchJobs handles GET /jobs/search?q=
+func (h *JobHandler) SearchJobs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get actual counts from database (more accurate than in-memory metrics)
-	ctx := r.Context()
-
-	totalJobs, err := h.repo.GetTotalJobsCount(ctx)
+	query := r.URL.Query()
+	limit, err := parseNonNegativeInt(query.Get("limit"))
 	if err != nil {
-		log.Printf("error getting total jobs count: %v", err)
-		totalJobs = 0
+		http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+		return
 	}
 
-	completedJobs, err := h.repo.GetCompletedJobsCount(ctx)
+	// An authenticated caller only searches its own tenant's jobs
+	authTenant, _ := TenantFromContext(r.Context())
+	jobs, err := h.jobService.SearchJobs(r.Context(), authTenant, query.Get("q"), limit)
 	if err != nil {
-		log.Printf("error getting completed jobs count: %v", err)
-		completedJobs = 0
+		if err == service.ErrSearchQueryTooShort {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("error searching jobs: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to search jobs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if jobs == nil {
+		jobs = []*models.Job{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(jobs); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// ListTenantIdempotencyKeys handles GET /tenants/{id}/idempotency-keys
+func (h *JobHandler) ListTenantIdempotencyKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	failedJobs, err := h.repo.GetFailedJobsCount(ctx)
+	path := strings.TrimPrefix(r.URL.Path, "/tenants/")
+	tenantID := strings.TrimSuffix(path, "/idempotency-keys")
+	if path == r.URL.Path || tenantID == path {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if tenantID == "" || strings.Contains(tenantID, "/") {
+		http.Error(w, "tenant id is required", http.StatusBadRequest)
+		return
+	}
+
+	// An API key only lists its own tenant's keys; other tenants look like unknown paths
+	if authTenant, ok := TenantFromContext(r.Context()); ok && authTenant != tenantID {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	entries, err := h.jobService.ListIdempotencyKeys(r.Context(), tenantID)
 	if err != nil {
-		log.Printf("error getting failed jobs count: %v", err)
-		failedJobs = 0
+		log.Printf("error listing idempotency keys: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to list idempotency keys: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// Get retried jobs from in-memory metrics (this is tracked separately)
-	inMemoryMetrics := h.metrics.GetSnapshot()
-	retriedJobs := inMemoryMetrics["retried_jobs"]
+	if entries == nil {
+		entries = []*models.IdempotencyKeyEntry{}
+	}
 
-	metrics := map[string]int64{
-		"total_jobs":     int64(totalJobs),
-		"completed_jobs": int64(completedJobs),
-		"failed_jobs":    int64(failedJobs),
-		"retried_jobs":   retriedJobs,
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// GetMetrics handles GET /metrics
+func (h *JobHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
+	metrics := h.metricsService.Snapshot(r.Context())
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(metrics); err != nil {
 		log.Printf("error encoding response: %v", err)
 	}
 }
 
+// ResetMetrics handles POST /metrics/reset
+func (h *JobHandler) ResetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.metricsService.Reset()
+	log.Printf("metrics counters reset")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PauseQueue handles POST /admin/pause
+func (h *JobHandler) PauseQueue(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, true)
+}
+
+// ResumeQueue handles POST /admin/resume
+func (h *JobHandler) ResumeQueue(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, false)
+}
+
+// setPaused pauses or resumes leasing for PauseQueue and ResumeQueue
+func (h *JobHandler) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Pausing stops leasing for every tenant, so an ordinary tenant key may not do it
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	if err := h.jobService.SetPaused(r.Context(), paused); err != nil {
+		log.Printf("error setting paused=%t: %v", paused, err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to update pause state: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("leasing paused=%t", paused)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStats handles GET /stats
+func (h *JobHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := h.jobService.GetStats(r.Context())
+	if err != nil {
+		log.Printf("error getting job stats: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to get job stats: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// GetMetricsHistoryCSV handles GET /metrics/history.csv
+func (h *JobHandler) GetMetricsHistoryCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshots, err := h.metricsService.ListSnapshots(r.Context())
+	if err != nil {
+		log.Printf("error listing metrics snapshots: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to retrieve metrics history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Columns are the union of counters across snapshots so older rows stay aligned as counters are added
+	columnSet := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		for name := range snapshot.Counters {
+			columnSet[name] = true
+		}
+	}
+	columns := make([]string, 0, len(columnSet))
+	for name := range columnSet {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="metrics-history.csv"`)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"timestamp"}, columns...)); err != nil {
+		log.Printf("error writing csv header: %v", err)
+		return
+	}
+
+	for _, snapshot := range snapshots {
+		record := make([]string, 0, len(columns)+1)
+		record = append(record, snapshot.TakenAt.UTC().Format(time.RFC3339))
+		for _, name := range columns {
+			record = append(record, strconv.FormatInt(snapshot.Counters[name], 10))
+		}
+		if err := writer.Write(record); err != nil {
+			log.Printf("error writing csv row: %v", err)
+			return
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("error flushing csv: %v", err)
+	}
+}
+
 // GetDeadLetterQueue handles GET /dlq
 func (h *JobHandler) GetDeadLetterQueue(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		w.Write([]byte("method not allowed"))
+		writeJSONError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	dlqJobs, err := h.jobService.ListDeadLetterJobs(r.Context())
+	query := r.URL.Query()
+	limit, err := parseNonNegativeInt(query.Get("limit"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "limit must be a non-negative integer")
+		return
+	}
+	offset, err := parseNonNegativeInt(query.Get("offset"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, codeInvalidRequest, "offset must be a non-negative integer")
+		return
+	}
+
+	// An API key only sees its own tenant's entries, with or without tenant_id
+	tenantID := query.Get("tenant_id")
+	if !authorizeTenant(w, r, &tenantID) {
+		return
+	}
+
+	dlqJobs, total, err := h.jobService.ListDeadLetterJobsFiltered(r.Context(), tenantID, limit, offset)
 	if err != nil {
 		log.Printf("error listing dead letter jobs: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
 
 		// Provide more descriptive error messages
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "database") || strings.Contains(errMsg, "connection") {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("failed to retrieve dead letter queue: database error"))
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "failed to retrieve dead letter queue: database error")
 		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("failed to retrieve dead letter queue: " + errMsg))
+			writeJSONError(w, http.StatusInternalServerError, codeInternalError, "failed to retrieve dead letter queue: "+errMsg)
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	if err := json.NewEncoder(w).Encode(dlqJobs); err != nil {
 		log.Printf("error encoding response: %v", err)
 	}
 }
+
+// GetDeadLetterSummary handles GET /dlq/summary
+func (h *JobHandler) GetDeadLetterSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// A scoped key only sees its own tenant's failures
+	authTenant, _ := TenantFromContext(r.Context())
+	counts, err := h.jobService.CountDeadLetterByReason(r.Context(), authTenant)
+	if err != nil {
+		log.Printf("error summarizing dead letter jobs: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, codeInternalError, "failed to summarize dead letter queue")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(counts); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds for the Retry-After header
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// parseNonNegativeInt parses an optional query parameter, treating an empty value as zero
+func parseNonNegativeInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("value %d is negative", n)
+	}
+	return n, nil
+}
+
+// Codes of the JSON error responses, stable for clients to match on
+const (
+	codeMethodNotAllowed        = "method_not_allowed"
+	codeInvalidRequest          = "invalid_request"
+	codeForbidden               = "forbidden"
+	codeNotFound                = "not_found"
+	codeDuplicateJobID          = "duplicate_job_id"
+	codeDuplicateIdempotencyKey = "duplicate_idempotency_key"
+	codePayloadTooLarge         = "payload_too_large"
+	codeRateLimited             = "rate_limited"
+	codeQueueFull               = "queue_full"
+	codeInternalError           = "internal_error"
+	codeQueryTimeout            = "query_timeout"
+)
+
+// writeJSONError responds with status and an error envelope carrying a machine-readable code
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(models.ErrorResponse{Error: models.ErrorDetail{Code: code, Message: message}}); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// writeValidationErrors responds 400 with every invalid field of the request
+func writeValidationErrors(w http.ResponseWriter, errs []models.FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(models.ValidationErrorResponse{Errors: errs}); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,1092 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"job-queue/internal/repository"
+	"job-queue/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHandler creates a job handler backed by a temporary SQLite database
+func newTestHandler(t *testing.T) (*JobHandler, *repository.SQLiteRepository) {
+	t.Helper()
+
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	metricsInstance := metrics.NewMetrics()
+	jobService := service.NewJobService(repo, service.NewRateLimiter(10), metricsInstance)
+	metricsService := service.NewMetricsService(repo, repo, metricsInstance)
+
+	return NewJobHandler(jobService, metricsService, repo), repo
+}
+
+func TestJobHandler_GetMetricsHistoryCSV(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	snapshots := []*models.MetricsSnapshot{
+		{TakenAt: first, Counters: map[string]int64{"total_jobs": 3, "completed_jobs": 1, "failed_jobs": 0, "retried_jobs": 2}},
+		{TakenAt: first.Add(time.Minute), Counters: map[string]int64{"total_jobs": 5, "completed_jobs": 4, "failed_jobs": 1, "retried_jobs": 2}},
+	}
+	for _, snapshot := range snapshots {
+		if err := repo.RecordMetricsSnapshot(ctx, snapshot); err != nil {
+			t.Fatalf("failed to record snapshot: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics/history.csv", nil)
+	rec := httptest.NewRecorder()
+	h.GetMetricsHistoryCSV(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected Content-Type text/csv, got %s", ct)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse csv: %v", err)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d records", len(records))
+	}
+
+	expectedHeader := []string{"timestamp", "completed_jobs", "failed_jobs", "retried_jobs", "total_jobs"}
+	for i, column := range expectedHeader {
+		if records[0][i] != column {
+			t.Errorf("expected header column %d to be %s, got %s", i, column, records[0][i])
+		}
+	}
+
+	expectedRows := [][]string{
+		{"2024-01-01T12:00:00Z", "1", "0", "2", "3"},
+		{"2024-01-01T12:01:00Z", "4", "1", "2", "5"},
+	}
+	for i, expected := range expectedRows {
+		row := records[i+1]
+		for j, value := range expected {
+			if row[j] != value {
+				t.Errorf("row %d column %s: expected %s, got %s", i+1, expectedHeader[j], value, row[j])
+			}
+		}
+	}
+}
+
+func TestJobHandler_GetMetricsHistoryCSV_Empty(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics/history.csv", nil)
+	rec := httptest.NewRecorder()
+	h.GetMetricsHistoryCSV(rec, req)
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse csv: %v", err)
+	}
+
+	if len(records) != 1 || records[0][0] != "timestamp" {
+		t.Errorf("expected header only, got %v", records)
+	}
+}
+
+func TestJobHandler_CreateJob_IdempotentHitReturns200(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	body := `{"tenant_id":"tenant-1","idempotency_key":"key-1","payload":"hello"}`
+
+	var ids []string
+	for i, expected := range []int{http.StatusCreated, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.CreateJob(rec, req)
+
+		if rec.Code != expected {
+			t.Fatalf("request %d: expected status %d, got %d", i+1, expected, rec.Code)
+		}
+
+		var job models.Job
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		ids = append(ids, job.ID)
+	}
+
+	if ids[0] != ids[1] {
+		t.Errorf("expected the same job to be returned, got %s and %s", ids[0], ids[1])
+	}
+}
+
+func TestJobHandler_CreateJob_RateLimitedSetsRetryAfter(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 11; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tenant_id":"tenant-1","payload":"hello"}`))
+		rec = httptest.NewRecorder()
+		h.CreateJob(rec, req)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 once the window is full, got %d", rec.Code)
+	}
+
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("expected a numeric Retry-After header, got %q", rec.Header().Get("Retry-After"))
+	}
+	if retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("expected Retry-After between 1 and 60 seconds, got %d", retryAfter)
+	}
+}
+
+func TestJobHandler_CancelJob(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	// Another tenant cannot see the job
+	req := httptest.NewRequest(http.MethodDelete, "/jobs/job-1", nil)
+	rec := httptest.NewRecorder()
+	h.CancelJob(rec, req.WithContext(WithTenant(req.Context(), "tenant-2")))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for another tenant, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodDelete, "/jobs/job-1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var cancelled models.Job
+	if err := json.NewDecoder(rec.Body).Decode(&cancelled); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if cancelled.Status != models.StatusCancelled || cancelled.FinishedAt == nil {
+		t.Errorf("expected a CANCELLED job with finished_at, got %s", cancelled.Status)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodDelete, "/jobs/job-1", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 when cancelling twice, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodDelete, "/jobs/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing job, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_CancelJob_Post(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodGet, "/jobs/job-1/cancel", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodPost, "/jobs/job-1/cancel", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var cancelled models.Job
+	if err := json.NewDecoder(rec.Body).Decode(&cancelled); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if cancelled.ID != "job-1" || cancelled.Status != models.StatusCancelled {
+		t.Errorf("expected job-1 to be CANCELLED, got %s %s", cancelled.ID, cancelled.Status)
+	}
+
+	// The same rule as DELETE applies
+	rec = httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodPost, "/jobs/job-1/cancel", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 when cancelling twice, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodPost, "/jobs/missing/cancel", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing job, got %d", rec.Code)
+	}
+}
+func TestJobHandler_CancelJob_Running(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	// Both forms cancel a RUNNING job, so DELETE can stop work in progress; only finished jobs conflict
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/jobs/job-1/cancel", nil),
+		httptest.NewRequest(http.MethodDelete, "/jobs/job-2", nil),
+	} {
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/jobs/"), "/cancel")
+		if err := repo.CreateJob(ctx, &models.Job{ID: id, TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if err := repo.UpdateJobStatus(ctx, id, models.StatusRunning); err != nil {
+			t.Fatalf("failed to start job: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		h.CancelJob(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status 200 for a RUNNING job, got %d: %s", req.Method, req.URL.Path, rec.Code, rec.Body.String())
+		}
+		var cancelled models.Job
+		if err := json.NewDecoder(rec.Body).Decode(&cancelled); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if cancelled.Status != models.StatusCancelled {
+			t.Errorf("%s %s: expected the RUNNING job to be CANCELLED, got %s", req.Method, req.URL.Path, cancelled.Status)
+		}
+	}
+
+	if err := repo.CreateJob(ctx, &models.Job{ID: "job-3", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	if err := repo.UpdateJobStatus(ctx, "job-3", models.StatusDone); err != nil {
+		t.Fatalf("failed to finish job: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.CancelJob(rec, httptest.NewRequest(http.MethodPost, "/jobs/job-3/cancel", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a DONE job, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_UpdateJobStatusBatch(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3},
+		{ID: "job-2", TenantID: "tenant-1", Payload: "work", Status: models.StatusDone, MaxRetries: 3},
+		{ID: "job-3", TenantID: "tenant-2", Payload: "work", Status: models.StatusPending, MaxRetries: 3},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	body := `{"ids":["job-1","job-2","job-3","missing"],"status":"CANCELLED"}`
+	req := httptest.NewRequest(http.MethodPost, "/jobs/status", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.UpdateJobStatusBatch(rec, req.WithContext(WithTenant(req.Context(), "tenant-1")))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var results []models.JobStatusUpdateResult
+	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if !results[0].Updated || results[0].Error != "" {
+		t.Errorf("expected job-1 to be cancelled, got %+v", results[0])
+	}
+	if results[1].Updated || results[1].Error != "cannot move a DONE job to CANCELLED" {
+		t.Errorf("expected job-2 to be rejected as DONE, got %+v", results[1])
+	}
+	if results[2].Updated || results[2].Error != "job not found" {
+		t.Errorf("expected another tenant's job to be reported as not found, got %+v", results[2])
+	}
+	if results[3].Updated || results[3].Error != "job not found" {
+		t.Errorf("expected a missing job to be reported as not found, got %+v", results[3])
+	}
+
+	job, err := repo.GetJobByID(ctx, "job-3")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.Status != models.StatusPending {
+		t.Errorf("expected another tenant's job to stay PENDING, got %s", job.Status)
+	}
+
+	// A cancelled job may be put back in the queue
+	rec = httptest.NewRecorder()
+	h.UpdateJobStatusBatch(rec, httptest.NewRequest(http.MethodPost, "/jobs/status", strings.NewReader(`{"ids":["job-1"],"status":"PENDING"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	job, err = repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.Status != models.StatusPending || job.FinishedAt != nil {
+		t.Errorf("expected job-1 to be PENDING without finished_at, got %s", job.Status)
+	}
+
+	for _, body := range []string{
+		`{"ids":["job-1"],"status":"DONE"}`,
+		`{"ids":[],"status":"CANCELLED"}`,
+		`not json`,
+	} {
+		rec = httptest.NewRecorder()
+		h.UpdateJobStatusBatch(rec, httptest.NewRequest(http.MethodPost, "/jobs/status", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestJobHandler_UpdateJob(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.UpdateJob(rec, httptest.NewRequest(http.MethodPatch, "/jobs/"+id, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := patch("job-1", `{"max_retries": 7}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated models.Job
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if updated.MaxRetries != 7 {
+		t.Errorf("expected max_retries 7, got %d", updated.MaxRetries)
+	}
+
+	if rec := patch("job-1", `{"max_retries": -1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for negative max_retries, got %d", rec.Code)
+	}
+	if rec := patch("job-1", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without max_retries or payload, got %d", rec.Code)
+	}
+
+	rec = patch("job-1", `{"payload": {"to": "fixed@example.com"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if string(body["id"]) != `"job-1"` || string(body["payload"]) != `{"to":"fixed@example.com"}` {
+		t.Errorf("expected job-1 to keep its id with the new payload, got id %s payload %s", body["id"], body["payload"])
+	}
+	if rec := patch("job-1", `{"payload": "`+strings.Repeat("x", service.DefaultMaxPayloadBytes+1)+`"}`); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413 for an oversized payload, got %d", rec.Code)
+	}
+
+	// An empty payload is rejected like a job submitted without one
+	rec = patch("job-1", `{"payload": ""}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an empty payload, got %d", rec.Code)
+	}
+	var resp models.ValidationErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Field != "payload" || resp.Errors[0].Message != "payload is required" {
+		t.Errorf("expected a payload is required error, got %+v", resp.Errors)
+	}
+	if got, err := repo.GetJobByID(ctx, "job-1"); err != nil || got.Payload != `{"to":"fixed@example.com"}` {
+		t.Errorf("expected the job to keep its payload, got %+v (err %v)", got, err)
+	}
+
+	if rec := patch("missing", `{"max_retries": 1}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing job, got %d", rec.Code)
+	}
+
+	if err := repo.UpdateJobStatus(ctx, "job-1", models.StatusRunning); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	if rec := patch("job-1", `{"max_retries": 1}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a running job, got %d", rec.Code)
+	}
+	if rec := patch("job-1", `{"payload": "late"}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 when replacing a running job's payload, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_GetJob_Attempts(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	if err := repo.CreateJob(ctx, &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	getAttempts := func() (int, int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GetJob(rec, httptest.NewRequest(http.MethodGet, "/jobs/job-1", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var body struct {
+			Attempt           int `json:"attempt"`
+			AttemptsRemaining int `json:"attempts_remaining"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		return body.Attempt, body.AttemptsRemaining
+	}
+
+	if attempt, remaining := getAttempts(); attempt != 1 || remaining != 3 {
+		t.Errorf("expected attempt 1 with 3 remaining, got %d with %d", attempt, remaining)
+	}
+
+	// What the worker does when an attempt fails and is retried
+	if _, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, repository.LeaseOptions{}); err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if ok, err := repo.UpdateJobStatusIf(ctx, "job-1", models.StatusRunning, models.StatusPending); err != nil || !ok {
+		t.Fatalf("failed to reset job: %v", err)
+	}
+	if err := repo.IncrementRetryCount(ctx, "job-1"); err != nil {
+		t.Fatalf("failed to increment retry count: %v", err)
+	}
+
+	if attempt, remaining := getAttempts(); attempt != 2 || remaining != 2 {
+		t.Errorf("expected attempt 2 with 2 remaining after a retry, got %d with %d", attempt, remaining)
+	}
+}
+
+func TestJobHandler_GetJobPosition(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending},
+		{ID: "job-2", TenantID: "tenant-2", Payload: "work", Status: models.StatusPending},
+		{ID: "other-queue", TenantID: "tenant-1", Queue: "emails", Payload: "work", Status: models.StatusPending},
+		{ID: "job-3", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+	if err := repo.UpdateJobStatus(ctx, "job-1", models.StatusRunning); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	tests := []struct {
+		id        string
+		status    models.JobStatus
+		jobsAhead int
+	}{
+		{id: "job-1", status: models.StatusRunning, jobsAhead: 0},
+		{id: "job-2", status: models.StatusPending, jobsAhead: 0},
+		{id: "job-3", status: models.StatusPending, jobsAhead: 1},
+		{id: "other-queue", status: models.StatusPending, jobsAhead: 0},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.GetJobPosition(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+tt.id+"/position", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.id, rec.Code)
+		}
+
+		var position models.JobPosition
+		if err := json.NewDecoder(rec.Body).Decode(&position); err != nil {
+			t.Fatalf("%s: failed to decode position: %v", tt.id, err)
+		}
+		if position.Status != tt.status || position.JobsAhead != tt.jobsAhead {
+			t.Errorf("%s: expected %s with %d jobs ahead, got %+v", tt.id, tt.status, tt.jobsAhead, position)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetJobPosition(rec, httptest.NewRequest(http.MethodGet, "/jobs/missing/position", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown job, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_CreateJob_ClientSuppliedID(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	body := `{"id":"order-42","tenant_id":"tenant-1","payload":"hello"}`
+	for i, expected := range []int{http.StatusCreated, http.StatusConflict} {
+		rec := httptest.NewRecorder()
+		h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		if rec.Code != expected {
+			t.Fatalf("request %d: expected status %d, got %d: %s", i+1, expected, rec.Code, rec.Body.String())
+		}
+
+		if expected == http.StatusCreated {
+			var job models.Job
+			if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+				t.Fatalf("failed to decode job: %v", err)
+			}
+			if job.ID != "order-42" {
+				t.Errorf("expected job ID order-42, got %s", job.ID)
+			}
+		}
+	}
+
+	// An idempotent retry of the same submission still returns the existing job
+	body = `{"id":"order-43","tenant_id":"tenant-1","idempotency_key":"key-1","payload":"hello"}`
+	for i, expected := range []int{http.StatusCreated, http.StatusOK} {
+		rec := httptest.NewRecorder()
+		h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		if rec.Code != expected {
+			t.Fatalf("idempotent request %d: expected status %d, got %d", i+1, expected, rec.Code)
+		}
+
+		// The key is echoed back, and a replay says so
+		var job models.Job
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		if job.IdempotencyKey != "key-1" || rec.Header().Get("Idempotency-Key") != "key-1" {
+			t.Errorf("idempotent request %d: expected key-1 in the body and header, got %q and %q", i+1, job.IdempotencyKey, rec.Header().Get("Idempotency-Key"))
+		}
+		wantReplay := ""
+		if expected == http.StatusOK {
+			wantReplay = "true"
+		}
+		if replay := rec.Header().Get("Idempotent-Replay"); replay != wantReplay {
+			t.Errorf("idempotent request %d: expected Idempotent-Replay %q, got %q", i+1, wantReplay, replay)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tenant_id":"tenant-1","payload":"hello"}`)))
+	if rec.Header().Get("Idempotency-Key") != "" || rec.Header().Get("Idempotent-Replay") != "" {
+		t.Errorf("expected no idempotency headers without a key, got %v", rec.Header())
+	}
+}
+
+func TestJobHandler_CreateJob_ValidationErrors(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	body := `{"id": "orders/42", "max_retries": -1, "tags": ["email", "email"]}`
+	rec := httptest.NewRecorder()
+	h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+
+	var resp models.ValidationErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	fields := make(map[string]bool)
+	for _, fieldErr := range resp.Errors {
+		fields[fieldErr.Field] = true
+	}
+	for _, field := range []string{"id", "tenant_id", "payload", "max_retries", "tags"} {
+		if !fields[field] {
+			t.Errorf("expected an error for %s, got %+v", field, resp.Errors)
+		}
+	}
+}
+
+func TestJobHandler_CreateJob_StructuredPayload(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "object", payload: `{"command":"python3","args":["resize.py"]}`},
+		{name: "string", payload: `"hello"`},
+		{name: "string holding JSON", payload: `"{\"a\":1}"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"tenant_id":"tenant-1","payload":` + tt.payload + `}`
+			rec := httptest.NewRecorder()
+			h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var created models.Job
+			if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+				t.Fatalf("failed to decode job: %v", err)
+			}
+
+			rec = httptest.NewRecorder()
+			h.GetJob(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+created.ID, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+
+			var got struct {
+				Payload json.RawMessage `json:"payload"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode job: %v", err)
+			}
+			if string(got.Payload) != tt.payload {
+				t.Errorf("expected payload %s, got %s", tt.payload, got.Payload)
+			}
+		})
+	}
+}
+
+func TestJobHandler_GetJobRetries(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusRunning, MaxRetries: 1}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	for i, reason := range []string{"timeout", "connection refused"} {
+		if err := repo.RecordJobAttempt(ctx, job.ID, &models.JobAttempt{Attempt: i + 1, Reason: reason, At: time.Now()}); err != nil {
+			t.Fatalf("failed to record attempt: %v", err)
+		}
+	}
+
+	getRetries := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.GetJobRetries(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+id+"/retries", nil))
+		return rec
+	}
+
+	// The history is the same before and after the job moves to the DLQ
+	for _, stage := range []string{"jobs", "dlq"} {
+		if stage == "dlq" {
+			if err := repo.MoveToDeadLetterQueue(ctx, job, "max retries exceeded"); err != nil {
+				t.Fatalf("failed to move job to DLQ: %v", err)
+			}
+		}
+
+		rec := getRetries(job.ID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", stage, rec.Code)
+		}
+		var attempts []models.JobAttempt
+		if err := json.NewDecoder(rec.Body).Decode(&attempts); err != nil {
+			t.Fatalf("%s: failed to decode attempts: %v", stage, err)
+		}
+		if len(attempts) != 2 || attempts[0].Reason != "timeout" || attempts[1].Attempt != 2 {
+			t.Errorf("%s: expected both attempts in order, got %+v", stage, attempts)
+		}
+	}
+
+	if rec := getRetries("missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown job, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_GetJobEvents(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	if _, err := repo.CancelJob(ctx, job.ID); err != nil {
+		t.Fatalf("failed to cancel job: %v", err)
+	}
+
+	getEvents := func(id, tenantID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/jobs/"+id+"/events", nil)
+		if tenantID != "" {
+			req = req.WithContext(WithTenant(req.Context(), tenantID))
+		}
+		rec := httptest.NewRecorder()
+		h.GetJobEvents(rec, req)
+		return rec
+	}
+
+	rec := getEvents(job.ID, "tenant-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var transitions []models.JobTransition
+	if err := json.NewDecoder(rec.Body).Decode(&transitions); err != nil {
+		t.Fatalf("failed to decode events: %v", err)
+	}
+	if len(transitions) != 2 || transitions[0].FromStatus != "" || transitions[1].FromStatus != models.StatusPending || transitions[1].ToStatus != models.StatusCancelled {
+		t.Errorf("expected creation then cancellation, got %+v", transitions)
+	}
+
+	if rec := getEvents(job.ID, "tenant-2"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for another tenant, got %d", rec.Code)
+	}
+	if rec := getEvents("missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown job, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_GetDeadLetterSummary(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	for i, reason := range []string{"max retries exceeded: timeout", "max retries exceeded: exit status 1", "permanent failure: bad input"} {
+		job := &models.Job{ID: fmt.Sprintf("job-%d", i), TenantID: "tenant-1", Payload: "work", Status: models.StatusRunning, MaxRetries: 1}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if err := repo.MoveToDeadLetterQueue(ctx, job, reason); err != nil {
+			t.Fatalf("failed to move job to DLQ: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetDeadLetterSummary(rec, httptest.NewRequest(http.MethodGet, "/dlq/summary", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var counts map[string]int
+	if err := json.NewDecoder(rec.Body).Decode(&counts); err != nil {
+		t.Fatalf("failed to decode summary: %v", err)
+	}
+	if len(counts) != 2 || counts["max retries exceeded"] != 2 || counts["permanent failure"] != 1 {
+		t.Errorf("expected 2 max retries exceeded and 1 permanent failure, got %v", counts)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetDeadLetterSummary(rec, httptest.NewRequest(http.MethodPost, "/dlq/summary", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_PauseResumeQueue(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	if err := repo.CreateJob(ctx, &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	paused := func() int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GetStats(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		var stats models.JobStats
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("failed to decode stats: %v", err)
+		}
+		return stats[models.StatsKeyPaused]
+	}
+
+	rec := httptest.NewRecorder()
+	h.PauseQueue(rec, httptest.NewRequest(http.MethodPost, "/admin/pause", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+	if got := paused(); got != 1 {
+		t.Errorf("expected PAUSED=1, got %d", got)
+	}
+	if job, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, repository.LeaseOptions{}); err != nil || job != nil {
+		t.Fatalf("expected nothing to be leased while paused, got %+v, %v", job, err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ResumeQueue(rec, httptest.NewRequest(http.MethodPost, "/admin/resume", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+	if got := paused(); got != 0 {
+		t.Errorf("expected PAUSED=0, got %d", got)
+	}
+	if job, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, repository.LeaseOptions{}); err != nil || job == nil {
+		t.Fatalf("expected the job to be leased once resumed, got %+v, %v", job, err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.PauseQueue(rec, httptest.NewRequest(http.MethodGet, "/admin/pause", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_QueryTimeoutReturns503(t *testing.T) {
+	repo, err := repository.NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), repository.SQLiteOptions{QueryTimeout: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	metricsInstance := metrics.NewMetrics()
+	jobService := service.NewJobService(repo, service.NewRateLimiter(10), metricsInstance)
+	h := NewJobHandler(jobService, service.NewMetricsService(repo, repo, metricsInstance), repo)
+
+	rec := httptest.NewRecorder()
+	h.ListJobs(rec, httptest.NewRequest(http.MethodGet, "/jobs?status=PENDING", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetJob(rec, httptest.NewRequest(http.MethodGet, "/jobs/job-1", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJobHandler_JSONErrors(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		request *http.Request
+		status  int
+		code    string
+	}{
+		{"create with a bad body", h.CreateJob, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader("{")), http.StatusBadRequest, "invalid_request"},
+		{"create with GET", h.CreateJob, httptest.NewRequest(http.MethodGet, "/jobs", nil), http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"get a missing job", h.GetJob, httptest.NewRequest(http.MethodGet, "/jobs/missing", nil), http.StatusNotFound, "not_found"},
+		{"list without filters", h.ListJobs, httptest.NewRequest(http.MethodGet, "/jobs", nil), http.StatusBadRequest, "invalid_request"},
+		{"list an unknown status", h.ListJobs, httptest.NewRequest(http.MethodGet, "/jobs?status=LOST", nil), http.StatusBadRequest, "invalid_request"},
+		{"dlq with a bad limit", h.GetDeadLetterQueue, httptest.NewRequest(http.MethodGet, "/dlq?limit=-1", nil), http.StatusBadRequest, "invalid_request"},
+		{"dlq with POST", h.GetDeadLetterQueue, httptest.NewRequest(http.MethodPost, "/dlq", nil), http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"dlq summary with POST", h.GetDeadLetterSummary, httptest.NewRequest(http.MethodPost, "/dlq/summary", nil), http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, tt.request)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected application/json, got %q", ct)
+			}
+
+			var body models.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("expected a JSON error envelope, got %v", err)
+			}
+			if body.Error.Code != tt.code || body.Error.Message == "" {
+				t.Errorf("expected code %q with a message, got %+v", tt.code, body.Error)
+			}
+		})
+	}
+}
+
+func TestJobHandler_CreateJob_QueueFull(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	metricsInstance := metrics.NewMetrics()
+	jobService := service.NewJobServiceWithConfig(repo, service.NewRateLimiter(10), metricsInstance, service.JobServiceConfig{MaxPendingJobs: 1})
+	h := NewJobHandler(jobService, service.NewMetricsService(repo, repo, metricsInstance), repo)
+
+	create := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tenant_id":"tenant-1","payload":"hello"}`)))
+		return rec
+	}
+
+	if rec := create(); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := create()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 once the queue is full, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "5" {
+		t.Errorf("expected Retry-After 5, got %q", rec.Header().Get("Retry-After"))
+	}
+	var body models.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code != "queue_full" {
+		t.Errorf("expected a queue_full error, got %+v (%v)", body, err)
+	}
+}
+
+func TestJobHandler_ListJobs_Cursor(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if err := repo.CreateJob(ctx, &models.Job{ID: fmt.Sprintf("job-%d", i), TenantID: "tenant-1", Payload: "work", Status: models.StatusPending}); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, []models.Job) {
+		rec := httptest.NewRecorder()
+		h.ListJobs(rec, httptest.NewRequest(http.MethodGet, "/jobs?"+query, nil))
+		var jobs []models.Job
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+				t.Fatalf("failed to decode jobs: %v", err)
+			}
+		}
+		return rec, jobs
+	}
+
+	rec, jobs := list("status=PENDING&limit=2")
+	if rec.Code != http.StatusOK || len(jobs) != 2 || jobs[0].ID != "job-1" {
+		t.Fatalf("expected the first 2 jobs, got status %d and %+v", rec.Code, jobs)
+	}
+	cursor := rec.Header().Get("X-Next-Cursor")
+	if cursor == "" {
+		t.Fatal("expected X-Next-Cursor on a full page")
+	}
+
+	rec, jobs = list("status=PENDING&limit=2&after=" + cursor)
+	if rec.Code != http.StatusOK || len(jobs) != 1 || jobs[0].ID != "job-3" {
+		t.Fatalf("expected job-3 on the second page, got status %d and %+v", rec.Code, jobs)
+	}
+	if next := rec.Header().Get("X-Next-Cursor"); next != "" {
+		t.Errorf("expected no cursor after the last page, got %q", next)
+	}
+
+	rec, jobs = list("status=PENDING&limit=2&order=desc")
+	if rec.Code != http.StatusOK || len(jobs) != 2 || jobs[0].ID != "job-3" || jobs[1].ID != "job-2" {
+		t.Fatalf("expected job-3 and job-2 newest first, got status %d and %+v", rec.Code, jobs)
+	}
+	rec, jobs = list("status=PENDING&limit=2&order=desc&after=" + rec.Header().Get("X-Next-Cursor"))
+	if rec.Code != http.StatusOK || len(jobs) != 1 || jobs[0].ID != "job-1" {
+		t.Fatalf("expected job-1 on the second newest first page, got status %d and %+v", rec.Code, jobs)
+	}
+	if rec, jobs := list("status=PENDING&order=asc"); rec.Code != http.StatusOK || len(jobs) != 3 || jobs[0].ID != "job-1" {
+		t.Errorf("expected order=asc to list oldest first, got status %d and %+v", rec.Code, jobs)
+	}
+
+	for _, query := range []string{"status=PENDING&after=bogus", "status=PENDING&limit=-1", "tag=email&limit=2", "status=PENDING&order=newest", "tag=email&order=desc"} {
+		if rec, _ := list(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestJobHandler_ListJobs_MetadataFilter(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	for _, body := range []string{
+		`{"tenant_id":"tenant-1","payload":"a","metadata":{"user_id":"42","source":"web"}}`,
+		`{"tenant_id":"tenant-1","payload":"b","metadata":{"user_id":"7"}}`,
+	} {
+		rec := httptest.NewRecorder()
+		h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ListJobs(rec, httptest.NewRequest(http.MethodGet, "/jobs?status=PENDING&meta.user_id=42", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var jobs []models.Job
+	if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+		t.Fatalf("failed to decode jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Payload != "a" || jobs[0].Metadata["source"] != "web" {
+		t.Errorf("expected only the job of user 42 with its metadata, got %+v", jobs)
+	}
+
+	for _, query := range []string{"status=PENDING&meta.=42", "status=PENDING&meta.user_id=42&meta.user_id=7", "tag=email&meta.user_id=42"} {
+		rec := httptest.NewRecorder()
+		h.ListJobs(rec, httptest.NewRequest(http.MethodGet, "/jobs?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.CreateJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tenant_id":"tenant-1","payload":"c","metadata":{"":"x"}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an empty metadata key, got %d", rec.Code)
+	}
+}
+
+func TestJobHandler_ListJobs_MultipleStatuses(t *testing.T) {
+	h, repo := newTestHandler(t)
+	ctx := context.Background()
+
+	for i, status := range []models.JobStatus{models.StatusPending, models.StatusRunning, models.StatusDone} {
+		id := fmt.Sprintf("job-%d", i+1)
+		if err := repo.CreateJob(ctx, &models.Job{ID: id, TenantID: "tenant-1", Payload: "work", Status: models.StatusPending}); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if err := repo.UpdateJobStatus(ctx, id, status); err != nil {
+			t.Fatalf("failed to update job: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+		count    int
+	}{
+		{name: "single status", query: "status=PENDING", expected: http.StatusOK, count: 1},
+		{name: "comma-separated", query: "status=PENDING,RUNNING", expected: http.StatusOK, count: 2},
+		{name: "repeated", query: "status=PENDING&status=DONE", expected: http.StatusOK, count: 2},
+		{name: "duplicates", query: "status=RUNNING,RUNNING", expected: http.StatusOK, count: 1},
+		{name: "invalid value", query: "status=PENDING,BOGUS", expected: http.StatusBadRequest},
+		{name: "empty", query: "status=,", expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ListJobs(rec, httptest.NewRequest(http.MethodGet, "/jobs?"+tt.query, nil))
+			if rec.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+			if tt.expected != http.StatusOK {
+				return
+			}
+
+			var jobs []models.Job
+			if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+				t.Fatalf("failed to decode jobs: %v", err)
+			}
+			if len(jobs) != tt.count {
+				t.Errorf("expected %d jobs, got %d", tt.count, len(jobs))
+			}
+		})
+	}
+}
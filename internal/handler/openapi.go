@@ -0,0 +1,23 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the hand-maintained OpenAPI 3 description of the API. Keep it in step
+// with the handlers and the models it describes.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI handles GET /openapi.json and serves the API's OpenAPI 3 description
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"job-queue/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// openAPIDocument is the part of the OpenAPI document the tests inspect
+type openAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Paths      map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+		Responses map[string]json.RawMessage `json:"responses"`
+	} `json:"components"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	OpenAPI(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("expected a valid JSON document, got %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("expected an OpenAPI 3 document, got version %q", doc.OpenAPI)
+	}
+	for _, path := range []string{"/jobs", "/jobs/{id}", "/dlq", "/metrics"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("expected %s to be described", path)
+		}
+	}
+
+	// Every reference must point at a defined component
+	for _, ref := range regexp.MustCompile(`"\$ref":\s*"#/components/(schemas|responses)/(\w+)"`).FindAllStringSubmatch(rec.Body.String(), -1) {
+		var found bool
+		if ref[1] == "schemas" {
+			_, found = doc.Components.Schemas[ref[2]]
+		} else {
+			_, found = doc.Components.Responses[ref[2]]
+		}
+		if !found {
+			t.Errorf("reference to undefined %s %q", ref[1], ref[2])
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	OpenAPI(rec, httptest.NewRequest(http.MethodPost, "/openapi.json", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", rec.Code)
+	}
+}
+
+// TestOpenAPI_SchemasMatchModels fails when a model gains or loses a JSON field without
+// the hand-maintained schema following
+func TestOpenAPI_SchemasMatchModels(t *testing.T) {
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("expected a valid JSON document, got %v", err)
+	}
+
+	for name, model := range map[string]interface{}{
+		"Job":                     models.Job{},
+		"CreateJobRequest":        models.CreateJobRequest{},
+		"UpdateJobRequest":        models.UpdateJobRequest{},
+		"FieldError":              models.FieldError{},
+		"ValidationErrorResponse": models.ValidationErrorResponse{},
+		"ErrorResponse":           models.ErrorResponse{},
+		"ErrorDetail":             models.ErrorDetail{},
+		"JobAttempt":              models.JobAttempt{},
+		"DeadLetterJob":           models.DeadLetterJob{},
+	} {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("expected a %s schema", name)
+			continue
+		}
+
+		var documented []string
+		for property := range schema.Properties {
+			documented = append(documented, property)
+		}
+		sort.Strings(documented)
+
+		fields := jsonFieldNames(reflect.TypeOf(model))
+		if !reflect.DeepEqual(documented, fields) {
+			t.Errorf("%s schema lists %v, the model has %v", name, documented, fields)
+		}
+	}
+}
+
+// jsonFieldNames returns the sorted JSON names of a struct's encoded fields
+func jsonFieldNames(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = typ.Field(i).Name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
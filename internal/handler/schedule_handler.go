@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"job-queue/internal/models"
+	"job-queue/internal/service"
+	"log"
+	"net/http"
+)
+
+// ScheduleHandler handles HTTP requests for recurring schedules
+type ScheduleHandler struct {
+	schedulerService *service.SchedulerService
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(schedulerService *service.SchedulerService) *ScheduleHandler {
+	return &ScheduleHandler{
+		schedulerService: schedulerService,
+	}
+}
+
+// CreateSchedule handles POST /schedules
+func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CreateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTenant(w, r, &req.TenantID) {
+		return
+	}
+
+	if req.TenantID == "" {
+		http.Error(w, "tenant_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.CronExpr == "" {
+		http.Error(w, "cron_expr is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Payload == "" {
+		http.Error(w, "payload is required", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.schedulerService.CreateSchedule(r.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCronExpr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("error creating schedule: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "schedule creation failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(schedule); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// ListSchedules handles GET /schedules
+func (h *ScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// An authenticated caller only lists its own tenant's schedules
+	authTenant, _ := TenantFromContext(r.Context())
+	schedules, err := h.schedulerService.ListSchedules(r.Context(), authTenant)
+	if err != nil {
+		log.Printf("error listing schedules: %v", err)
+		if writeQueryTimeout(w, err) {
+			return
+		}
+		http.Error(w, "failed to list schedules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schedules); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"encoding/json"
+	"job-queue/internal/models"
+	"log"
+	"net/http"
+)
+
+// WorkerStatusSource reports a worker's current status; it is satisfied by *service.WorkerService
+type WorkerStatusSource interface {
+	Status() models.WorkerStatus
+}
+
+// WorkerStatusHandler serves a worker's status on its admin port
+type WorkerStatusHandler struct {
+	worker WorkerStatusSource
+}
+
+// NewWorkerStatusHandler creates a new worker status handler
+func NewWorkerStatusHandler(worker WorkerStatusSource) *WorkerStatusHandler {
+	return &WorkerStatusHandler{
+		worker: worker,
+	}
+}
+
+// Status handles GET /status and reports the worker ID and the jobs it is processing
+func (h *WorkerStatusHandler) Status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.worker.Status()); err != nil {
+		log.Printf("error encoding worker status: %v", err)
+	}
+}
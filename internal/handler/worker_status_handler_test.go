@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"job-queue/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// staticWorkerStatus is a WorkerStatusSource reporting a fixed status
+type staticWorkerStatus models.WorkerStatus
+
+func (s staticWorkerStatus) Status() models.WorkerStatus {
+	return models.WorkerStatus(s)
+}
+
+func TestWorkerStatusHandler_Status(t *testing.T) {
+	h := NewWorkerStatusHandler(staticWorkerStatus{
+		WorkerID: "host:42",
+		Queue:    models.DefaultQueue,
+		ActiveJobs: []models.ActiveJob{
+			{JobID: "job-1", TenantID: "tenant-1", Queue: models.DefaultQueue, Attempt: 1, StartedAt: time.Now().Add(-time.Minute), RunningSeconds: 60},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	h.Status(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected application/json, got %q", contentType)
+	}
+
+	var status models.WorkerStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if status.WorkerID != "host:42" || len(status.ActiveJobs) != 1 || status.ActiveJobs[0].JobID != "job-1" || status.ActiveJobs[0].RunningSeconds != 60 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Status(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", rec.Code)
+	}
+}
@@ -2,8 +2,17 @@ package metrics
 
 import (
 	"sync"
+	"time"
 )
 
+// QueueKeyPrefix starts the snapshot keys of the per-queue counters
+const QueueKeyPrefix = "by_queue."
+
+// QueueKey returns the snapshot key of a per-queue counter, such as by_queue.emails.failed_jobs
+func QueueKey(queue, counter string) string {
+	return QueueKeyPrefix + queue + "." + counter
+}
+
 // Metrics tracks system metrics
 type Metrics struct {
 	mu sync.RWMutex
@@ -11,51 +20,171 @@ type Metrics struct {
 	totalJobs     int64
 	completedJobs int64
 	failedJobs    int64
+	dlqJobs       int64
 	retriedJobs   int64
+	reclaimedJobs int64
+	emptyLeases   int64
+	leaseErrors   int64
+	dbWriteErrors int64
+
+	// queueCompleted and queueFailed break completed and failed jobs down by queue
+	queueCompleted map[string]int64
+	queueFailed    map[string]int64
+
+	// startTime and lastProcessed are liveness signals rather than counters, so Reset keeps them
+	startTime     time.Time
+	lastProcessed time.Time
+
+	emitter Emitter
 }
 
-// NewMetrics creates a new metrics instance
+// Emitter receives every counter increment as it happens, to push it to an external system
+type Emitter interface {
+	Count(name string, delta int64)
+}
+
+// NewMetrics creates a new metrics instance, taking the current time as the process start
 func NewMetrics() *Metrics {
-	return &Metrics{}
+	return &Metrics{startTime: time.Now()}
 }
 
-// IncrementTotalJobs increments the total jobs counter
-func (m *Metrics) IncrementTotalJobs() {
+// SetEmitter pushes every later counter increment to e as well
+func (m *Metrics) SetEmitter(e Emitter) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.totalJobs++
+	m.emitter = e
 }
 
-// IncrementCompletedJobs increments the completed jobs counter
-func (m *Metrics) IncrementCompletedJobs() {
+// add adds delta to a counter and passes the increment on to the emitter, if any
+func (m *Metrics) add(counter *int64, name string, delta int64) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.completedJobs++
+	*counter += delta
+	emitter := m.emitter
+	m.mu.Unlock()
+
+	if emitter != nil {
+		emitter.Count(name, delta)
+	}
 }
 
-// IncrementFailedJobs increments the failed jobs counter
-func (m *Metrics) IncrementFailedJobs() {
+// addQueue increments a per-queue counter and passes the increment on to the emitter, if any
+func (m *Metrics) addQueue(counts *map[string]int64, queue, name string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.failedJobs++
+	if *counts == nil {
+		*counts = make(map[string]int64)
+	}
+	(*counts)[queue]++
+	emitter := m.emitter
+	m.mu.Unlock()
+
+	if emitter != nil {
+		emitter.Count(QueueKey(queue, name), 1)
+	}
+}
+
+// IncrementTotalJobs increments the total jobs counter
+func (m *Metrics) IncrementTotalJobs() {
+	m.add(&m.totalJobs, "total_jobs", 1)
+}
+
+// IncrementCompletedJobs increments the completed jobs counter, overall and for the job's queue
+func (m *Metrics) IncrementCompletedJobs(queue string) {
+	m.add(&m.completedJobs, "completed_jobs", 1)
+	m.addQueue(&m.queueCompleted, queue, "completed_jobs")
+}
+
+// IncrementFailedJobs increments the failed jobs counter, overall and for the job's queue
+func (m *Metrics) IncrementFailedJobs(queue string) {
+	m.add(&m.failedJobs, "failed_jobs", 1)
+	m.addQueue(&m.queueFailed, queue, "failed_jobs")
+}
+
+// IncrementDLQJobs increments the counter of jobs moved to the dead letter queue
+func (m *Metrics) IncrementDLQJobs() {
+	m.add(&m.dlqJobs, "dlq_jobs", 1)
 }
 
 // IncrementRetriedJobs increments the retried jobs counter
 func (m *Metrics) IncrementRetriedJobs() {
+	m.add(&m.retriedJobs, "retried_jobs", 1)
+}
+
+// AddReclaimedJobs adds n to the reclaimed jobs counter
+func (m *Metrics) AddReclaimedJobs(n int64) {
+	m.add(&m.reclaimedJobs, "reclaimed_jobs", n)
+}
+
+// IncrementEmptyLeases increments the counter of lease attempts that found no job
+func (m *Metrics) IncrementEmptyLeases() {
+	m.add(&m.emptyLeases, "empty_leases", 1)
+}
+
+// IncrementLeaseErrors increments the counter of lease attempts that failed
+func (m *Metrics) IncrementLeaseErrors() {
+	m.add(&m.leaseErrors, "lease_errors", 1)
+}
+
+// IncrementDBWriteErrors increments the counter of writes that failed because the database
+// was read-only or the disk was full
+func (m *Metrics) IncrementDBWriteErrors() {
+	m.add(&m.dbWriteErrors, "db_write_errors", 1)
+}
+
+// SetLastProcessed records when this process last finished a job successfully
+func (m *Metrics) SetLastProcessed(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastProcessed = t
+}
+
+// Reset zeroes all counters
+func (m *Metrics) Reset() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.retriedJobs++
+	m.totalJobs = 0
+	m.completedJobs = 0
+	m.failedJobs = 0
+	m.dlqJobs = 0
+	m.retriedJobs = 0
+	m.reclaimedJobs = 0
+	m.emptyLeases = 0
+	m.leaseErrors = 0
+	m.dbWriteErrors = 0
+	m.queueCompleted = nil
+	m.queueFailed = nil
 }
 
-// GetSnapshot returns a snapshot of all metrics
+// GetSnapshot returns a snapshot of all metrics. The by_queue section holds the per-queue
+// counters under keys built with QueueKey. start_time and last_processed_at are Unix seconds,
+// and last_processed_at is 0 until a job has been processed.
 func (m *Metrics) GetSnapshot() map[string]int64 {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return map[string]int64{
-		"total_jobs":     m.totalJobs,
-		"completed_jobs": m.completedJobs,
-		"failed_jobs":    m.failedJobs,
-		"retried_jobs":   m.retriedJobs,
+	snapshot := map[string]int64{
+		"total_jobs":      m.totalJobs,
+		"completed_jobs":  m.completedJobs,
+		"failed_jobs":     m.failedJobs,
+		"dlq_jobs":        m.dlqJobs,
+		"retried_jobs":    m.retriedJobs,
+		"reclaimed_jobs":  m.reclaimedJobs,
+		"empty_leases":    m.emptyLeases,
+		"lease_errors":    m.leaseErrors,
+		"db_write_errors": m.dbWriteErrors,
+
+		"start_time":        m.startTime.Unix(),
+		"uptime_seconds":    int64(time.Since(m.startTime) / time.Second),
+		"last_processed_at": 0,
+	}
+	if !m.lastProcessed.IsZero() {
+		snapshot["last_processed_at"] = m.lastProcessed.Unix()
+	}
+	for queue, n := range m.queueCompleted {
+		snapshot[QueueKey(queue, "completed_jobs")] = n
 	}
+	for queue, n := range m.queueFailed {
+		snapshot[QueueKey(queue, "failed_jobs")] = n
+	}
+
+	return snapshot
 }
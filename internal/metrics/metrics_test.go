@@ -3,6 +3,7 @@ package metrics
 import (
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestMetrics_IncrementTotalJobs(t *testing.T) {
@@ -17,7 +18,7 @@ func TestMetrics_IncrementTotalJobs(t *testing.T) {
 
 func TestMetrics_IncrementCompletedJobs(t *testing.T) {
 	m := NewMetrics()
-	m.IncrementCompletedJobs()
+	m.IncrementCompletedJobs("default")
 
 	snapshot := m.GetSnapshot()
 	if snapshot["completed_jobs"] != 1 {
@@ -27,7 +28,7 @@ func TestMetrics_IncrementCompletedJobs(t *testing.T) {
 
 func TestMetrics_IncrementFailedJobs(t *testing.T) {
 	m := NewMetrics()
-	m.IncrementFailedJobs()
+	m.IncrementFailedJobs("default")
 
 	snapshot := m.GetSnapshot()
 	if snapshot["failed_jobs"] != 1 {
@@ -35,6 +36,16 @@ func TestMetrics_IncrementFailedJobs(t *testing.T) {
 	}
 }
 
+func TestMetrics_IncrementDLQJobs(t *testing.T) {
+	m := NewMetrics()
+	m.IncrementDLQJobs()
+
+	snapshot := m.GetSnapshot()
+	if snapshot["dlq_jobs"] != 1 {
+		t.Errorf("expected dlq_jobs 1, got %d", snapshot["dlq_jobs"])
+	}
+}
+
 func TestMetrics_IncrementRetriedJobs(t *testing.T) {
 	m := NewMetrics()
 	m.IncrementRetriedJobs()
@@ -45,6 +56,57 @@ func TestMetrics_IncrementRetriedJobs(t *testing.T) {
 	}
 }
 
+func TestMetrics_AddReclaimedJobs(t *testing.T) {
+	m := NewMetrics()
+	m.AddReclaimedJobs(3)
+	m.AddReclaimedJobs(2)
+
+	snapshot := m.GetSnapshot()
+	if snapshot["reclaimed_jobs"] != 5 {
+		t.Errorf("expected reclaimed_jobs 5, got %d", snapshot["reclaimed_jobs"])
+	}
+}
+
+func TestMetrics_LeaseCounters(t *testing.T) {
+	m := NewMetrics()
+	m.IncrementEmptyLeases()
+	m.IncrementEmptyLeases()
+	m.IncrementLeaseErrors()
+
+	snapshot := m.GetSnapshot()
+	if snapshot["empty_leases"] != 2 {
+		t.Errorf("expected empty_leases 2, got %d", snapshot["empty_leases"])
+	}
+	if snapshot["lease_errors"] != 1 {
+		t.Errorf("expected lease_errors 1, got %d", snapshot["lease_errors"])
+	}
+}
+
+func TestMetrics_ByQueue(t *testing.T) {
+	m := NewMetrics()
+	m.IncrementCompletedJobs("emails")
+	m.IncrementCompletedJobs("emails")
+	m.IncrementCompletedJobs("reports")
+	m.IncrementFailedJobs("reports")
+
+	snapshot := m.GetSnapshot()
+	expected := map[string]int64{
+		"completed_jobs":                  3,
+		"failed_jobs":                     1,
+		"by_queue.emails.completed_jobs":  2,
+		"by_queue.reports.completed_jobs": 1,
+		"by_queue.reports.failed_jobs":    1,
+	}
+	for key, want := range expected {
+		if snapshot[key] != want {
+			t.Errorf("expected %s %d, got %d", key, want, snapshot[key])
+		}
+	}
+	if _, ok := snapshot[QueueKey("emails", "failed_jobs")]; ok {
+		t.Error("expected no failed_jobs entry for a queue without failures")
+	}
+}
+
 func TestMetrics_ConcurrentAccess(t *testing.T) {
 	m := NewMetrics()
 	var wg sync.WaitGroup
@@ -55,8 +117,8 @@ func TestMetrics_ConcurrentAccess(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			m.IncrementTotalJobs()
-			m.IncrementCompletedJobs()
-			m.IncrementFailedJobs()
+			m.IncrementCompletedJobs("default")
+			m.IncrementFailedJobs("default")
 			m.IncrementRetriedJobs()
 		}()
 	}
@@ -76,8 +138,9 @@ func TestMetrics_GetSnapshot(t *testing.T) {
 	m := NewMetrics()
 	m.IncrementTotalJobs()
 	m.IncrementTotalJobs()
-	m.IncrementCompletedJobs()
-	m.IncrementFailedJobs()
+	m.IncrementCompletedJobs("default")
+	m.IncrementFailedJobs("default")
+	m.IncrementDLQJobs()
 	m.IncrementRetriedJobs()
 
 	snapshot := m.GetSnapshot()
@@ -86,6 +149,7 @@ func TestMetrics_GetSnapshot(t *testing.T) {
 		"total_jobs":     2,
 		"completed_jobs": 1,
 		"failed_jobs":    1,
+		"dlq_jobs":       1,
 		"retried_jobs":   1,
 	}
 
@@ -95,3 +159,54 @@ func TestMetrics_GetSnapshot(t *testing.T) {
 		}
 	}
 }
+
+func TestMetrics_Reset(t *testing.T) {
+	m := NewMetrics()
+	m.IncrementTotalJobs()
+	m.IncrementCompletedJobs("default")
+	m.IncrementFailedJobs("default")
+	m.IncrementDLQJobs()
+	m.IncrementRetriedJobs()
+	m.AddReclaimedJobs(3)
+	m.IncrementEmptyLeases()
+	m.IncrementLeaseErrors()
+
+	m.SetLastProcessed(time.Now())
+
+	m.Reset()
+
+	snapshot := m.GetSnapshot()
+	for name, value := range snapshot {
+		if name == "start_time" || name == "uptime_seconds" || name == "last_processed_at" {
+			continue
+		}
+		if value != 0 {
+			t.Errorf("expected %s 0 after reset, got %d", name, value)
+		}
+	}
+	if snapshot["start_time"] == 0 || snapshot["last_processed_at"] == 0 {
+		t.Errorf("expected the liveness signals to survive a reset, got %v", snapshot)
+	}
+}
+
+func TestMetrics_Liveness(t *testing.T) {
+	before := time.Now().Unix()
+	m := NewMetrics()
+
+	snapshot := m.GetSnapshot()
+	if start := snapshot["start_time"]; start < before || start > time.Now().Unix() {
+		t.Errorf("expected start_time to be when NewMetrics ran, got %d", start)
+	}
+	if snapshot["uptime_seconds"] != 0 {
+		t.Errorf("expected no uptime yet, got %d", snapshot["uptime_seconds"])
+	}
+	if snapshot["last_processed_at"] != 0 {
+		t.Errorf("expected last_processed_at 0 before any job, got %d", snapshot["last_processed_at"])
+	}
+
+	processed := time.Unix(1700000000, 0)
+	m.SetLastProcessed(processed)
+	if got := m.GetSnapshot()["last_processed_at"]; got != processed.Unix() {
+		t.Errorf("expected last_processed_at %d, got %d", processed.Unix(), got)
+	}
+}
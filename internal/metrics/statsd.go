@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+)
+
+// StatsDEmitter pushes counter increments to a StatsD server over UDP, for example a Datadog agent
+type StatsDEmitter struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsDEmitter creates an emitter sending to addr (host:port). A non-empty prefix is
+// prepended to every metric name with a dot.
+func NewStatsDEmitter(addr, prefix string) (*StatsDEmitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd: %w", err)
+	}
+
+	if prefix != "" {
+		prefix += "."
+	}
+
+	return &StatsDEmitter{
+		conn:   conn,
+		prefix: prefix,
+	}, nil
+}
+
+// Count sends a counter increment. Delivery is best effort, so send errors are ignored
+// rather than slowing down job processing.
+func (e *StatsDEmitter) Count(name string, delta int64) {
+	fmt.Fprintf(e.conn, "%s%s:%d|c", e.prefix, name, delta)
+}
+
+// Close closes the connection to the StatsD server
+func (e *StatsDEmitter) Close() error {
+	return e.conn.Close()
+}
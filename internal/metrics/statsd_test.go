@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsDEmitter_PushesIncrements(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	emitter, err := NewStatsDEmitter(server.LocalAddr().String(), "jobqueue")
+	if err != nil {
+		t.Fatalf("failed to create emitter: %v", err)
+	}
+	defer emitter.Close()
+
+	m := NewMetrics()
+	m.SetEmitter(emitter)
+	m.IncrementCompletedJobs("default")
+	m.AddReclaimedJobs(3)
+
+	buf := make([]byte, 512)
+	for _, expected := range []string{"jobqueue.completed_jobs:1|c", "jobqueue.by_queue.default.completed_jobs:1|c", "jobqueue.reclaimed_jobs:3|c"} {
+		server.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := server.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to read packet: %v", err)
+		}
+		if got := string(buf[:n]); got != expected {
+			t.Errorf("expected packet %q, got %q", expected, got)
+		}
+	}
+
+	if got := m.GetSnapshot()["completed_jobs"]; got != 1 {
+		t.Errorf("expected completed_jobs 1, got %d", got)
+	}
+}
@@ -1,38 +1,243 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // JobStatus represents the state of a job
 type JobStatus string
 
 const (
-	StatusPending JobStatus = "PENDING"
-	StatusRunning JobStatus = "RUNNING"
-	StatusDone    JobStatus = "DONE"
-	StatusFailed  JobStatus = "FAILED"
+	StatusPending   JobStatus = "PENDING"
+	StatusRunning   JobStatus = "RUNNING"
+	StatusDone      JobStatus = "DONE"
+	StatusFailed    JobStatus = "FAILED"
+	StatusCancelled JobStatus = "CANCELLED"
 )
 
+// IsValid reports whether s is one of the known job statuses
+func (s JobStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusRunning, StatusDone, StatusFailed, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether a job in this status will not change status again
+func (s JobStatus) IsTerminal() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCancelled
+}
+
+// StatsKeyDLQ is the JobStats key counting jobs in the dead letter queue
+const StatsKeyDLQ = "DLQ"
+
+// StatsKeyPaused is the JobStats key that is 1 while leasing is paused and 0 otherwise
+const StatsKeyPaused = "PAUSED"
+
+// JobStats maps each job status, plus StatsKeyDLQ, to the number of jobs in it, and
+// StatsKeyPaused to whether leasing is paused
+type JobStats map[string]int
+
+// DefaultQueue is the queue jobs are placed on when none is specified
+const DefaultQueue = "default"
+
 // Job represents a job in the system
 type Job struct {
 	ID             string     `json:"id"`
 	TenantID       string     `json:"tenant_id"`
+	Queue          string     `json:"queue"`
 	IdempotencyKey string     `json:"idempotency_key,omitempty"`
 	Payload        string     `json:"payload"`
+	// PayloadJSON is true when Payload holds JSON text that is emitted as structured JSON
+	PayloadJSON    bool       `json:"-"`
+	// PayloadEncoding is PayloadEncodingBase64 when Payload holds base64 of binary data
+	PayloadEncoding string    `json:"payload_encoding,omitempty"`
+	// DedupHash identifies the job's content for dedup_window lookups
+	DedupHash      string     `json:"-"`
+	Tags           []string   `json:"tags,omitempty"`
+	// Metadata holds client-supplied key/value pairs, such as user_id or source, that
+	// listings can filter on
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	// DependsOn lists the jobs that must be DONE before this job is leased
+	DependsOn      []string   `json:"depends_on,omitempty"`
+	Result         string     `json:"result,omitempty"`
 	Status         JobStatus  `json:"status"`
 	MaxRetries     int        `json:"max_retries"`
 	RetryCount     int        `json:"retry_count"`
 	LeasedAt       *time.Time `json:"leased_at,omitempty"`
 	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	// LeasedBy is the worker that last leased the job
+	LeasedBy       string     `json:"leased_by,omitempty"`
+	// DeadLetterID is the dead letter entry of a FAILED job that was kept after failing
+	DeadLetterID   string     `json:"dead_letter_id,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	// NextRetryAt is when a PENDING job rescheduled by its handler may be leased again
+	NextRetryAt    *time.Time `json:"next_retry_at,omitempty"`
+	// DeletedAt is when a FAILED job kept after moving to the dead letter queue was tombstoned
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	// ExpiresAt is when a job that has not started stops being worth running
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	// BypassLimits is set on urgent jobs submitted with a privileged API key, which are
+	// leased even while their tenant is at its concurrent running limit
+	BypassLimits   bool       `json:"bypass_limits,omitempty"`
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
+// Attempt returns the 1-based attempt the job is on: the one running or about to run, or
+// the last one once the job has finished
+func (j Job) Attempt() int {
+	return j.RetryCount + 1
+}
+
+// AttemptsRemaining returns how many retries are left after the current attempt
+func (j Job) AttemptsRemaining() int {
+	return max(j.MaxRetries-j.RetryCount, 0)
+}
+
+// JobDetail is the GET /jobs/{id} view of a job. It adds attempt and attempts_remaining,
+// derived from retry_count and max_retries, so clients can show "attempt 2 of 4".
+type JobDetail struct {
+	*Job
+}
+
+// MarshalJSON emits the job as Job.MarshalJSON does, followed by the derived attempt counts
+func (d JobDetail) MarshalJSON() ([]byte, error) {
+	type job Job
+	return json.Marshal(struct {
+		job
+		Payload           json.RawMessage `json:"payload"`
+		Attempt           int             `json:"attempt"`
+		AttemptsRemaining int             `json:"attempts_remaining"`
+	}{job(*d.Job), EncodePayload(d.Payload, d.PayloadJSON), d.Attempt(), d.AttemptsRemaining()})
+}
+
+// JobEvent represents a job status transition
+type JobEvent struct {
+	JobID  string    `json:"job_id"`
+	Status JobStatus `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+// JobOutcome is what a worker publishes when it finishes a job for good: DONE with the
+// handler's result, or FAILED with the reason the job moved to the dead letter queue
+type JobOutcome struct {
+	JobID         string    `json:"job_id"`
+	TenantID      string    `json:"tenant_id"`
+	Queue         string    `json:"queue"`
+	Status        JobStatus `json:"status"`
+	Result        string    `json:"result,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	FinishedAt    time.Time `json:"finished_at"`
+}
+
+// ActiveJob is a job a worker is processing right now
+type ActiveJob struct {
+	JobID          string    `json:"job_id"`
+	TenantID       string    `json:"tenant_id"`
+	Queue          string    `json:"queue"`
+	Attempt        int       `json:"attempt"`
+	StartedAt      time.Time `json:"started_at"`
+	RunningSeconds float64   `json:"running_seconds"`
+}
+
+// WorkerStatus reports what a worker process is doing, for diagnosing a stuck worker
+type WorkerStatus struct {
+	WorkerID   string      `json:"worker_id"`
+	Queue      string      `json:"queue"`
+	Draining   bool        `json:"draining"`
+	ActiveJobs []ActiveJob `json:"active_jobs"`
+}
+
 // CreateJobRequest represents a request to create a job
 type CreateJobRequest struct {
-	TenantID       string `json:"tenant_id"`
-	IdempotencyKey string `json:"idempotency_key,omitempty"`
-	Payload        string `json:"payload"`
-	MaxRetries     *int   `json:"max_retries,omitempty"`
+	// ID is an optional client-chosen job ID; a UUID is generated when it is empty
+	ID             string          `json:"id,omitempty"`
+	TenantID       string          `json:"tenant_id"`
+	Queue          string          `json:"queue,omitempty"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	// Payload is either a string or any other JSON value
+	Payload        json.RawMessage `json:"payload"`
+	// ContentType is the media type of a string payload, such as application/json; it is
+	// only used to validate the payload and is not stored
+	ContentType    string          `json:"content_type,omitempty"`
+	// PayloadEncoding is "utf8" (the default) or "base64" for a string payload holding
+	// base64 of binary data, which workers decode before calling the handler
+	PayloadEncoding string         `json:"payload_encoding,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	// Metadata holds optional key/value pairs that GET /jobs can filter on with meta.<key>
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	DependsOn      []string        `json:"depends_on,omitempty"`
+	MaxRetries     *int            `json:"max_retries,omitempty"`
+	// ExpiresAt fails the job instead of running it if it has not started by then
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	// DedupWindow, such as "5m", returns the tenant's identical job (same queue and payload)
+	// created within the window instead of creating another one
+	DedupWindow    string          `json:"dedup_window,omitempty"`
+	// BypassLimits skips the tenant's submission rate and concurrent running limits for an
+	// urgent job; the API only accepts it from privileged API keys
+	BypassLimits   bool            `json:"bypass_limits,omitempty"`
+}
+
+// FieldError describes why one field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is the body of a 400 response listing every invalid field
+type ValidationErrorResponse struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// ErrorResponse is the body of an error response
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes why a request failed. Code is stable for clients to match on;
+// Message is meant for people.
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// UpdateJobRequest represents a partial update of a PENDING job
+type UpdateJobRequest struct {
+	MaxRetries *int            `json:"max_retries"`
+	// Payload replaces the job's payload, as a string or any other JSON value
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// IdempotencyKeyEntry represents an idempotency key in use by a tenant and the job it maps to
+type IdempotencyKeyEntry struct {
+	IdempotencyKey string    `json:"idempotency_key"`
+	JobID          string    `json:"job_id"`
+	Status         JobStatus `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// BatchJobResult represents the outcome of a single item in a batch submission
+type BatchJobResult struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// UpdateJobStatusBatchRequest represents a status change applied to several jobs at once
+type UpdateJobStatusBatchRequest struct {
+	IDs    []string  `json:"ids"`
+	Status JobStatus `json:"status"`
+}
+
+// JobStatusUpdateResult represents the outcome for one job of a batch status change
+type JobStatusUpdateResult struct {
+	ID      string `json:"id"`
+	Updated bool   `json:"updated"`
+	Error   string `json:"error,omitempty"`
 }
 
 // DeadLetterJob represents a job that has permanently failed
@@ -41,6 +246,36 @@ type DeadLetterJob struct {
 	JobID        string    `json:"job_id"`
 	TenantID     string    `json:"tenant_id"`
 	Payload      string    `json:"payload"`
+	PayloadJSON  bool      `json:"-"`
+	PayloadEncoding string `json:"payload_encoding,omitempty"`
 	FailureReason string   `json:"failure_reason"`
 	FailedAt     time.Time `json:"failed_at"`
+	Attempts     []JobAttempt `json:"attempts"`
+}
+
+// JobPosition reports how many PENDING jobs of the same queue are leased before a job
+type JobPosition struct {
+	JobID     string    `json:"job_id"`
+	TenantID  string    `json:"tenant_id"`
+	Queue     string    `json:"queue"`
+	Status    JobStatus `json:"status"`
+	JobsAhead int       `json:"jobs_ahead"`
+}
+
+// JobTransition is one entry in a job's audit trail of status changes. FromStatus is empty
+// for the job's creation; WorkerID is set when the job entered or left RUNNING.
+type JobTransition struct {
+	JobID      string    `json:"job_id"`
+	TenantID   string    `json:"tenant_id"`
+	FromStatus JobStatus `json:"from_status,omitempty"`
+	ToStatus   JobStatus `json:"to_status"`
+	At         time.Time `json:"at"`
+	WorkerID   string    `json:"worker_id,omitempty"`
+}
+
+// JobAttempt records why a single processing attempt of a job failed
+type JobAttempt struct {
+	Attempt int       `json:"attempt"`
+	Reason  string    `json:"reason"`
+	At      time.Time `json:"at"`
 }
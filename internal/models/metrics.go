@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// MetricsSnapshot represents the metrics counters captured at a point in time
+type MetricsSnapshot struct {
+	TakenAt  time.Time        `json:"taken_at"`
+	Counters map[string]int64 `json:"counters"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidPayload is returned when a payload is not valid JSON
+var ErrInvalidPayload = errors.New("payload must be valid JSON")
+
+// Payload encodings. A utf8 payload is handed to handlers as stored; a base64 payload is
+// decoded first, so jobs can carry binary data in the TEXT payload column.
+const (
+	PayloadEncodingUTF8   = "utf8"
+	PayloadEncodingBase64 = "base64"
+)
+
+// DecodePayload converts a submitted payload into the text handed to handlers and reports
+// whether that text is JSON. A JSON string is unquoted, so string payloads reach handlers
+// exactly as before; any other JSON value is kept as compact JSON text. A missing or null
+// payload decodes to an empty string.
+func DecodePayload(raw json.RawMessage) (string, bool, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", false, nil
+	}
+
+	if raw[0] == '"' {
+		var payload string
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return "", false, ErrInvalidPayload
+		}
+		return payload, false, nil
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return "", false, ErrInvalidPayload
+	}
+	return compact.String(), true, nil
+}
+
+// EncodePayload returns the JSON form of a payload decoded with DecodePayload
+func EncodePayload(payload string, isJSON bool) json.RawMessage {
+	if isJSON {
+		return json.RawMessage(payload)
+	}
+	encoded, _ := json.Marshal(payload)
+	return encoded
+}
+
+// StringPayload returns the JSON form of a plain string payload, for building a CreateJobRequest
+func StringPayload(payload string) json.RawMessage {
+	return EncodePayload(payload, false)
+}
+
+// MarshalJSON emits a JSON payload as structured JSON and any other payload as a string
+func (j Job) MarshalJSON() ([]byte, error) {
+	type job Job
+	return json.Marshal(struct {
+		job
+		Payload json.RawMessage `json:"payload"`
+	}{job(j), EncodePayload(j.Payload, j.PayloadJSON)})
+}
+
+// UnmarshalJSON accepts a payload given either as a string or as structured JSON
+func (j *Job) UnmarshalJSON(data []byte) error {
+	type job Job
+	aux := struct {
+		*job
+		Payload json.RawMessage `json:"payload"`
+	}{job: (*job)(j)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	j.Payload, j.PayloadJSON, err = DecodePayload(aux.Payload)
+	return err
+}
+
+// MarshalJSON emits a JSON payload as structured JSON and any other payload as a string
+func (j DeadLetterJob) MarshalJSON() ([]byte, error) {
+	type deadLetterJob DeadLetterJob
+	return json.Marshal(struct {
+		deadLetterJob
+		Payload json.RawMessage `json:"payload"`
+	}{deadLetterJob(j), EncodePayload(j.Payload, j.PayloadJSON)})
+}
+
+// UnmarshalJSON accepts a payload given either as a string or as structured JSON
+func (j *DeadLetterJob) UnmarshalJSON(data []byte) error {
+	type deadLetterJob DeadLetterJob
+	aux := struct {
+		*deadLetterJob
+		Payload json.RawMessage `json:"payload"`
+	}{deadLetterJob: (*deadLetterJob)(j)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	j.Payload, j.PayloadJSON, err = DecodePayload(aux.Payload)
+	return err
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Schedule represents a recurring job template fired on a cron expression
+type Schedule struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	CronExpr    string     `json:"cron_expr"`
+	Payload     string     `json:"payload"`
+	MaxRetries  int        `json:"max_retries"`
+	NextFireAt  time.Time  `json:"next_fire_at"`
+	LastFiredAt *time.Time `json:"last_fired_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CreateScheduleRequest represents a request to create a schedule
+type CreateScheduleRequest struct {
+	TenantID   string `json:"tenant_id"`
+	CronExpr   string `json:"cron_expr"`
+	Payload    string `json:"payload"`
+	MaxRetries *int   `json:"max_retries,omitempty"`
+}
@@ -0,0 +1,31 @@
+// Package profiling serves net/http/pprof on its own listener, so profiles of a live API
+// server or worker can be captured without exposing them on the API port
+package profiling
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+)
+
+// Handler returns a mux serving the pprof endpoints under /debug/pprof/
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Serve serves Handler on addr in the background. A listener that fails is logged rather
+// than fatal, since profiling is never worth stopping the process for.
+func Serve(addr string) {
+	go func() {
+		log.Printf("serving pprof on http://%s/debug/pprof/", addr)
+		if err := http.ListenAndServe(addr, Handler()); err != nil {
+			log.Printf("pprof server error: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,25 @@
+package profiling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Errorf("expected a goroutine profile, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected only pprof paths to be served, got %d for /jobs", rec.Code)
+	}
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// encodePayload returns the value stored in a payload column and whether it is compressed.
+// Payloads longer than compressAbove bytes are gzipped, unless that does not make them
+// smaller; a compressAbove of zero or less stores every payload as text.
+func encodePayload(payload string, compressAbove int) (interface{}, bool, error) {
+	if compressAbove <= 0 || len(payload) <= compressAbove {
+		return payload, false, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(payload)); err != nil {
+		return nil, false, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false, fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	if buf.Len() >= len(payload) {
+		return payload, false, nil
+	}
+	return buf.Bytes(), true, nil
+}
+
+// decodePayload returns the text of a stored payload, decompressing it if needed
+func decodePayload(stored []byte, compressed bool) (string, error) {
+	if !compressed {
+		return string(stored), nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	defer zr.Close()
+
+	payload, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	return string(payload), nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import "sync"
+
+// jobNotifier wakes lease calls waiting for work when this process makes a job PENDING.
+// Changes made by other processes sharing the database are not seen, so waiters still
+// poll once their wait runs out. The zero value is ready to use.
+type jobNotifier struct {
+	mu      sync.Mutex
+	pending chan struct{}
+}
+
+// wait returns a channel that is closed the next time notify is called
+func (n *jobNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.pending == nil {
+		n.pending = make(chan struct{})
+	}
+	return n.pending
+}
+
+// notify wakes every caller currently waiting
+func (n *jobNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.pending != nil {
+		close(n.pending)
+		n.pending = nil
+	}
+}
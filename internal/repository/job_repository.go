@@ -2,24 +2,131 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"job-queue/internal/models"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// LeaseOptions controls which jobs LeaseJob and LeaseJobs pick. The limits cap how many jobs a tenant
+// may have RUNNING when a job is leased; a limit of zero or less means unlimited.
+type LeaseOptions struct {
+	MaxRunningPerTenant int
+	// TenantMaxRunning overrides MaxRunningPerTenant for individual tenants
+	TenantMaxRunning map[string]int
+	// FairScheduling leases the oldest job of the least recently served tenant
+	// instead of the oldest job in the queue
+	FairScheduling bool
+	// WorkerID is stored as the leased job's leased_by and recorded in its job_events
+	WorkerID string
+	// TenantID restricts leasing to one tenant's jobs; empty leases jobs of every tenant
+	TenantID string
+}
+
+// ErrInvalidCursor is returned when a job cursor cannot be parsed
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrWriteUnavailable is returned by Ping while writes fail because the database is
+// read-only or its disk is full
+var ErrWriteUnavailable = errors.New("database is not writable")
+
+// JobCursor marks a position in a job listing ordered by creation: the creation time and
+// insertion sequence of the last job of a page
+type JobCursor struct {
+	CreatedAt int64
+	Seq       int64
+}
+
+// String encodes the cursor for use in a URL
+func (c JobCursor) String() string {
+	return fmt.Sprintf("%d.%d", c.CreatedAt, c.Seq)
+}
+
+// ParseJobCursor decodes a cursor produced by JobCursor.String
+func ParseJobCursor(s string) (JobCursor, error) {
+	createdAt, seq, ok := strings.Cut(s, ".")
+	if !ok {
+		return JobCursor{}, ErrInvalidCursor
+	}
+
+	var c JobCursor
+	var err error
+	if c.CreatedAt, err = strconv.ParseInt(createdAt, 10, 64); err != nil {
+		return JobCursor{}, ErrInvalidCursor
+	}
+	if c.Seq, err = strconv.ParseInt(seq, 10, 64); err != nil {
+		return JobCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// JobPage selects part of a job listing. After continues behind the cursor returned with the
+// previous page and takes precedence over Offset. A Limit of zero or less returns every
+// remaining job. Descending lists newest first; a cursor only continues a listing in the
+// order it came from. Metadata keeps only the jobs whose metadata has every given pair, and a
+// non-empty TenantID only that tenant's jobs.
+type JobPage struct {
+	After      *JobCursor
+	Offset     int
+	Limit      int
+	Descending bool
+	Metadata   map[string]string
+	TenantID   string
+}
+
 // JobRepository defines the interface for job persistence
 type JobRepository interface {
 	CreateJob(ctx context.Context, job *models.Job) error
+	CreateJobsBatch(ctx context.Context, jobs []*models.Job) ([]error, error)
 	GetJobByID(ctx context.Context, id string) (*models.Job, error)
-	GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string) (*models.Job, error)
-	ListJobsByStatus(ctx context.Context, status models.JobStatus) ([]*models.Job, error)
-	LeaseJob(ctx context.Context, leaseDuration time.Duration) (*models.Job, error)
+	GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string, since time.Time) (*models.Job, error)
+	GetJobByTenantAndDedupHash(ctx context.Context, tenantID, dedupHash string, since time.Time) (*models.Job, error)
+	ListJobsByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.Job, error)
+	ListJobsByStatusPage(ctx context.Context, page JobPage, statuses ...models.JobStatus) ([]*models.Job, *JobCursor, error)
+	ListJobsByTag(ctx context.Context, tenantID, tag string, statuses ...models.JobStatus) ([]*models.Job, error)
+	SearchJobs(ctx context.Context, tenantID, query string, limit int) ([]*models.Job, error)
+	ListIdempotencyKeysByTenant(ctx context.Context, tenantID string, since time.Time) ([]*models.IdempotencyKeyEntry, error)
+	LeaseJob(ctx context.Context, queue string, leaseDuration time.Duration, limits LeaseOptions) (*models.Job, error)
+	LeaseJobs(ctx context.Context, queue string, n int, leaseDuration time.Duration, limits LeaseOptions) ([]*models.Job, error)
+	LeaseJobsWait(ctx context.Context, queue string, n int, leaseDuration time.Duration, limits LeaseOptions, wait time.Duration) ([]*models.Job, error)
+	ReclaimExpiredLeases(ctx context.Context) (int64, error)
+	ListExpiredJobs(ctx context.Context, now time.Time) ([]*models.Job, error)
+	FailExpiredJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, deadLetter bool) (bool, error)
+	SetPaused(ctx context.Context, paused bool) error
+	IsPaused(ctx context.Context) (bool, error)
 	UpdateJobStatus(ctx context.Context, id string, status models.JobStatus) error
+	UpdateJobStatusIf(ctx context.Context, id string, from, to models.JobStatus) (bool, error)
+	CompleteJob(ctx context.Context, id, result string) (bool, error)
+	RescheduleJob(ctx context.Context, id string, at time.Time) (bool, error)
+	CancelJob(ctx context.Context, id string) (bool, error)
+	UpdateJobStatusBatch(ctx context.Context, ids []string, from []models.JobStatus, to models.JobStatus) ([]bool, error)
+	UpdateMaxRetries(ctx context.Context, id string, maxRetries int) (bool, error)
+	UpdatePayload(ctx context.Context, id string, payload string, payloadJSON bool, dedupHash string) (bool, error)
 	IncrementRetryCount(ctx context.Context, id string) error
+	RetryJob(ctx context.Context, id string, attempt *models.JobAttempt) (bool, error)
 	GetRunningJobsCountByTenant(ctx context.Context, tenantID string) (int, error)
+	RecordJobAttempt(ctx context.Context, jobID string, attempt *models.JobAttempt) error
+	ListJobAttempts(ctx context.Context, jobID string) ([]models.JobAttempt, error)
+	ListJobTransitions(ctx context.Context, jobID string) ([]models.JobTransition, error)
 	MoveToDeadLetterQueue(ctx context.Context, job *models.Job, failureReason string) error
+	FailRunningJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, failureReason string) (bool, error)
 	ListDeadLetterJobs(ctx context.Context) ([]*models.DeadLetterJob, error)
+	GetDeadLetterJobByJobID(ctx context.Context, jobID string) (*models.DeadLetterJob, error)
+	ListDeadLetterJobsFiltered(ctx context.Context, tenantID string, limit, offset int) ([]*models.DeadLetterJob, int, error)
+	DeleteJobsOlderThan(ctx context.Context, status models.JobStatus, cutoff time.Time) (int64, error)
+	ListDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) ([]*models.DeadLetterJob, error)
+	DeleteDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error)
 	GetTotalJobsCount(ctx context.Context) (int, error)
 	GetCompletedJobsCount(ctx context.Context) (int, error)
 	GetFailedJobsCount(ctx context.Context) (int, error)
 	GetDeadLetterQueueCount(ctx context.Context) (int, error)
+	CountJobsByStatus(ctx context.Context, status models.JobStatus) (int, error)
+	CountJobsAheadOf(ctx context.Context, job *models.Job) (int, error)
+	CountJobsGroupedByStatus(ctx context.Context) (map[models.JobStatus]int, error)
+	CountDeadLetterByReason(ctx context.Context, tenantID string) (map[string]int, error)
+	OldestPendingJobAge(ctx context.Context) (time.Duration, error)
+	PendingJobAgePercentiles(ctx context.Context, percentiles []float64) ([]time.Duration, error)
+	Ping(ctx context.Context) error
 }
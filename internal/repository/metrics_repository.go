@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"job-queue/internal/models"
+)
+
+// MetricsSnapshotRepository defines the interface for persisting periodic metrics snapshots
+type MetricsSnapshotRepository interface {
+	RecordMetricsSnapshot(ctx context.Context, snapshot *models.MetricsSnapshot) error
+	ListMetricsSnapshots(ctx context.Context) ([]*models.MetricsSnapshot, error)
+}
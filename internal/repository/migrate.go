@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"job-queue/migrations"
+	"time"
+)
+
+// migration is one versioned schema change. Released migrations must never be edited;
+// schema changes are made by appending a new version.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// schemaMigrations lists every migration in version order. Most are .sql files in the
+// migrations directory; the Go ones add columns that databases created before the
+// migration runner existed may already have.
+var schemaMigrations = []migration{
+	{1, "baseline", sqlMigration("0001_baseline.sql")},
+	{2, "schedules", sqlMigration("0002_schedules.sql")},
+	{3, "metrics_snapshots", sqlMigration("0003_metrics_snapshots.sql")},
+	{4, "jobs_queue", func(ctx context.Context, tx *sql.Tx) error {
+		if err := addColumn(ctx, tx, "jobs", "queue", "TEXT NOT NULL DEFAULT 'default'"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_jobs_queue_status ON jobs(queue, status)`)
+		return err
+	}},
+	{5, "jobs_status_updated_at_index", sqlMigration("0005_jobs_status_updated_at_index.sql")},
+	{6, "jobs_processing_timestamps", func(ctx context.Context, tx *sql.Tx) error {
+		if err := addColumn(ctx, tx, "jobs", "started_at", "INTEGER"); err != nil {
+			return err
+		}
+		return addColumn(ctx, tx, "jobs", "finished_at", "INTEGER")
+	}},
+	{7, "job_attempts", sqlMigration("0007_job_attempts.sql")},
+	{8, "dead_letter_attempts", func(ctx context.Context, tx *sql.Tx) error {
+		return addColumn(ctx, tx, "dead_letter_jobs", "attempts", "TEXT NOT NULL DEFAULT '[]'")
+	}},
+	{9, "jobs_tags", func(ctx context.Context, tx *sql.Tx) error {
+		return addColumn(ctx, tx, "jobs", "tags", "TEXT NOT NULL DEFAULT '[]'")
+	}},
+	{10, "jobs_tenant_status_index", sqlMigration("0010_jobs_tenant_status_index.sql")},
+	{11, "jobs_result", sqlMigration("0011_jobs_result.sql")},
+	{12, "tenant_leases", sqlMigration("0012_tenant_leases.sql")},
+	{13, "millisecond_timestamps", sqlMigration("0013_millisecond_timestamps.sql")},
+	{14, "jobs_seq", sqlMigration("0014_jobs_seq.sql")},
+	{15, "jobs_idempotency_window", sqlMigration("0015_jobs_idempotency_window.sql")},
+	{16, "payload_json", sqlMigration("0016_payload_json.sql")},
+	{17, "jobs_depends_on", sqlMigration("0017_jobs_depends_on.sql")},
+	{18, "job_events", sqlMigration("0018_job_events.sql")},
+	{19, "jobs_leased_by", sqlMigration("0019_jobs_leased_by.sql")},
+	{20, "payload_compression", sqlMigration("0020_payload_compression.sql")},
+	{21, "jobs_dead_letter_id", sqlMigration("0021_jobs_dead_letter_id.sql")},
+	{22, "jobs_status_created_index", sqlMigration("0022_jobs_status_created_index.sql")},
+	{23, "jobs_next_retry_at", sqlMigration("0023_jobs_next_retry_at.sql")},
+	{24, "settings", sqlMigration("0024_settings.sql")},
+	{25, "jobs_deleted_at", sqlMigration("0025_jobs_deleted_at.sql")},
+	{26, "jobs_expires_at", sqlMigration("0026_jobs_expires_at.sql")},
+	{27, "payload_encoding", sqlMigration("0027_payload_encoding.sql")},
+	{28, "jobs_dedup_hash", sqlMigration("0028_jobs_dedup_hash.sql")},
+	{29, "jobs_bypass_limits", sqlMigration("0029_jobs_bypass_limits.sql")},
+	{30, "jobs_metadata", sqlMigration("0030_jobs_metadata.sql")},
+}
+
+// sqlMigration returns a migration step that executes an embedded .sql file
+func sqlMigration(file string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		script, err := migrations.FS.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		_, err = tx.ExecContext(ctx, string(script))
+		return err
+	}
+}
+
+// addColumn adds a column unless the table already has it
+func addColumn(ctx context.Context, tx *sql.Tx, table, column, definition string) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// migrate applies every migration newer than the database's schema version.
+// Each migration runs in its own transaction together with its schema_migrations row,
+// so processes starting at the same time apply it exactly once.
+func (r *SQLiteRepository) migrate(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := r.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := schemaMigrations[len(schemaMigrations)-1].version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d", current, latest)
+	}
+
+	for _, m := range schemaMigrations {
+		if m.version <= current {
+			continue
+		}
+		if err := r.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs one migration unless another process applied it first
+func (r *SQLiteRepository) applyMigration(ctx context.Context, m migration) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var applied int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.version).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	if err := m.up(ctx, tx); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SchemaVersion returns the highest migration version applied to the database
+func (r *SQLiteRepository) SchemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"job-queue/internal/models"
+	"time"
+)
+
+// ScheduleRepository defines the interface for recurring schedule persistence
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *models.Schedule) error
+	ListSchedules(ctx context.Context, tenantID string) ([]*models.Schedule, error)
+	ListDueSchedules(ctx context.Context, now time.Time) ([]*models.Schedule, error)
+	FireSchedule(ctx context.Context, schedule *models.Schedule, job *models.Job, nextFireAt time.Time) (bool, error)
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"job-queue/internal/models"
+	"time"
+)
+
+// RecordMetricsSnapshot stores a metrics snapshot
+func (r *SQLiteRepository) RecordMetricsSnapshot(ctx context.Context, snapshot *models.MetricsSnapshot) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		counters, err := json.Marshal(snapshot.Counters)
+		if err != nil {
+			return fmt.Errorf("failed to encode metrics snapshot: %w", err)
+		}
+
+		_, err = r.db.ExecContext(ctx,
+			"INSERT INTO metrics_snapshots (taken_at, counters) VALUES (?, ?)",
+			snapshot.TakenAt.Unix(),
+			string(counters),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record metrics snapshot: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ListMetricsSnapshots retrieves all stored metrics snapshots, oldest first
+func (r *SQLiteRepository) ListMetricsSnapshots(ctx context.Context) ([]*models.MetricsSnapshot, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT taken_at, counters
+		FROM metrics_snapshots
+		ORDER BY taken_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*models.MetricsSnapshot
+	for rows.Next() {
+		var takenAt int64
+		var counters string
+
+		if err := rows.Scan(&takenAt, &counters); err != nil {
+			return nil, fmt.Errorf("failed to scan metrics snapshot: %w", err)
+		}
+
+		snapshot := &models.MetricsSnapshot{TakenAt: time.Unix(takenAt, 0)}
+		if err := json.Unmarshal([]byte(counters), &snapshot.Counters); err != nil {
+			return nil, fmt.Errorf("failed to decode metrics snapshot: %w", err)
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate metrics snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
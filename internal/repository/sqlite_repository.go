@@ -3,23 +3,117 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"job-queue/internal/models"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 // SQLiteRepository implements JobRepository using SQLite
 type SQLiteRepository struct {
 	db *sql.DB
+	// readDB serves the read-only queries listed at SQLiteOptions.ReadPath; nil reads from db
+	readDB  *sql.DB
+	options SQLiteOptions
+	// jobsReady wakes LeaseJobsWait callers when a job may have become leasable
+	jobsReady jobNotifier
+	// writeUnavailable is set by a write failing with IsWriteUnavailable and cleared by the
+	// next write that succeeds
+	writeUnavailable atomic.Bool
 }
 
-// NewSQLiteRepository creates a new SQLite repository
+// SQLiteOptions holds the tunable settings of the SQLite repository
+type SQLiteOptions struct {
+	// QueryTimeout bounds each repository call, so a slow query fails with
+	// context.DeadlineExceeded instead of blocking its caller. Zero disables it.
+	QueryTimeout time.Duration
+	// CompressPayloadBytes gzips stored payloads longer than this many bytes; reads
+	// decompress them transparently. Zero stores every payload as text.
+	CompressPayloadBytes int
+	// BusyRetries is how many more times a write is tried when it fails because the
+	// database is locked, after the busy timeout ran out. Zero fails on the first error.
+	BusyRetries int
+	// KeepFailedJobs soft deletes a job that moves to the dead letter queue: its row stays
+	// with status FAILED, a deleted_at tombstone and a dead_letter_id linking it to its
+	// entry, instead of being deleted.
+	KeepFailedJobs bool
+	// BusyTimeout is how long SQLite waits for another connection's lock before failing
+	// with "database is locked". Zero uses DefaultBusyTimeout.
+	BusyTimeout time.Duration
+	// JournalMode is SQLite's journal_mode, one of JournalModes. Empty uses DefaultJournalMode.
+	JournalMode string
+	// ReadPath opens a separate query-only connection pool that serves GetJobByID, the job
+	// listings and the count methods, while writes and leases stay on the primary pool. It
+	// may name the primary database itself, so dashboard reads do not queue behind writes
+	// in WAL mode, or a replica kept up to date by other tooling, whose reads may lag.
+	// Empty serves every query from the primary.
+	ReadPath string
+	// OnWriteUnavailable is called with every write error that IsWriteUnavailable reports,
+	// such as to count it in the db_write_errors metric
+	OnWriteUnavailable func(err error)
+}
+
+const (
+	// DefaultBusyTimeout is the busy timeout used when SQLiteOptions leaves it unset
+	DefaultBusyTimeout = 5 * time.Second
+	// DefaultJournalMode is the journal mode used when SQLiteOptions leaves it unset
+	DefaultJournalMode = "WAL"
+)
+
+// JournalModes lists the journal modes SQLiteOptions accepts
+var JournalModes = []string{"WAL", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "OFF"}
+
+// busyRetryDelay is the pause before the first retry of a locked write; it doubles on each retry
+const busyRetryDelay = 25 * time.Millisecond
+
+// dsnParams returns the connection parameters applied to every connection in the pool.
+//
+// WAL lets readers run alongside the single writer, but a deferred transaction that
+// reads first and then writes (as LeaseJob does) cannot wait for the write lock: if
+// another connection committed since its read snapshot, SQLite fails the upgrade with
+// "database is locked" immediately and the busy timeout never applies. Starting every
+// transaction with BEGIN IMMEDIATE takes the write lock up front, so concurrent writers
+// from this or other processes queue on the busy timeout instead of failing.
+func (o SQLiteOptions) dsnParams() (string, error) {
+	busyTimeout := o.BusyTimeout
+	if busyTimeout == 0 {
+		busyTimeout = DefaultBusyTimeout
+	}
+	if busyTimeout < 0 {
+		return "", fmt.Errorf("busy timeout must not be negative, got %s", busyTimeout)
+	}
+
+	journalMode := strings.ToUpper(o.JournalMode)
+	if journalMode == "" {
+		journalMode = DefaultJournalMode
+	}
+	if !slices.Contains(JournalModes, journalMode) {
+		return "", fmt.Errorf("unsupported journal mode %q, want one of %s", o.JournalMode, strings.Join(JournalModes, ", "))
+	}
+
+	return fmt.Sprintf("_journal_mode=%s&_busy_timeout=%d&_txlock=immediate&_synchronous=NORMAL", journalMode, busyTimeout.Milliseconds()), nil
+}
+
+// NewSQLiteRepository creates a new SQLite repository with the default options
 func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_timeout=5000")
+	return NewSQLiteRepositoryWithOptions(dbPath, SQLiteOptions{})
+}
+
+// NewSQLiteRepositoryWithOptions creates a new SQLite repository with the given options
+func NewSQLiteRepositoryWithOptions(dbPath string, options SQLiteOptions) (*SQLiteRepository, error) {
+	params, err := options.dsnParams()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", dbPath+"?"+params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -28,68 +122,238 @@ func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	repo := &SQLiteRepository{db: db}
-	if err := repo.initSchema(); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	repo := &SQLiteRepository{db: db, options: options}
+	if err := repo.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if options.ReadPath != "" {
+		repo.readDB, err = openReadDB(options)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
 	}
 
 	return repo, nil
 }
 
-// Close closes the database connection
+// openReadDB opens the query-only connection pool of SQLiteOptions.ReadPath. The journal
+// mode is left to the primary, as a query-only connection cannot change it.
+func openReadDB(options SQLiteOptions) (*sql.DB, error) {
+	busyTimeout := options.BusyTimeout
+	if busyTimeout == 0 {
+		busyTimeout = DefaultBusyTimeout
+	}
+
+	readDB, err := sql.Open("sqlite3", fmt.Sprintf("%s?_busy_timeout=%d&_query_only=1", options.ReadPath, busyTimeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read database: %w", err)
+	}
+	if err := readDB.Ping(); err != nil {
+		readDB.Close()
+		return nil, fmt.Errorf("failed to ping read database: %w", err)
+	}
+	return readDB, nil
+}
+
+// reader returns the connection pool that serves read-only queries
+func (r *SQLiteRepository) reader() *sql.DB {
+	if r.readDB != nil {
+		return r.readDB
+	}
+	return r.db
+}
+
+// withQueryTimeout bounds a repository call by the configured query timeout
+func (r *SQLiteRepository) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.options.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.options.QueryTimeout)
+}
+
+// withBusyRetry runs fn, and runs it again up to BusyRetries times while it fails with
+// SQLITE_BUSY or SQLITE_LOCKED. Any other error is returned right away. fn must leave
+// the database unchanged when it fails, as a single statement or a rolled back
+// transaction does. The query timeout bounds all tries together.
+func (r *SQLiteRepository) withBusyRetry(ctx context.Context, fn func() error) error {
+	err := fn()
+	delay := busyRetryDelay
+	for retry := 0; retry < r.options.BusyRetries && isBusy(err); retry++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		err = fn()
+	}
+	r.recordWrite(err)
+	return err
+}
+
+// recordWrite tracks whether writes are failing because the database cannot be written
+func (r *SQLiteRepository) recordWrite(err error) {
+	if IsWriteUnavailable(err) {
+		r.writeUnavailable.Store(true)
+		if r.options.OnWriteUnavailable != nil {
+			r.options.OnWriteUnavailable(err)
+		}
+	} else if err == nil {
+		r.writeUnavailable.Store(false)
+	}
+}
+
+// withBusyRetryResult is withBusyRetry for calls that also return a result
+func withBusyRetryResult[T any](ctx context.Context, r *SQLiteRepository, fn func() (T, error)) (T, error) {
+	var result T
+	err := r.withBusyRetry(ctx, func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+// isBusy reports whether err is SQLite failing because another connection holds a lock
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+// IsWriteUnavailable reports whether err is a write failing because the database file is
+// read-only or the disk is full. Retrying at once cannot help, so callers should back off.
+func IsWriteUnavailable(err error) bool {
+	if errors.Is(err, ErrWriteUnavailable) {
+		return true
+	}
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrReadonly || sqliteErr.Code == sqlite3.ErrFull)
+}
+
+// Close closes the database connections
 func (r *SQLiteRepository) Close() error {
+	if r.readDB != nil {
+		r.readDB.Close()
+	}
 	return r.db.Close()
 }
 
-// initSchema initializes the database schema
-func (r *SQLiteRepository) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS jobs (
-		id TEXT PRIMARY KEY,
-		tenant_id TEXT NOT NULL,
-		idempotency_key TEXT,
-		payload TEXT NOT NULL,
-		status TEXT NOT NULL DEFAULT 'PENDING',
-		max_retries INTEGER NOT NULL DEFAULT 3,
-		retry_count INTEGER NOT NULL DEFAULT 0,
-		leased_at INTEGER,
-		lease_expires_at INTEGER,
-		created_at INTEGER NOT NULL,
-		updated_at INTEGER NOT NULL,
-		UNIQUE(tenant_id, idempotency_key)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
-	CREATE INDEX IF NOT EXISTS idx_jobs_tenant_id ON jobs(tenant_id);
-	CREATE INDEX IF NOT EXISTS idx_jobs_lease_expires ON jobs(lease_expires_at);
-
-	CREATE TABLE IF NOT EXISTS dead_letter_jobs (
-		id TEXT PRIMARY KEY,
-		job_id TEXT NOT NULL,
-		tenant_id TEXT NOT NULL,
-		payload TEXT NOT NULL,
-		failure_reason TEXT NOT NULL,
-		failed_at INTEGER NOT NULL
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_dlq_tenant_id ON dead_letter_jobs(tenant_id);
-	`
+// Ping checks that the database is reachable and, after a write failed because the database
+// was read-only or the disk was full, that it can be written again
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
 
-	_, err := r.db.Exec(schema)
-	return err
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	if r.readDB != nil {
+		if err := r.readDB.PingContext(ctx); err != nil {
+			return fmt.Errorf("failed to ping read database: %w", err)
+		}
+	}
+	if r.writeUnavailable.Load() {
+		if err := r.checkWritable(ctx); err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteUnavailable, err)
+		}
+	}
+	return nil
+}
+
+// settingWriteCheck is the settings key Ping rewrites to find out whether writes work again
+const settingWriteCheck = "write_check"
+
+// checkWritable makes a real write, so a database that became writable again clears
+// writeUnavailable even when no other write is attempted
+func (r *SQLiteRepository) checkWritable(ctx context.Context) error {
+	return r.withBusyRetry(ctx, func() error {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO settings (key, value) VALUES (?, ?)
+			ON CONFLICT (key) DO UPDATE SET value = excluded.value
+		`, settingWriteCheck, strconv.FormatInt(time.Now().UnixMilli(), 10))
+		return err
+	})
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // CreateJob creates a new job
 func (r *SQLiteRepository) CreateJob(ctx context.Context, job *models.Job) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		if err := insertJob(ctx, r.db, job, r.options.CompressPayloadBytes); err != nil {
+			return err
+		}
+		r.jobsReady.notify()
+		return nil
+	})
+}
+
+// CreateJobsBatch creates multiple jobs in a single transaction.
+// Each job is inserted independently, so a failing item (e.g. a duplicate idempotency key)
+// is reported in the returned slice at its index without aborting the rest of the batch.
+func (r *SQLiteRepository) CreateJobsBatch(ctx context.Context, jobs []*models.Job) ([]error, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() ([]error, error) {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		errs := make([]error, len(jobs))
+		for i, job := range jobs {
+			errs[i] = insertJob(ctx, tx, job, r.options.CompressPayloadBytes)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		r.jobsReady.notify()
+
+		return errs, nil
+	})
+}
+
+// payloadEncoding returns the value stored in a payload_encoding column, which defaults to utf8
+func payloadEncoding(encoding string) string {
+	if encoding == "" {
+		return models.PayloadEncodingUTF8
+	}
+	return encoding
+}
+
+// insertJob inserts a job using the given connection or transaction
+func insertJob(ctx context.Context, db execer, job *models.Job, compressAbove int) error {
 	query := `
-		INSERT INTO jobs (id, tenant_id, idempotency_key, payload, status, max_retries, retry_count, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO jobs (id, tenant_id, idempotency_key, payload, compressed, payload_json, payload_encoding, status, max_retries, retry_count, created_at, updated_at, queue, tags, depends_on, expires_at, dedup_hash, bypass_limits, metadata, seq)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(seq), 0) + 1 FROM jobs))
 	`
 
-	now := time.Now()
+	now := timestampNow()
 	job.CreatedAt = now
 	job.UpdatedAt = now
 
+	if job.Queue == "" {
+		job.Queue = models.DefaultQueue
+	}
+
 	// Convert empty string to NULL for idempotency_key
 	// SQLite allows multiple NULLs in a UNIQUE constraint, but not multiple empty strings
 	var idempotencyKey interface{}
@@ -99,22 +363,60 @@ func (r *SQLiteRepository) CreateJob(ctx context.Context, job *models.Job) error
 		idempotencyKey = job.IdempotencyKey
 	}
 
-	_, err := r.db.ExecContext(ctx, query,
+	tags, err := encodeStringList(job.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+	dependsOn, err := encodeStringList(job.DependsOn)
+	if err != nil {
+		return fmt.Errorf("failed to encode depends_on: %w", err)
+	}
+	metadata, err := encodeMetadata(job.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	payload, compressed, err := encodePayload(job.Payload, compressAbove)
+	if err != nil {
+		return err
+	}
+	var expiresAt interface{}
+	if job.ExpiresAt != nil {
+		expiresAt = job.ExpiresAt.UnixMilli()
+	}
+	var dedupHash interface{}
+	if job.DedupHash != "" {
+		dedupHash = job.DedupHash
+	}
+
+	_, err = db.ExecContext(ctx, query,
 		job.ID,
 		job.TenantID,
 		idempotencyKey,
-		job.Payload,
+		payload,
+		compressed,
+		job.PayloadJSON,
+		payloadEncoding(job.PayloadEncoding),
 		job.Status,
 		job.MaxRetries,
 		job.RetryCount,
-		job.CreatedAt.Unix(),
-		job.UpdatedAt.Unix(),
+		job.CreatedAt.UnixMilli(),
+		job.UpdatedAt.UnixMilli(),
+		job.Queue,
+		tags,
+		dependsOn,
+		expiresAt,
+		dedupHash,
+		job.BypassLimits,
+		metadata,
 	)
 
 	if err != nil {
 		// Check if it's a unique constraint violation (idempotency key conflict)
 		if errStr := err.Error(); errStr != "" {
 			// SQLite returns "UNIQUE constraint failed" for unique violations
+			if strings.Contains(errStr, "UNIQUE constraint failed: jobs.id") {
+				return &ErrDuplicateJobID{ID: job.ID}
+			}
 			if strings.Contains(errStr, "UNIQUE constraint failed") {
 				// Only return duplicate error if idempotency_key was provided (not empty)
 				if job.IdempotencyKey != "" {
@@ -131,6 +433,32 @@ func (r *SQLiteRepository) CreateJob(ctx context.Context, job *models.Job) error
 	return nil
 }
 
+// encodeStringList stores a list such as tags as a JSON array so it can be matched with json_each
+func encodeStringList(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// encodeMetadata encodes job metadata as a JSON object, {} when there is none
+func encodeMetadata(metadata map[string]string) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // ErrDuplicateIdempotencyKey is returned when a job with the same idempotency key already exists
 type ErrDuplicateIdempotencyKey struct {
 	TenantID       string
@@ -141,25 +469,42 @@ func (e *ErrDuplicateIdempotencyKey) Error() string {
 	return fmt.Sprintf("job with idempotency_key %s already exists for tenant %s", e.IdempotencyKey, e.TenantID)
 }
 
-// GetJobByID retrieves a job by ID
-func (r *SQLiteRepository) GetJobByID(ctx context.Context, id string) (*models.Job, error) {
-	query := `
-		SELECT id, tenant_id, idempotency_key, payload, status, max_retries, retry_count,
-		       leased_at, lease_expires_at, created_at, updated_at
-		FROM jobs
-		WHERE id = ?
-	`
+// ErrDuplicateJobID is returned when a job with the same ID already exists
+type ErrDuplicateJobID struct {
+	ID string
+}
+
+func (e *ErrDuplicateJobID) Error() string {
+	return fmt.Sprintf("job with id %s already exists", e.ID)
+}
+
+// jobColumns lists the columns selected for a job, in the order scanJob expects
+const jobColumns = `id, tenant_id, idempotency_key, payload, compressed, payload_json, payload_encoding, status, max_retries, retry_count,
+		       leased_at, lease_expires_at, created_at, updated_at, queue, started_at, finished_at, tags, result, depends_on, leased_by, dead_letter_id, next_retry_at, deleted_at, expires_at, bypass_limits, metadata`
 
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanJob scans a row selected with jobColumns into a job
+func scanJob(row rowScanner) (*models.Job, error) {
 	var job models.Job
-	var idempotencyKeyVal sql.NullString
-	var leasedAt, leaseExpiresAt sql.NullInt64
+	var idempotencyKeyVal, leasedBy, deadLetterID sql.NullString
+	var payload []byte
+	var compressed bool
+	var leasedAt, leaseExpiresAt, startedAt, finishedAt, nextRetryAt, deletedAt, expiresAt sql.NullInt64
 	var createdAt, updatedAt int64
+	var tags, dependsOn, metadata string
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&job.ID,
 		&job.TenantID,
 		&idempotencyKeyVal,
-		&job.Payload,
+		&payload,
+		&compressed,
+		&job.PayloadJSON,
+		&job.PayloadEncoding,
 		&job.Status,
 		&job.MaxRetries,
 		&job.RetryCount,
@@ -167,13 +512,41 @@ func (r *SQLiteRepository) GetJobByID(ctx context.Context, id string) (*models.J
 		&leaseExpiresAt,
 		&createdAt,
 		&updatedAt,
+		&job.Queue,
+		&startedAt,
+		&finishedAt,
+		&tags,
+		&job.Result,
+		&dependsOn,
+		&leasedBy,
+		&deadLetterID,
+		&nextRetryAt,
+		&deletedAt,
+		&expiresAt,
+		&job.BypassLimits,
+		&metadata,
 	)
+	if err != nil {
+		return nil, err
+	}
+	job.LeasedBy = leasedBy.String
+	job.DeadLetterID = deadLetterID.String
 
+	job.Payload, err = decodePayload(payload, compressed)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, sql.ErrNoRows
+		return nil, fmt.Errorf("failed to decode job %s: %w", job.ID, err)
+	}
+
+	if err := json.Unmarshal([]byte(tags), &job.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+	if err := json.Unmarshal([]byte(dependsOn), &job.DependsOn); err != nil {
+		return nil, fmt.Errorf("failed to decode depends_on: %w", err)
+	}
+	if metadata != "{}" {
+		if err := json.Unmarshal([]byte(metadata), &job.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get job: %w", err)
 	}
 
 	// Handle NULL idempotency_key
@@ -183,321 +556,1220 @@ func (r *SQLiteRepository) GetJobByID(ctx context.Context, id string) (*models.J
 		job.IdempotencyKey = ""
 	}
 
-	job.CreatedAt = time.Unix(createdAt, 0)
-	job.UpdatedAt = time.Unix(updatedAt, 0)
+	job.CreatedAt = fromUnixMillis(createdAt)
+	job.UpdatedAt = fromUnixMillis(updatedAt)
 
 	if leasedAt.Valid {
-		t := time.Unix(leasedAt.Int64, 0)
+		t := fromUnixMillis(leasedAt.Int64)
 		job.LeasedAt = &t
 	}
 
 	if leaseExpiresAt.Valid {
-		t := time.Unix(leaseExpiresAt.Int64, 0)
+		t := fromUnixMillis(leaseExpiresAt.Int64)
 		job.LeaseExpiresAt = &t
 	}
 
-	return &job, nil
-}
-
-// GetJobByTenantAndIdempotencyKey retrieves a job by tenant ID and idempotency key
-func (r *SQLiteRepository) GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string) (*models.Job, error) {
-	// Handle NULL idempotency_key (empty string means no idempotency key)
-	var query string
-	var args []interface{}
-
-	if idempotencyKey == "" {
-		query = `
-			SELECT id, tenant_id, idempotency_key, payload, status, max_retries, retry_count,
-			       leased_at, lease_expires_at, created_at, updated_at
-			FROM jobs
-			WHERE tenant_id = ? AND idempotency_key IS NULL
-		`
-		args = []interface{}{tenantID}
-	} else {
-		query = `
-			SELECT id, tenant_id, idempotency_key, payload, status, max_retries, retry_count,
-			       leased_at, lease_expires_at, created_at, updated_at
-			FROM jobs
-			WHERE tenant_id = ? AND idempotency_key = ?
-		`
-		args = []interface{}{tenantID, idempotencyKey}
+	if startedAt.Valid {
+		t := fromUnixMillis(startedAt.Int64)
+		job.StartedAt = &t
 	}
 
-	var job models.Job
-	var idempotencyKeyVal sql.NullString
-	var leasedAt, leaseExpiresAt sql.NullInt64
-	var createdAt, updatedAt int64
-
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(
-		&job.ID,
-		&job.TenantID,
-		&idempotencyKeyVal,
-		&job.Payload,
-		&job.Status,
-		&job.MaxRetries,
-		&job.RetryCount,
-		&leasedAt,
-		&leaseExpiresAt,
-		&createdAt,
-		&updatedAt,
-	)
-
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to get job: %w", err)
+	if finishedAt.Valid {
+		t := fromUnixMillis(finishedAt.Int64)
+		job.FinishedAt = &t
 	}
 
-	// Handle NULL idempotency_key
-	if idempotencyKeyVal.Valid {
-		job.IdempotencyKey = idempotencyKeyVal.String
-	} else {
-		job.IdempotencyKey = ""
+	if nextRetryAt.Valid {
+		t := fromUnixMillis(nextRetryAt.Int64)
+		job.NextRetryAt = &t
 	}
 
-	job.CreatedAt = time.Unix(createdAt, 0)
-	job.UpdatedAt = time.Unix(updatedAt, 0)
-
-	if leasedAt.Valid {
-		t := time.Unix(leasedAt.Int64, 0)
-		job.LeasedAt = &t
+	if deletedAt.Valid {
+		t := fromUnixMillis(deletedAt.Int64)
+		job.DeletedAt = &t
 	}
 
-	if leaseExpiresAt.Valid {
-		t := time.Unix(leaseExpiresAt.Int64, 0)
-		job.LeaseExpiresAt = &t
+	if expiresAt.Valid {
+		t := fromUnixMillis(expiresAt.Int64)
+		job.ExpiresAt = &t
 	}
 
 	return &job, nil
 }
 
-// ListJobsByStatus retrieves all jobs with a specific status
-func (r *SQLiteRepository) ListJobsByStatus(ctx context.Context, status models.JobStatus) ([]*models.Job, error) {
+// Job timestamps are stored as Unix milliseconds, so jobs created within the same second
+// keep their order and API responses keep sub-second precision
+
+// timestampNow returns the current time at the precision it is stored with
+func timestampNow() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// fromUnixMillis converts a stored timestamp back to a UTC time
+func fromUnixMillis(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
+}
+
+// GetJobByID retrieves a job by ID
+func (r *SQLiteRepository) GetJobByID(ctx context.Context, id string) (*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, tenant_id, idempotency_key, payload, status, max_retries, retry_count,
-		       leased_at, lease_expires_at, created_at, updated_at
+		SELECT ` + jobColumns + `
 		FROM jobs
-		WHERE status = ?
-		ORDER BY created_at ASC
+		WHERE id = ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, status)
+	job, err := scanJob(r.reader().QueryRowContext(ctx, query, id))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query jobs: %w", err)
-	}
-	defer rows.Close()
-
-	var jobs []*models.Job
-	for rows.Next() {
-		var job models.Job
-		var idempotencyKeyVal sql.NullString
-		var leasedAt, leaseExpiresAt sql.NullInt64
-		var createdAt, updatedAt int64
-
-		err := rows.Scan(
-			&job.ID,
-			&job.TenantID,
-			&idempotencyKeyVal,
-			&job.Payload,
-			&job.Status,
-			&job.MaxRetries,
-			&job.RetryCount,
-			&leasedAt,
-			&leaseExpiresAt,
-			&createdAt,
-			&updatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan job: %w", err)
-		}
-
-		// Handle NULL idempotency_key
-		if idempotencyKeyVal.Valid {
-			job.IdempotencyKey = idempotencyKeyVal.String
-		} else {
-			job.IdempotencyKey = ""
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
 		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
 
-		job.CreatedAt = time.Unix(createdAt, 0)
-		job.UpdatedAt = time.Unix(updatedAt, 0)
-
-		if leasedAt.Valid {
-			t := time.Unix(leasedAt.Int64, 0)
-			job.LeasedAt = &t
-		}
+	return job, nil
+}
 
-		if leaseExpiresAt.Valid {
-			t := time.Unix(leaseExpiresAt.Int64, 0)
-			job.LeaseExpiresAt = &t
-		}
+// GetJobByTenantAndIdempotencyKey retrieves the newest job of a tenant with the given idempotency key
+// that was created at or after since. A zero since matches jobs of any age.
+func (r *SQLiteRepository) GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string, since time.Time) (*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
 
-		jobs = append(jobs, &job)
+	// Handle NULL idempotency_key (empty string means no idempotency key)
+	keyFilter := "idempotency_key IS NULL"
+	args := []interface{}{tenantID}
+	if idempotencyKey != "" {
+		keyFilter = "idempotency_key = ?"
+		args = append(args, idempotencyKey)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate jobs: %w", err)
+	var sinceMillis int64
+	if !since.IsZero() {
+		sinceMillis = since.UnixMilli()
 	}
+	args = append(args, sinceMillis)
 
-	return jobs, nil
-}
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE tenant_id = ? AND ` + keyFilter + ` AND created_at >= ?
+		ORDER BY created_at DESC, seq DESC
+		LIMIT 1
+	`
 
-// LeaseJob leases a job for processing using a transaction
-func (r *SQLiteRepository) LeaseJob(ctx context.Context, leaseDuration time.Duration) (*models.Job, error) {
-	tx, err := r.db.BeginTx(ctx, nil)
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
 	}
-	defer tx.Rollback()
 
-	now := time.Now()
-	nowUnix := now.Unix()
-	expiresAt := now.Add(leaseDuration)
-	expiresAtUnix := expiresAt.Unix()
+	return job, nil
+}
+
+// GetJobByTenantAndDedupHash returns the tenant's most recent job with the given content hash
+// created at or after since, or nil if there is none
+func (r *SQLiteRepository) GetJobByTenantAndDedupHash(ctx context.Context, tenantID, dedupHash string, since time.Time) (*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
 
-	// Find a job that can be leased:
-	// - PENDING jobs
-	// - RUNNING jobs whose lease has expired
 	query := `
-		SELECT id, tenant_id, idempotency_key, payload, status, max_retries, retry_count,
-		       leased_at, lease_expires_at, created_at, updated_at
+		SELECT ` + jobColumns + `
 		FROM jobs
-		WHERE (status = 'PENDING' OR (status = 'RUNNING' AND lease_expires_at < ?))
-		ORDER BY created_at ASC
+		WHERE tenant_id = ? AND dedup_hash = ? AND created_at >= ?
+		ORDER BY created_at DESC, seq DESC
 		LIMIT 1
 	`
 
-	var job models.Job
-	var idempotencyKeyVal sql.NullString
-	var leasedAt, leaseExpiresAt sql.NullInt64
-	var createdAt, updatedAt int64
-
-	err = tx.QueryRowContext(ctx, query, nowUnix).Scan(
-		&job.ID,
-		&job.TenantID,
-		&idempotencyKeyVal,
-		&job.Payload,
-		&job.Status,
-		&job.MaxRetries,
-		&job.RetryCount,
-		&leasedAt,
-		&leaseExpiresAt,
-		&createdAt,
-		&updatedAt,
-	)
-
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, tenantID, dedupHash, since.UnixMilli()))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to find leasable job: %w", err)
+		return nil, fmt.Errorf("failed to get job: %w", err)
 	}
 
-	// Handle NULL idempotency_key
-	if idempotencyKeyVal.Valid {
-		job.IdempotencyKey = idempotencyKeyVal.String
-	} else {
-		job.IdempotencyKey = ""
-	}
+	return job, nil
+}
 
-	job.CreatedAt = time.Unix(createdAt, 0)
-	job.UpdatedAt = time.Unix(updatedAt, 0)
+// ListJobsByStatus retrieves all jobs in any of the given statuses
+func (r *SQLiteRepository) ListJobsByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
 
-	if leasedAt.Valid {
-		t := time.Unix(leasedAt.Int64, 0)
-		job.LeasedAt = &t
+	if len(statuses) == 0 {
+		return nil, nil
 	}
 
-	// Update the job to RUNNING with new lease
-	updateQuery := `
-		UPDATE jobs
-		SET status = 'RUNNING',
-		    leased_at = ?,
-		    lease_expires_at = ?,
-		    updated_at = ?
-		WHERE id = ?
+	placeholders, args := statusList(statuses)
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE status IN (` + placeholders + `)
+		ORDER BY created_at ASC, seq ASC
 	`
 
-	_, err = tx.ExecContext(ctx, updateQuery, nowUnix, expiresAtUnix, nowUnix, job.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update job lease: %w", err)
-	}
+	return queryJobs(ctx, r.reader(), query, args...)
+}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
+// ListJobsByStatusPage retrieves one page of the jobs in any of the given statuses, in the
+// order of ListJobsByStatus or, with page.Descending, newest first. The returned cursor
+// continues the listing behind the page; it is nil once a page comes back short, as there
+// are no more jobs.
+func (r *SQLiteRepository) ListJobsByStatusPage(ctx context.Context, page JobPage, statuses ...models.JobStatus) ([]*models.Job, *JobCursor, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
 
-	job.Status = models.StatusRunning
-	job.LeasedAt = &now
-	job.LeaseExpiresAt = &expiresAt
-	job.UpdatedAt = now
+	if len(statuses) == 0 {
+		return nil, nil, nil
+	}
 
-	return &job, nil
+	placeholders, args := statusList(statuses)
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE status IN (` + placeholders + `)
+	`
+	if page.TenantID != "" {
+		query += ` AND tenant_id = ?`
+		args = append(args, page.TenantID)
+	}
+	// Each metadata filter must match a key of the job exactly; keys are sorted so the
+	// query text does not depend on map order
+	keys := make([]string, 0, len(page.Metadata))
+	for key := range page.Metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		query += ` AND EXISTS (SELECT 1 FROM json_each(jobs.metadata) WHERE json_each.key = ? AND json_each.value = ?)`
+		args = append(args, key, page.Metadata[key])
+	}
+	seek, direction := ">", "ASC"
+	if page.Descending {
+		seek, direction = "<", "DESC"
+	}
+	offset := page.Offset
+	if page.After != nil {
+		// Seeking past the cursor reads only this page, however deep it is
+		query += ` AND (created_at, seq) ` + seek + ` (?, ?)`
+		args = append(args, page.After.CreatedAt, page.After.Seq)
+		offset = 0
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		// SQLite treats a negative limit as no limit
+		limit = -1
+	}
+	query += ` ORDER BY created_at ` + direction + `, seq ` + direction + ` LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	jobs, err := queryJobs(ctx, r.reader(), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if page.Limit <= 0 || len(jobs) < page.Limit {
+		return jobs, nil, nil
+	}
+
+	last := jobs[len(jobs)-1]
+	next := &JobCursor{CreatedAt: last.CreatedAt.UnixMilli()}
+	if err := r.reader().QueryRowContext(ctx, "SELECT seq FROM jobs WHERE id = ?", last.ID).Scan(&next.Seq); err != nil {
+		return nil, nil, fmt.Errorf("failed to read cursor of job %s: %w", last.ID, err)
+	}
+	return jobs, next, nil
+}
+
+// ListJobsByTag retrieves jobs carrying the given tag, optionally restricted to one tenant and
+// to some statuses
+func (r *SQLiteRepository) ListJobsByTag(ctx context.Context, tenantID, tag string, statuses ...models.JobStatus) ([]*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE EXISTS (SELECT 1 FROM json_each(jobs.tags) WHERE json_each.value = ?)
+	`
+	args := []interface{}{tag}
+	if tenantID != "" {
+		query += ` AND tenant_id = ?`
+		args = append(args, tenantID)
+	}
+	if len(statuses) > 0 {
+		placeholders, statusArgs := statusList(statuses)
+		query += ` AND status IN (` + placeholders + `)`
+		args = append(args, statusArgs...)
+	}
+	query += ` ORDER BY created_at ASC, seq ASC`
+
+	return queryJobs(ctx, r.reader(), query, args...)
+}
+
+// statusList returns the placeholders of an IN clause over the statuses along with its arguments
+func statusList(statuses []models.JobStatus) (string, []interface{}) {
+	placeholders := make([]string, len(statuses))
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		placeholders[i] = "?"
+		args[i] = status
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// SearchJobs retrieves up to limit jobs whose payload contains the query string, newest first,
+// optionally restricted to one tenant. Compressed payloads are not searched.
+func (r *SQLiteRepository) SearchJobs(ctx context.Context, tenantID, query string, limit int) ([]*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	tenantFilter := ""
+	args := []interface{}{"%" + escapeLike(query) + "%"}
+	if tenantID != "" {
+		tenantFilter = "AND tenant_id = ?"
+		args = append(args, tenantID)
+	}
+	args = append(args, limit)
+
+	sqlQuery := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE compressed = 0 AND payload LIKE ? ESCAPE '\' ` + tenantFilter + `
+		ORDER BY created_at DESC, seq DESC
+		LIMIT ?
+	`
+
+	return r.queryJobs(ctx, sqlQuery, args...)
+}
+
+// escapeLike escapes LIKE wildcards so the query string is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// queryJobs runs a query selecting jobColumns and scans the resulting rows
+func (r *SQLiteRepository) queryJobs(ctx context.Context, query string, args ...interface{}) ([]*models.Job, error) {
+	return queryJobs(ctx, r.db, query, args...)
+}
+
+// queryJobs runs a query selecting jobColumns on the given connection or transaction
+func queryJobs(ctx context.Context, db queryer, query string, args ...interface{}) ([]*models.Job, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ListIdempotencyKeysByTenant retrieves the idempotency keys a tenant used at or after since, once
+// each, with the job that holds the key: the newest job created with it. A zero since matches jobs
+// of any age.
+func (r *SQLiteRepository) ListIdempotencyKeysByTenant(ctx context.Context, tenantID string, since time.Time) ([]*models.IdempotencyKeyEntry, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var sinceMillis int64
+	if !since.IsZero() {
+		sinceMillis = since.UnixMilli()
+	}
+
+	// SQLite takes the bare id and status columns from the row holding MAX(created_at)
+	query := `
+		SELECT idempotency_key, id, status, MAX(created_at)
+		FROM jobs
+		WHERE tenant_id = ? AND idempotency_key IS NOT NULL AND created_at >= ?
+		GROUP BY idempotency_key
+		ORDER BY idempotency_key ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID, sinceMillis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query idempotency keys: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.IdempotencyKeyEntry
+	for rows.Next() {
+		var entry models.IdempotencyKeyEntry
+		var createdAt int64
+
+		if err := rows.Scan(&entry.IdempotencyKey, &entry.JobID, &entry.Status, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan idempotency key: %w", err)
+		}
+
+		entry.CreatedAt = fromUnixMillis(createdAt)
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate idempotency keys: %w", err)
+	}
+
+	return entries, nil
+}
+
+// LeaseJob leases a job from the given queue for processing using a transaction.
+// Jobs of tenants already at their running limit are skipped. The count and the lease
+// happen in the same write transaction, so concurrent workers cannot overshoot the limit.
+func (r *SQLiteRepository) LeaseJob(ctx context.Context, queue string, leaseDuration time.Duration, opts LeaseOptions) (*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	jobs, err := r.LeaseJobs(ctx, queue, 1, leaseDuration, opts)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return jobs[0], nil
+}
+
+// LeaseJobsWait is the long-poll variant of LeaseJobs: when no job is available it waits up
+// to wait for this process to make a job leasable and tries again, returning an empty slice
+// once wait has passed. Jobs added by other processes are only found by the next call, so
+// callers keep polling. Each lease attempt is bounded by the query timeout, the wait is not.
+func (r *SQLiteRepository) LeaseJobsWait(ctx context.Context, queue string, n int, leaseDuration time.Duration, opts LeaseOptions, wait time.Duration) ([]*models.Job, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		// Take the channel before leasing so a job added in between still wakes us
+		ready := r.jobsReady.wait()
+
+		jobs, err := r.LeaseJobs(ctx, queue, n, leaseDuration, opts)
+		if err != nil || len(jobs) > 0 {
+			return jobs, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, nil
+		case <-ready:
+		}
+	}
+}
+
+// LeaseJobs leases up to n jobs from the given queue in a single transaction, in the order
+// LeaseJob would lease them one by one. Each job counts towards its tenant's running limit
+// before the next one is picked. It returns no jobs when none can be leased.
+func (r *SQLiteRepository) LeaseJobs(ctx context.Context, queue string, n int, leaseDuration time.Duration, opts LeaseOptions) ([]*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() ([]*models.Job, error) {
+		if n <= 0 {
+			return nil, nil
+		}
+
+		tenantLimits := opts.TenantMaxRunning
+		if tenantLimits == nil {
+			tenantLimits = map[string]int{}
+		}
+		overrides, err := json.Marshal(tenantLimits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tenant limits: %w", err)
+		}
+
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Workers keep polling while leasing is paused, they just get nothing
+		paused, err := isPaused(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		if paused {
+			return nil, nil
+		}
+
+		now := timestampNow()
+		nowMillis := now.UnixMilli()
+		expiresAt := now.Add(leaseDuration)
+		expiresAtMillis := expiresAt.UnixMilli()
+
+		// Find a job in the queue that can be leased:
+		// - PENDING jobs
+		// - RUNNING jobs whose lease has expired
+		// that were not rescheduled to a later time and have not expired,
+		// whose tenant has fewer live leases than its limit unless the job bypasses limits,
+		// and whose dependencies are all DONE. A dependency missing from jobs was either
+		// purged after finishing or moved to the dead letter queue, which is checked.
+		// seq breaks ties between jobs created in the same millisecond.
+		// Fair scheduling orders by when the tenant was last served before falling back to FIFO,
+		// so tenants that have never been served come first.
+		order := "created_at ASC, seq ASC"
+		if opts.FairScheduling {
+			order = `COALESCE((
+				SELECT lease_seq FROM tenant_leases
+				WHERE tenant_leases.queue = jobs.queue AND tenant_leases.tenant_id = jobs.tenant_id
+			), 0) ASC, created_at ASC, seq ASC`
+		}
+
+		// A tenant-scoped worker only considers that tenant's jobs
+		tenantFilter := ""
+		args := []interface{}{string(overrides), queue, nowMillis, nowMillis, nowMillis}
+		if opts.TenantID != "" {
+			tenantFilter = "AND tenant_id = ?"
+			args = append(args, opts.TenantID)
+		}
+		args = append(args, opts.MaxRunningPerTenant, nowMillis, opts.MaxRunningPerTenant)
+
+		query := `
+			WITH tenant_limits AS (
+				SELECT key AS tenant_id, value AS max_running FROM json_each(?)
+			)
+			SELECT ` + jobColumns + `
+			FROM jobs
+			WHERE queue = ? AND (status = 'PENDING' OR (status = 'RUNNING' AND lease_expires_at < ?))
+			  AND (next_retry_at IS NULL OR next_retry_at <= ?)
+			  AND (expires_at IS NULL OR expires_at > ?)
+			  ` + tenantFilter + `
+			  AND (
+				bypass_limits = 1
+				OR COALESCE((SELECT max_running FROM tenant_limits WHERE tenant_limits.tenant_id = jobs.tenant_id), ?) <= 0
+				OR (
+					SELECT COUNT(*) FROM jobs AS running
+					WHERE running.tenant_id = jobs.tenant_id AND running.status = 'RUNNING' AND running.lease_expires_at >= ?
+				) < COALESCE((SELECT max_running FROM tenant_limits WHERE tenant_limits.tenant_id = jobs.tenant_id), ?)
+			  )
+			  AND NOT EXISTS (
+				SELECT 1 FROM json_each(jobs.depends_on) AS dependency
+				WHERE EXISTS (SELECT 1 FROM jobs AS parent WHERE parent.id = dependency.value AND parent.status != 'DONE')
+				   OR EXISTS (SELECT 1 FROM dead_letter_jobs WHERE dead_letter_jobs.job_id = dependency.value)
+			  )
+			ORDER BY ` + order + `
+			LIMIT 1
+		`
+
+		// Update the job to RUNNING with new lease; each attempt restarts the processing clock
+		updateQuery := `
+			UPDATE jobs
+			SET status = 'RUNNING',
+			    leased_at = ?,
+			    lease_expires_at = ?,
+			    started_at = ?,
+			    finished_at = NULL,
+			    leased_by = ?,
+			    next_retry_at = NULL,
+			    updated_at = ?
+			WHERE id = ?
+		`
+
+		var workerID interface{}
+		if opts.WorkerID != "" {
+			workerID = opts.WorkerID
+		}
+
+		// Pick one job at a time so each lease is visible to the limit and fairness checks of the next
+		var jobs []*models.Job
+		for len(jobs) < n {
+			job, err := scanJob(tx.QueryRowContext(ctx, query, args...))
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					break
+				}
+				return nil, fmt.Errorf("failed to find leasable job: %w", err)
+			}
+
+			_, err = tx.ExecContext(ctx, updateQuery, nowMillis, expiresAtMillis, nowMillis, workerID, nowMillis, job.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update job lease: %w", err)
+			}
+
+			if opts.FairScheduling {
+				// Move the tenant to the back of the line for this queue
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO tenant_leases (queue, tenant_id, lease_seq)
+					VALUES (?, ?, (SELECT COALESCE(MAX(lease_seq), 0) + 1 FROM tenant_leases))
+					ON CONFLICT (queue, tenant_id) DO UPDATE SET lease_seq = excluded.lease_seq
+				`, job.Queue, job.TenantID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to record tenant lease: %w", err)
+				}
+			}
+
+			jobs = append(jobs, job)
+		}
+
+		if len(jobs) == 0 {
+			return nil, nil
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		for _, job := range jobs {
+			leasedAt, leaseExpiresAt, startedAt := now, expiresAt, now
+			job.Status = models.StatusRunning
+			job.LeasedAt = &leasedAt
+			job.LeaseExpiresAt = &leaseExpiresAt
+			job.StartedAt = &startedAt
+			job.FinishedAt = nil
+			job.NextRetryAt = nil
+			job.LeasedBy = opts.WorkerID
+			job.UpdatedAt = now
+		}
+
+		return jobs, nil
+	})
+}
+
+// ListExpiredJobs returns PENDING jobs whose expires_at is at or before now, soonest expired first
+func (r *SQLiteRepository) ListExpiredJobs(ctx context.Context, now time.Time) ([]*models.Job, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE status = 'PENDING' AND expires_at IS NOT NULL AND expires_at <= ?
+		ORDER BY expires_at ASC
+	`
+	return queryJobs(ctx, r.db, query, now.UnixMilli())
+}
+
+// settingPaused is the settings key that pauses leasing across every worker while it is "true"
+const settingPaused = "paused"
+
+// SetPaused pauses or resumes leasing for every worker sharing the database
+func (r *SQLiteRepository) SetPaused(ctx context.Context, paused bool) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO settings (key, value) VALUES (?, ?)
+			ON CONFLICT (key) DO UPDATE SET value = excluded.value
+		`, settingPaused, strconv.FormatBool(paused))
+		if err != nil {
+			return fmt.Errorf("failed to set paused: %w", err)
+		}
+
+		if !paused {
+			// Long-polling workers in this process can lease again right away
+			r.jobsReady.notify()
+		}
+		return nil
+	})
+}
+
+// IsPaused reports whether leasing is paused
+func (r *SQLiteRepository) IsPaused(ctx context.Context) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return isPaused(ctx, r.db)
+}
+
+// isPaused reads the paused setting through db, so LeaseJobs can check it inside its transaction
+func isPaused(ctx context.Context, db queryer) (bool, error) {
+	var value string
+	err := db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = ?", settingPaused).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read paused setting: %w", err)
+	}
+	return value == "true", nil
+}
+
+// ReclaimExpiredLeases returns RUNNING jobs whose lease has expired to PENDING and reports how many were reclaimed
+func (r *SQLiteRepository) ReclaimExpiredLeases(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (int64, error) {
+		query := `
+			UPDATE jobs
+			SET status = 'PENDING',
+			    leased_at = NULL,
+			    lease_expires_at = NULL,
+			    updated_at = ?
+			WHERE status = 'RUNNING' AND lease_expires_at < ?
+		`
+
+		now := time.Now().UnixMilli()
+		result, err := r.db.ExecContext(ctx, query, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reclaim expired leases: %w", err)
+		}
+
+		reclaimed, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to check reclaimed jobs: %w", err)
+		}
+		if reclaimed > 0 {
+			r.jobsReady.notify()
+		}
+
+		return reclaimed, nil
+	})
+}
+
+// UpdateJobStatus updates the status of a job, recording finished_at when the job reaches a terminal status
+func (r *SQLiteRepository) UpdateJobStatus(ctx context.Context, id string, status models.JobStatus) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		query := `
+			UPDATE jobs
+			SET status = ?, finished_at = ?, updated_at = ?
+			WHERE id = ?
+		`
+
+		now := timestampNow()
+		var finishedAt interface{}
+		if status.IsTerminal() {
+			finishedAt = now.UnixMilli()
+		}
+
+		_, err := r.db.ExecContext(ctx, query, status, finishedAt, now.UnixMilli(), id)
+		if err != nil {
+			return fmt.Errorf("failed to update job status: %w", err)
+		}
+		if status == models.StatusPending {
+			r.jobsReady.notify()
+		}
+
+		return nil
+	})
+}
+
+// UpdateJobStatusIf moves a job from one status to another only if it is still in the from status.
+// It returns false when the job was in any other status, leaving it untouched.
+func (r *SQLiteRepository) UpdateJobStatusIf(ctx context.Context, id string, from, to models.JobStatus) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		query := `
+			UPDATE jobs
+			SET status = ?, finished_at = ?, updated_at = ?
+			WHERE id = ? AND status = ?
+		`
+
+		now := timestampNow()
+		var finishedAt interface{}
+		if to.IsTerminal() {
+			finishedAt = now.UnixMilli()
+		}
+
+		result, err := r.db.ExecContext(ctx, query, to, finishedAt, now.UnixMilli(), id, from)
+		if err != nil {
+			return false, fmt.Errorf("failed to update job status: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check job status update: %w", err)
+		}
+		if rows == 1 && to == models.StatusPending {
+			r.jobsReady.notify()
+		}
+
+		return rows == 1, nil
+	})
+}
+
+// CompleteJob moves a RUNNING job to DONE and stores the handler's result.
+// It returns false when the job was no longer RUNNING, leaving it untouched.
+func (r *SQLiteRepository) CompleteJob(ctx context.Context, id, result string) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		query := `
+			UPDATE jobs
+			SET status = 'DONE', result = ?, finished_at = ?, updated_at = ?
+			WHERE id = ? AND status = 'RUNNING'
+		`
+
+		now := time.Now().UnixMilli()
+		res, err := r.db.ExecContext(ctx, query, result, now, now, id)
+		if err != nil {
+			return false, fmt.Errorf("failed to complete job: %w", err)
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check job completion: %w", err)
+		}
+		if rows == 1 {
+			// Jobs depending on this one may be leasable now
+			r.jobsReady.notify()
+		}
+
+		return rows == 1, nil
+	})
+}
+
+// RescheduleJob returns a RUNNING job to PENDING without counting a retry and keeps it
+// from being leased again before at. It returns false when the job was no longer RUNNING.
+func (r *SQLiteRepository) RescheduleJob(ctx context.Context, id string, at time.Time) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		query := `
+			UPDATE jobs
+			SET status = 'PENDING', next_retry_at = ?, updated_at = ?
+			WHERE id = ? AND status = 'RUNNING'
+		`
+
+		res, err := r.db.ExecContext(ctx, query, at.UnixMilli(), time.Now().UnixMilli(), id)
+		if err != nil {
+			return false, fmt.Errorf("failed to reschedule job: %w", err)
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check job reschedule: %w", err)
+		}
+
+		return rows == 1, nil
+	})
+}
+
+// CancelJob moves a PENDING or RUNNING job to CANCELLED.
+// It returns false when the job does not exist or has already finished.
+func (r *SQLiteRepository) CancelJob(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		query := `
+			UPDATE jobs
+			SET status = 'CANCELLED', finished_at = ?, updated_at = ?
+			WHERE id = ? AND status IN ('PENDING', 'RUNNING')
+		`
+
+		now := time.Now().UnixMilli()
+		res, err := r.db.ExecContext(ctx, query, now, now, id)
+		if err != nil {
+			return false, fmt.Errorf("failed to cancel job: %w", err)
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check job cancellation: %w", err)
+		}
+
+		return rows == 1, nil
+	})
+}
+
+// UpdateJobStatusBatch moves each job to the to status in one transaction, but only if it is
+// currently in one of the from statuses. FAILED rows kept after moving to the dead letter queue
+// are never moved. The result reports per ID whether the job was updated.
+func (r *SQLiteRepository) UpdateJobStatusBatch(ctx context.Context, ids []string, from []models.JobStatus, to models.JobStatus) ([]bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() ([]bool, error) {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		placeholders, fromArgs := statusList(from)
+		query := `
+			UPDATE jobs
+			SET status = ?, finished_at = ?, updated_at = ?
+			WHERE id = ? AND status IN (` + placeholders + `) AND dead_letter_id IS NULL
+		`
+
+		now := timestampNow()
+		var finishedAt interface{}
+		if to.IsTerminal() {
+			finishedAt = now.UnixMilli()
+		}
+
+		updated := make([]bool, len(ids))
+		for i, id := range ids {
+			args := append([]interface{}{to, finishedAt, now.UnixMilli(), id}, fromArgs...)
+			res, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update job %s status: %w", id, err)
+			}
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("failed to check job %s status update: %w", id, err)
+			}
+			updated[i] = rows == 1
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		if to == models.StatusPending {
+			r.jobsReady.notify()
+		}
+
+		return updated, nil
+	})
+}
+
+// UpdateMaxRetries sets the retry budget of a PENDING job and reports whether the job was PENDING
+func (r *SQLiteRepository) UpdateMaxRetries(ctx context.Context, id string, maxRetries int) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		query := `
+			UPDATE jobs
+			SET max_retries = ?, updated_at = ?
+			WHERE id = ? AND status = 'PENDING'
+		`
+
+		res, err := r.db.ExecContext(ctx, query, maxRetries, time.Now().UnixMilli(), id)
+		if err != nil {
+			return false, fmt.Errorf("failed to update max retries: %w", err)
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check max retries update: %w", err)
+		}
+
+		return rows == 1, nil
+	})
+}
+
+// UpdatePayload replaces the payload of a PENDING job and reports whether the job was PENDING.
+// The payload is compressed as it would be on insert, and dedupHash replaces the job's content
+// hash in the same statement so dedup_window lookups match the new payload.
+func (r *SQLiteRepository) UpdatePayload(ctx context.Context, id string, payload string, payloadJSON bool, dedupHash string) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	stored, compressed, err := encodePayload(payload, r.options.CompressPayloadBytes)
+	if err != nil {
+		return false, err
+	}
+
+	var hash interface{}
+	if dedupHash != "" {
+		hash = dedupHash
+	}
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		query := `
+			UPDATE jobs
+			SET payload = ?, compressed = ?, payload_json = ?, dedup_hash = ?, updated_at = ?
+			WHERE id = ? AND status = 'PENDING'
+		`
+
+		res, err := r.db.ExecContext(ctx, query, stored, compressed, payloadJSON, hash, time.Now().UnixMilli(), id)
+		if err != nil {
+			return false, fmt.Errorf("failed to update payload: %w", err)
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check payload update: %w", err)
+		}
+
+		return rows == 1, nil
+	})
+}
+
+// IncrementRetryCount increments the retry count of a job
+func (r *SQLiteRepository) IncrementRetryCount(ctx context.Context, id string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		query := `
+			UPDATE jobs
+			SET retry_count = retry_count + 1, updated_at = ?
+			WHERE id = ?
+		`
+
+		now := timestampNow()
+		_, err := r.db.ExecContext(ctx, query, now.UnixMilli(), id)
+		if err != nil {
+			return fmt.Errorf("failed to increment retry count: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RetryJob returns a RUNNING job to PENDING for another attempt, incrementing its retry count and
+// recording attempt in the same transaction, so a worker leasing it again never sees a stale
+// count. It returns false, changing nothing, when the job is no longer RUNNING.
+func (r *SQLiteRepository) RetryJob(ctx context.Context, id string, attempt *models.JobAttempt) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	ok, err := withBusyRetryResult(ctx, r, func() (bool, error) {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET status = 'PENDING', retry_count = retry_count + 1, finished_at = NULL, updated_at = ?
+			WHERE id = ? AND status = 'RUNNING'
+		`, timestampNow().UnixMilli(), id)
+		if err != nil {
+			return false, fmt.Errorf("failed to retry job: %w", err)
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check job retry: %w", err)
+		}
+		if rows != 1 {
+			return false, nil
+		}
+
+		if err := insertJobAttempt(ctx, tx, id, attempt); err != nil {
+			return false, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return true, nil
+	})
+	if ok {
+		r.jobsReady.notify()
+	}
+	return ok, err
+}
+
+// GetRunningJobsCountByTenant returns the count of running jobs for a tenant
+func (r *SQLiteRepository) GetRunningJobsCountByTenant(ctx context.Context, tenantID string) (int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(*)
+		FROM jobs
+		WHERE tenant_id = ? AND status = 'RUNNING'
+	`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, tenantID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count running jobs: %w", err)
+	}
+
+	return count, nil
+}
+
+// MoveToDeadLetterQueue moves a job to the dead letter queue, together with every PENDING
+// job that depends on it directly or indirectly. With KeepFailedJobs the jobs stay in
+// jobs as FAILED and point to their dead letter entries.
+func (r *SQLiteRepository) MoveToDeadLetterQueue(ctx context.Context, job *models.Job, failureReason string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := moveToDeadLetterQueue(ctx, tx, job, failureReason, r.options.KeepFailedJobs); err != nil {
+			return err
+		}
+		if err := deadLetterDependents(ctx, tx, job.ID, r.options.KeepFailedJobs); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// FailExpiredJob marks a PENDING job FAILED and records attempt as its failed attempt, then
+// moves it to the dead letter queue when deadLetter is set. Either way its PENDING dependents,
+// which can never run, go to the dead letter queue. Everything happens in one transaction; it
+// returns false, changing nothing, when the job is no longer PENDING.
+func (r *SQLiteRepository) FailExpiredJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, deadLetter bool) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Leased or cancelled since it was listed
+		if ok, err := claimFailedJob(ctx, tx, job.ID, models.StatusPending, attempt); err != nil || !ok {
+			return false, err
+		}
+
+		if deadLetter {
+			if err := moveToDeadLetterQueue(ctx, tx, job, attempt.Reason, r.options.KeepFailedJobs); err != nil {
+				return false, err
+			}
+		}
+		if err := deadLetterDependents(ctx, tx, job.ID, r.options.KeepFailedJobs); err != nil {
+			return false, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return true, nil
+	})
 }
 
-// UpdateJobStatus updates the status of a job
-func (r *SQLiteRepository) UpdateJobStatus(ctx context.Context, id string, status models.JobStatus) error {
-	query := `
-		UPDATE jobs
-		SET status = ?, updated_at = ?
-		WHERE id = ?
-	`
+// FailRunningJob marks a RUNNING job FAILED, records attempt as its last failed attempt and moves
+// it, along with its PENDING dependents, to the dead letter queue with failureReason, all in one
+// transaction. It returns false, changing nothing, when the job is no longer RUNNING.
+func (r *SQLiteRepository) FailRunningJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, failureReason string) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
 
-	now := time.Now()
-	_, err := r.db.ExecContext(ctx, query, status, now.Unix(), id)
-	if err != nil {
-		return fmt.Errorf("failed to update job status: %w", err)
-	}
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
 
-	return nil
+		if ok, err := claimFailedJob(ctx, tx, job.ID, models.StatusRunning, attempt); err != nil || !ok {
+			return false, err
+		}
+		if err := moveToDeadLetterQueue(ctx, tx, job, failureReason, r.options.KeepFailedJobs); err != nil {
+			return false, err
+		}
+		if err := deadLetterDependents(ctx, tx, job.ID, r.options.KeepFailedJobs); err != nil {
+			return false, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return true, nil
+	})
 }
 
-// IncrementRetryCount increments the retry count of a job
-func (r *SQLiteRepository) IncrementRetryCount(ctx context.Context, id string) error {
-	query := `
+// claimFailedJob moves a job from the from status to FAILED and records attempt within tx.
+// It returns false when the job was in any other status, leaving it untouched.
+func claimFailedJob(ctx context.Context, tx *sql.Tx, id string, from models.JobStatus, attempt *models.JobAttempt) (bool, error) {
+	now := timestampNow().UnixMilli()
+	res, err := tx.ExecContext(ctx, `
 		UPDATE jobs
-		SET retry_count = retry_count + 1, updated_at = ?
-		WHERE id = ?
-	`
-
-	now := time.Now()
-	_, err := r.db.ExecContext(ctx, query, now.Unix(), id)
+		SET status = 'FAILED', finished_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, now, now, id, from)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark job failed: %w", err)
+	}
+	rows, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to increment retry count: %w", err)
+		return false, fmt.Errorf("failed to check job status update: %w", err)
+	}
+	if rows != 1 {
+		return false, nil
 	}
 
-	return nil
+	if err := insertJobAttempt(ctx, tx, id, attempt); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// GetRunningJobsCountByTenant returns the count of running jobs for a tenant
-func (r *SQLiteRepository) GetRunningJobsCountByTenant(ctx context.Context, tenantID string) (int, error) {
-	query := `
-		SELECT COUNT(*)
-		FROM jobs
-		WHERE tenant_id = ? AND status = 'RUNNING'
-	`
-
-	var count int
-	err := r.db.QueryRowContext(ctx, query, tenantID).Scan(&count)
+// insertJobAttempt appends a failed attempt to a job's history within tx
+func insertJobAttempt(ctx context.Context, tx *sql.Tx, jobID string, attempt *models.JobAttempt) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO job_attempts (job_id, attempt, reason, at) VALUES (?, ?, ?, ?)",
+		jobID, attempt.Attempt, attempt.Reason, attempt.At.UnixMilli())
 	if err != nil {
-		return 0, fmt.Errorf("failed to count running jobs: %w", err)
+		return fmt.Errorf("failed to record job attempt: %w", err)
 	}
+	return nil
+}
 
-	return count, nil
+// deadLetterDependents moves every PENDING job that depends on the failed job, directly or
+// indirectly, to the dead letter queue within tx, since those jobs can never run
+func deadLetterDependents(ctx context.Context, tx *sql.Tx, jobID string, keep bool) error {
+	failed := []string{jobID}
+	for len(failed) > 0 {
+		parentID := failed[0]
+		failed = failed[1:]
+
+		dependents, err := queryJobs(ctx, tx, `
+			SELECT `+jobColumns+`
+			FROM jobs
+			WHERE status = 'PENDING'
+			  AND EXISTS (SELECT 1 FROM json_each(jobs.depends_on) WHERE json_each.value = ?)
+		`, parentID)
+		if err != nil {
+			return fmt.Errorf("failed to find dependent jobs: %w", err)
+		}
+
+		for _, dependent := range dependents {
+			if err := moveToDeadLetterQueue(ctx, tx, dependent, fmt.Sprintf("dependency failed: job %s", parentID), keep); err != nil {
+				return err
+			}
+			failed = append(failed, dependent.ID)
+		}
+	}
+	return nil
 }
 
-// MoveToDeadLetterQueue moves a job to the dead letter queue
-func (r *SQLiteRepository) MoveToDeadLetterQueue(ctx context.Context, job *models.Job, failureReason string) error {
-	tx, err := r.db.BeginTx(ctx, nil)
+// moveToDeadLetterQueue moves one job and its attempt history to the dead letter queue within tx.
+// When keep is set the job row and its attempts stay behind as a tombstone linked to the new entry.
+func moveToDeadLetterQueue(ctx context.Context, tx *sql.Tx, job *models.Job, failureReason string, keep bool) error {
+	// Carry the job's attempt history over to the DLQ entry
+	attempts, err := listJobAttempts(ctx, tx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	attemptsJSON, err := json.Marshal(attempts)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to encode attempts: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Insert into dead letter queue
+	// Insert into dead letter queue, copying the stored payload so a compressed one stays compressed
 	insertQuery := `
-		INSERT INTO dead_letter_jobs (id, job_id, tenant_id, payload, failure_reason, failed_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO dead_letter_jobs (id, job_id, tenant_id, payload, compressed, payload_json, payload_encoding, failure_reason, failed_at, attempts)
+		VALUES (?, ?, ?,
+		        COALESCE((SELECT payload FROM jobs WHERE id = ?), ?),
+		        COALESCE((SELECT compressed FROM jobs WHERE id = ?), 0),
+		        ?, ?, ?, ?, ?)
 	`
 
 	dlqID := fmt.Sprintf("dlq_%s_%d", job.ID, time.Now().Unix())
@@ -505,36 +1777,216 @@ func (r *SQLiteRepository) MoveToDeadLetterQueue(ctx context.Context, job *model
 		dlqID,
 		job.ID,
 		job.TenantID,
+		job.ID,
 		job.Payload,
+		job.ID,
+		job.PayloadJSON,
+		payloadEncoding(job.PayloadEncoding),
 		failureReason,
-		time.Now().Unix(),
+		time.Now().UnixMilli(),
+		string(attemptsJSON),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert into dead letter queue: %w", err)
 	}
 
+	// Jobs failed by a dependency are still PENDING; mark them FAILED first so the audit
+	// trail ends in the status the job left with
+	_, err = tx.ExecContext(ctx, "UPDATE jobs SET status = 'FAILED', updated_at = ? WHERE id = ? AND status != 'FAILED'", time.Now().UnixMilli(), job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to mark job failed: %w", err)
+	}
+
+	if keep {
+		_, err = tx.ExecContext(ctx, "UPDATE jobs SET dead_letter_id = ?, deleted_at = ? WHERE id = ?", dlqID, time.Now().UnixMilli(), job.ID)
+		if err != nil {
+			return fmt.Errorf("failed to link job to dead letter entry: %w", err)
+		}
+		return nil
+	}
+
 	// Delete from jobs table
 	_, err = tx.ExecContext(ctx, "DELETE FROM jobs WHERE id = ?", job.ID)
 	if err != nil {
 		return fmt.Errorf("failed to delete job: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	_, err = tx.ExecContext(ctx, "DELETE FROM job_attempts WHERE job_id = ?", job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete job attempts: %w", err)
 	}
 
 	return nil
 }
 
+// RecordJobAttempt appends a failed attempt to a job's history
+func (r *SQLiteRepository) RecordJobAttempt(ctx context.Context, jobID string, attempt *models.JobAttempt) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		query := `
+			INSERT INTO job_attempts (job_id, attempt, reason, at)
+			VALUES (?, ?, ?, ?)
+		`
+
+		_, err := r.db.ExecContext(ctx, query, jobID, attempt.Attempt, attempt.Reason, attempt.At.UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to record job attempt: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ListJobAttempts retrieves the attempt history of a job that is still in the jobs table, in order
+func (r *SQLiteRepository) ListJobAttempts(ctx context.Context, jobID string) ([]models.JobAttempt, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return listJobAttempts(ctx, r.db, jobID)
+}
+
+// listJobAttempts retrieves a job's attempt history in order
+func listJobAttempts(ctx context.Context, db queryer, jobID string) ([]models.JobAttempt, error) {
+	rows, err := db.QueryContext(ctx, "SELECT attempt, reason, at FROM job_attempts WHERE job_id = ? ORDER BY attempt ASC, id ASC", jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.JobAttempt{}
+	for rows.Next() {
+		var attempt models.JobAttempt
+		var at int64
+		if err := rows.Scan(&attempt.Attempt, &attempt.Reason, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan job attempt: %w", err)
+		}
+		attempt.At = fromUnixMillis(at)
+		attempts = append(attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate job attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// ListJobTransitions retrieves a job's audit trail of status changes in order. The trail
+// outlives the job, so it is still available after the job was purged or dead lettered.
+func (r *SQLiteRepository) ListJobTransitions(ctx context.Context, jobID string) ([]models.JobTransition, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT job_id, tenant_id, from_status, to_status, at, worker_id
+		FROM job_events
+		WHERE job_id = ?
+		ORDER BY id ASC
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job events: %w", err)
+	}
+	defer rows.Close()
+
+	transitions := []models.JobTransition{}
+	for rows.Next() {
+		var transition models.JobTransition
+		var fromStatus, workerID sql.NullString
+		var at int64
+		if err := rows.Scan(&transition.JobID, &transition.TenantID, &fromStatus, &transition.ToStatus, &at, &workerID); err != nil {
+			return nil, fmt.Errorf("failed to scan job event: %w", err)
+		}
+		transition.FromStatus = models.JobStatus(fromStatus.String)
+		transition.WorkerID = workerID.String
+		transition.At = fromUnixMillis(at)
+		transitions = append(transitions, transition)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate job events: %w", err)
+	}
+
+	return transitions, nil
+}
+
 // ListDeadLetterJobs retrieves all dead letter jobs
 func (r *SQLiteRepository) ListDeadLetterJobs(ctx context.Context) ([]*models.DeadLetterJob, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, job_id, tenant_id, payload, compressed, payload_json, payload_encoding, failure_reason, failed_at, attempts
+		FROM dead_letter_jobs
+		ORDER BY failed_at DESC
+	`
+
+	return r.queryDeadLetterJobs(ctx, query)
+}
+
+// GetDeadLetterJobByJobID retrieves the dead letter entry of a job, or nil if the job is not in the DLQ
+func (r *SQLiteRepository) GetDeadLetterJobByJobID(ctx context.Context, jobID string) (*models.DeadLetterJob, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, job_id, tenant_id, payload, failure_reason, failed_at
+		SELECT id, job_id, tenant_id, payload, compressed, payload_json, payload_encoding, failure_reason, failed_at, attempts
 		FROM dead_letter_jobs
+		WHERE job_id = ?
 		ORDER BY failed_at DESC
+		LIMIT 1
+	`
+
+	dlqJobs, err := r.queryDeadLetterJobs(ctx, query, jobID)
+	if err != nil || len(dlqJobs) == 0 {
+		return nil, err
+	}
+	return dlqJobs[0], nil
+}
+
+// ListDeadLetterJobsFiltered retrieves a page of dead letter jobs, optionally restricted to one tenant,
+// along with the total number of matching jobs. A limit of zero or less returns every match.
+func (r *SQLiteRepository) ListDeadLetterJobsFiltered(ctx context.Context, tenantID string, limit, offset int) ([]*models.DeadLetterJob, int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	where := ""
+	var args []interface{}
+	if tenantID != "" {
+		where = "WHERE tenant_id = ?"
+		args = append(args, tenantID)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dead_letter_jobs "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letter jobs: %w", err)
+	}
+
+	if limit <= 0 {
+		// SQLite treats a negative limit as no limit
+		limit = -1
+	}
+
+	query := `
+		SELECT id, job_id, tenant_id, payload, compressed, payload_json, payload_encoding, failure_reason, failed_at, attempts
+		FROM dead_letter_jobs
+		` + where + `
+		ORDER BY failed_at DESC, id ASC
+		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	dlqJobs, err := r.queryDeadLetterJobs(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return dlqJobs, total, nil
+}
+
+// queryDeadLetterJobs runs a dead letter job query and scans the resulting rows
+func (r *SQLiteRepository) queryDeadLetterJobs(ctx context.Context, query string, args ...interface{}) ([]*models.DeadLetterJob, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query dead letter jobs: %w", err)
 	}
@@ -544,20 +1996,34 @@ func (r *SQLiteRepository) ListDeadLetterJobs(ctx context.Context) ([]*models.De
 	for rows.Next() {
 		var dlqJob models.DeadLetterJob
 		var failedAt int64
+		var attempts string
+		var payload []byte
+		var compressed bool
 
 		err := rows.Scan(
 			&dlqJob.ID,
 			&dlqJob.JobID,
 			&dlqJob.TenantID,
-			&dlqJob.Payload,
+			&payload,
+			&compressed,
+			&dlqJob.PayloadJSON,
+			&dlqJob.PayloadEncoding,
 			&dlqJob.FailureReason,
 			&failedAt,
+			&attempts,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan dead letter job: %w", err)
 		}
 
-		dlqJob.FailedAt = time.Unix(failedAt, 0)
+		dlqJob.Payload, err = decodePayload(payload, compressed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter job %s: %w", dlqJob.JobID, err)
+		}
+		dlqJob.FailedAt = fromUnixMillis(failedAt)
+		if err := json.Unmarshal([]byte(attempts), &dlqJob.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter job attempts: %w", err)
+		}
 		dlqJobs = append(dlqJobs, &dlqJob)
 	}
 
@@ -568,8 +2034,74 @@ func (r *SQLiteRepository) ListDeadLetterJobs(ctx context.Context) ([]*models.De
 	return dlqJobs, nil
 }
 
+// DeleteJobsOlderThan deletes jobs in the given status that were last updated before the cutoff
+func (r *SQLiteRepository) DeleteJobsOlderThan(ctx context.Context, status models.JobStatus, cutoff time.Time) (int64, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (int64, error) {
+		result, err := r.db.ExecContext(ctx, "DELETE FROM jobs WHERE status = ? AND updated_at < ?", status, cutoff.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete old jobs: %w", err)
+		}
+
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to check deleted jobs: %w", err)
+		}
+
+		// Drop the attempt history of jobs that no longer exist
+		if deleted > 0 {
+			_, err = r.db.ExecContext(ctx, "DELETE FROM job_attempts WHERE job_id NOT IN (SELECT id FROM jobs)")
+			if err != nil {
+				return 0, fmt.Errorf("failed to delete orphaned job attempts: %w", err)
+			}
+		}
+
+		return deleted, nil
+	})
+}
+
+// ListDeadLetterJobsOlderThan retrieves the dead letter jobs that failed before the cutoff, oldest first
+func (r *SQLiteRepository) ListDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) ([]*models.DeadLetterJob, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, job_id, tenant_id, payload, compressed, payload_json, payload_encoding, failure_reason, failed_at, attempts
+		FROM dead_letter_jobs
+		WHERE failed_at < ?
+		ORDER BY failed_at ASC, id ASC
+	`
+
+	return r.queryDeadLetterJobs(ctx, query, cutoff.UnixMilli())
+}
+
+// DeleteDeadLetterJobsOlderThan deletes the dead letter jobs that failed before the cutoff
+func (r *SQLiteRepository) DeleteDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (int64, error) {
+		result, err := r.db.ExecContext(ctx, "DELETE FROM dead_letter_jobs WHERE failed_at < ?", cutoff.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete old dead letter jobs: %w", err)
+		}
+
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to check deleted dead letter jobs: %w", err)
+		}
+
+		return deleted, nil
+	})
+}
+
 // GetTotalJobsCount returns the total count of all jobs (including DLQ)
 func (r *SQLiteRepository) GetTotalJobsCount(ctx context.Context) (int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	// Count jobs in jobs table
 	var jobsCount int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs").Scan(&jobsCount)
@@ -589,6 +2121,9 @@ func (r *SQLiteRepository) GetTotalJobsCount(ctx context.Context) (int, error) {
 
 // GetCompletedJobsCount returns the count of completed (DONE) jobs
 func (r *SQLiteRepository) GetCompletedJobsCount(ctx context.Context) (int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs WHERE status = 'DONE'").Scan(&count)
 	if err != nil {
@@ -599,9 +2134,12 @@ func (r *SQLiteRepository) GetCompletedJobsCount(ctx context.Context) (int, erro
 
 // GetFailedJobsCount returns the count of failed jobs (FAILED status + DLQ)
 func (r *SQLiteRepository) GetFailedJobsCount(ctx context.Context) (int, error) {
-	// Count FAILED jobs
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Count FAILED jobs, leaving out those kept alongside their DLQ entry
 	var failedCount int
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs WHERE status = 'FAILED'").Scan(&failedCount)
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs WHERE status = 'FAILED' AND dead_letter_id IS NULL").Scan(&failedCount)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count failed jobs: %w", err)
 	}
@@ -618,6 +2156,9 @@ func (r *SQLiteRepository) GetFailedJobsCount(ctx context.Context) (int, error)
 
 // GetDeadLetterQueueCount returns the count of jobs in DLQ
 func (r *SQLiteRepository) GetDeadLetterQueueCount(ctx context.Context) (int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dead_letter_jobs").Scan(&count)
 	if err != nil {
@@ -625,3 +2166,194 @@ func (r *SQLiteRepository) GetDeadLetterQueueCount(ctx context.Context) (int, er
 	}
 	return count, nil
 }
+
+// CountJobsByStatus returns the number of jobs currently in the given status
+func (r *SQLiteRepository) CountJobsByStatus(ctx context.Context, status models.JobStatus) (int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := r.reader().QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs WHERE status = ?", status).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s jobs: %w", status, err)
+	}
+	return count, nil
+}
+
+// CountJobsAheadOf returns how many PENDING jobs in the job's queue come before it in lease
+// order, oldest first with seq breaking ties
+func (r *SQLiteRepository) CountJobsAheadOf(ctx context.Context, job *models.Job) (int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(*)
+		FROM jobs
+		WHERE queue = ? AND status = 'PENDING' AND id != ?
+		  AND (created_at < ? OR (created_at = ? AND seq < (SELECT seq FROM jobs WHERE id = ?)))
+	`
+
+	createdAt := job.CreatedAt.UnixMilli()
+	var count int
+	if err := r.reader().QueryRowContext(ctx, query, job.Queue, job.ID, createdAt, createdAt, job.ID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count jobs ahead of %s: %w", job.ID, err)
+	}
+	return count, nil
+}
+
+// CountJobsGroupedByStatus returns the number of jobs in each status that has any jobs
+func (r *SQLiteRepository) CountJobsGroupedByStatus(ctx context.Context) (map[models.JobStatus]int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.reader().QueryContext(ctx, "SELECT status, COUNT(*) FROM jobs GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.JobStatus]int)
+	for rows.Next() {
+		var status models.JobStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan status count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating status counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// CountDeadLetterByReason returns the number of dead letter jobs per failure reason, normalized to
+// the text before the first colon so reasons that differ only in the underlying error count together.
+// A non-empty tenantID counts only that tenant's jobs.
+func (r *SQLiteRepository) CountDeadLetterByReason(ctx context.Context, tenantID string) (map[string]int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT failure_reason, COUNT(*) FROM dead_letter_jobs"
+	var args []interface{}
+	if tenantID != "" {
+		query += " WHERE tenant_id = ?"
+		args = append(args, tenantID)
+	}
+	query += " GROUP BY failure_reason"
+
+	rows, err := r.reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count dead letter jobs by reason: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var reason string
+		var count int
+		if err := rows.Scan(&reason, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan reason count: %w", err)
+		}
+		counts[normalizeFailureReason(reason)] += count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reason counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// normalizeFailureReason reduces a failure reason to the text before its first colon
+func normalizeFailureReason(reason string) string {
+	prefix, _, _ := strings.Cut(reason, ":")
+	return strings.TrimSpace(prefix)
+}
+
+// OldestPendingJobAge returns how long the oldest PENDING job has been waiting, or zero if none are pending
+func (r *SQLiteRepository) OldestPendingJobAge(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var oldest sql.NullInt64
+	err := r.reader().QueryRowContext(ctx, "SELECT MIN(created_at) FROM jobs WHERE status = 'PENDING'").Scan(&oldest)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get oldest pending job: %w", err)
+	}
+
+	if !oldest.Valid {
+		return 0, nil
+	}
+
+	age := time.Since(fromUnixMillis(oldest.Int64))
+	if age < 0 {
+		age = 0
+	}
+	return age, nil
+}
+
+// pendingAgeSampleSize caps how many PENDING jobs PendingJobAgePercentiles reads
+const pendingAgeSampleSize = 10000
+
+// PendingJobAgePercentiles returns how long PENDING jobs have been waiting at each of the
+// given percentiles, such as 0.5 and 0.95, or zeros if none are pending. The ages are
+// computed from the created_at of at most pendingAgeSampleSize jobs, read oldest first in
+// one query on the (status, created_at) index. With a larger backlog the percentiles are
+// those of the oldest jobs only, so they overstate the typical wait.
+func (r *SQLiteRepository) PendingJobAgePercentiles(ctx context.Context, percentiles []float64) ([]time.Duration, error) {
+	return r.pendingJobAgePercentiles(ctx, percentiles, pendingAgeSampleSize)
+}
+
+func (r *SQLiteRepository) pendingJobAgePercentiles(ctx context.Context, percentiles []float64, sampleSize int) ([]time.Duration, error) {
+	for _, p := range percentiles {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("percentile %v out of range [0, 1]", p)
+		}
+	}
+
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.reader().QueryContext(ctx, `
+		SELECT created_at FROM jobs
+		WHERE status = 'PENDING'
+		ORDER BY created_at ASC
+		LIMIT ?
+	`, sampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample pending jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var createdAts []int64
+	for rows.Next() {
+		var createdAt int64
+		if err := rows.Scan(&createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending job: %w", err)
+		}
+		createdAts = append(createdAts, createdAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending jobs: %w", err)
+	}
+
+	ages := make([]time.Duration, len(percentiles))
+	if len(createdAts) == 0 {
+		return ages, nil
+	}
+
+	now := time.Now()
+	last := len(createdAts) - 1
+	for i, p := range percentiles {
+		// Ages grow as created_at shrinks, so the youngest jobs come last in created_at order
+		rank := last - int(p*float64(last))
+		if age := now.Sub(fromUnixMillis(createdAts[rank])); age > 0 {
+			ages[i] = age
+		}
+	}
+
+	return ages, nil
+}
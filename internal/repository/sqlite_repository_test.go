@@ -0,0 +1,2436 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"job-queue/internal/models"
+	"job-queue/migrations"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// newTestRepository creates a SQLite repository in a temporary directory
+func newTestRepository(t *testing.T) *SQLiteRepository {
+	t.Helper()
+
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+// seedJob inserts a PENDING job with the given identity
+func seedJob(t *testing.T, repo *SQLiteRepository, id, tenantID, idempotencyKey string) *models.Job {
+	t.Helper()
+
+	job := &models.Job{
+		ID:             id,
+		TenantID:       tenantID,
+		IdempotencyKey: idempotencyKey,
+		Payload:        "payload-" + id,
+		Status:         models.StatusPending,
+		MaxRetries:     3,
+	}
+	if err := repo.CreateJob(context.Background(), job); err != nil {
+		t.Fatalf("failed to create job %s: %v", id, err)
+	}
+
+	return job
+}
+
+func TestSQLiteRepository_ListIdempotencyKeysByTenant(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "key-b")
+	seedJob(t, repo, "job-2", "tenant-1", "key-a")
+	seedJob(t, repo, "job-3", "tenant-1", "")
+	seedJob(t, repo, "job-4", "tenant-2", "key-a")
+
+	// key-a was reused after job-0 held it, and key-c was last used before the window
+	now := time.Now()
+	for _, old := range []struct{ id, key string }{{"job-0", "key-a"}, {"job-5", "key-c"}} {
+		seedJob(t, repo, old.id, "tenant-1", old.key)
+		if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = ? WHERE id = ?`, now.Add(-2*time.Hour).UnixMilli(), old.id); err != nil {
+			t.Fatalf("failed to backdate job: %v", err)
+		}
+	}
+
+	entries, err := repo.ListIdempotencyKeysByTenant(ctx, "tenant-1", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []struct{ key, jobID string }{
+		{"key-a", "job-2"},
+		{"key-b", "job-1"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), len(entries))
+	}
+
+	for i, e := range expected {
+		if entries[i].IdempotencyKey != e.key || entries[i].JobID != e.jobID {
+			t.Errorf("entry %d: expected %s -> %s, got %s -> %s", i, e.key, e.jobID, entries[i].IdempotencyKey, entries[i].JobID)
+		}
+		if entries[i].Status != models.StatusPending {
+			t.Errorf("entry %d: expected status PENDING, got %s", i, entries[i].Status)
+		}
+	}
+
+	// Without a window every key is listed once, with its newest job
+	all, err := repo.ListIdempotencyKeysByTenant(ctx, "tenant-1", time.Time{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(all) != 3 || all[0].JobID != "job-2" || all[2].JobID != "job-5" {
+		t.Errorf("expected key-a -> job-2, key-b and key-c -> job-5, got %d entries", len(all))
+	}
+
+	none, err := repo.ListIdempotencyKeysByTenant(ctx, "tenant-3", time.Time{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("expected no keys for unknown tenant, got %d", len(none))
+	}
+}
+
+func TestSQLiteRepository_LeaseJob_QueueIsolation(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	defaultJob := seedJob(t, repo, "job-1", "tenant-1", "")
+	emailJob := &models.Job{ID: "job-2", TenantID: "tenant-1", Queue: "email", Payload: "send", Status: models.StatusPending}
+	if err := repo.CreateJob(ctx, emailJob); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	if defaultJob.Queue != models.DefaultQueue {
+		t.Errorf("expected queue to default to %q, got %q", models.DefaultQueue, defaultJob.Queue)
+	}
+
+	leased, err := repo.LeaseJob(ctx, "email", 30*time.Second, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if leased == nil || leased.ID != "job-2" {
+		t.Fatalf("expected to lease job-2 from the email queue, got %+v", leased)
+	}
+
+	leased, err = repo.LeaseJob(ctx, "email", 30*time.Second, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if leased != nil {
+		t.Errorf("expected email queue to be empty, got %s", leased.ID)
+	}
+
+	leased, err = repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if leased == nil || leased.ID != "job-1" || leased.Queue != models.DefaultQueue {
+		t.Errorf("expected to lease job-1 from the default queue, got %+v", leased)
+	}
+}
+
+func TestSQLiteRepository_LeaseJobsWait(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// Nothing arrives, so the call gives up after the wait
+	start := time.Now()
+	leased, err := repo.LeaseJobsWait(ctx, models.DefaultQueue, 1, 30*time.Second, LeaseOptions{}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(leased) != 0 {
+		t.Fatalf("expected no jobs, got %+v", leased)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the call to wait 50ms, returned after %s", elapsed)
+	}
+
+	// A job created while waiting is leased well before the wait runs out
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Errorf("failed to create job: %v", err)
+		}
+	}()
+
+	start = time.Now()
+	leased, err = repo.LeaseJobsWait(ctx, models.DefaultQueue, 1, 30*time.Second, LeaseOptions{}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(leased) != 1 || leased[0].ID != "job-1" {
+		t.Fatalf("expected to lease job-1, got %+v", leased)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the new job to wake the lease, took %s", elapsed)
+	}
+
+	// Cancelling the context ends the wait
+	cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := repo.LeaseJobsWait(cancelCtx, models.DefaultQueue, 1, 30*time.Second, LeaseOptions{}, 10*time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_LeaseJob_TenantScoped(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// The other tenant's jobs are older, so an unscoped lease would pick them first
+	seedJob(t, repo, "other-1", "tenant-2", "")
+	seedJob(t, repo, "other-2", "tenant-2", "")
+	seedJob(t, repo, "own-1", "tenant-1", "")
+
+	opts := LeaseOptions{TenantID: "tenant-1"}
+	leased, err := repo.LeaseJobs(ctx, models.DefaultQueue, 3, 30*time.Second, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(leased) != 1 || leased[0].ID != "own-1" {
+		t.Fatalf("expected to lease only own-1, got %+v", leased)
+	}
+
+	leased, err = repo.LeaseJobs(ctx, models.DefaultQueue, 3, 30*time.Second, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(leased) != 0 {
+		t.Errorf("expected a tenant-scoped worker never to lease another tenant's job, got %+v", leased)
+	}
+
+	pending, err := repo.ListJobsByStatus(ctx, models.StatusPending)
+	if err != nil {
+		t.Fatalf("failed to list jobs: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Errorf("expected tenant-2's jobs to stay PENDING, got %d pending", len(pending))
+	}
+
+	// Without a tenant every tenant is served
+	job, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job == nil || job.TenantID != "tenant-2" {
+		t.Errorf("expected an unscoped lease to pick up tenant-2's job, got %+v", job)
+	}
+}
+
+func TestSQLiteRepository_DeleteJobsOlderThan(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	seedJob(t, repo, "job-2", "tenant-1", "")
+	if err := repo.UpdateJobStatus(ctx, "job-1", models.StatusDone); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	deleted, err := repo.DeleteJobsOlderThan(ctx, models.StatusDone, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("expected recent job to be kept, %d deleted", deleted)
+	}
+
+	deleted, err = repo.DeleteJobsOlderThan(ctx, models.StatusDone, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 job deleted, got %d", deleted)
+	}
+
+	if _, err := repo.GetJobByID(ctx, "job-2"); err != nil {
+		t.Errorf("expected PENDING job to be kept, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_TruncateAll(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	failed := seedJob(t, repo, "job-2", "tenant-1", "")
+	if err := repo.RecordJobAttempt(ctx, failed.ID, &models.JobAttempt{Attempt: 1, Reason: "timeout", At: time.Now()}); err != nil {
+		t.Fatalf("failed to record attempt: %v", err)
+	}
+	if err := repo.MoveToDeadLetterQueue(ctx, failed, "failed"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+	if err := repo.SetPaused(ctx, true); err != nil {
+		t.Fatalf("failed to pause: %v", err)
+	}
+
+	var truncater TruncateRepository = repo
+	if err := truncater.TruncateAll(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, table := range truncatedTables {
+		var count int
+		if err := repo.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("expected %s to be empty, got %d rows", table, count)
+		}
+	}
+
+	var settings int
+	if err := repo.db.QueryRow("SELECT COUNT(*) FROM settings").Scan(&settings); err != nil {
+		t.Fatalf("failed to count settings: %v", err)
+	}
+	if settings == 0 {
+		t.Error("expected settings to be kept")
+	}
+
+	// The same IDs can be used again by the next case
+	seedJob(t, repo, "job-1", "tenant-1", "")
+}
+
+func TestSQLiteRepository_ListDeadLetterJobsFiltered(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, seed := range []struct{ id, tenantID string }{
+		{"job-1", "tenant-1"},
+		{"job-2", "tenant-1"},
+		{"job-3", "tenant-1"},
+		{"job-4", "tenant-2"},
+	} {
+		job := seedJob(t, repo, seed.id, seed.tenantID, "")
+		if err := repo.MoveToDeadLetterQueue(ctx, job, "failed"); err != nil {
+			t.Fatalf("failed to move %s to DLQ: %v", seed.id, err)
+		}
+	}
+
+	page, total, err := repo.ListDeadLetterJobsFiltered(ctx, "tenant-1", 2, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected total 3, got %d", total)
+	}
+	if len(page) != 2 {
+		t.Fatalf("expected 2 jobs on the first page, got %d", len(page))
+	}
+
+	rest, _, err := repo.ListDeadLetterJobsFiltered(ctx, "tenant-1", 2, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(rest) != 1 {
+		t.Fatalf("expected 1 job on the second page, got %d", len(rest))
+	}
+
+	seen := map[string]bool{}
+	for _, dlqJob := range append(page, rest...) {
+		if dlqJob.TenantID != "tenant-1" {
+			t.Errorf("expected only tenant-1 jobs, got %s", dlqJob.TenantID)
+		}
+		seen[dlqJob.JobID] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected pages not to overlap, saw %v", seen)
+	}
+
+	all, total, err := repo.ListDeadLetterJobsFiltered(ctx, "", 0, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 4 || len(all) != 4 {
+		t.Errorf("expected all 4 jobs without filters, got %d of %d", len(all), total)
+	}
+}
+
+func TestSQLiteRepository_ReclaimExpiredLeases(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	seedJob(t, repo, "job-2", "tenant-1", "")
+
+	// Lease job-1 with a live lease first; leasing in order would otherwise hand the
+	// already expired job-1 out again
+	if _, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Hour, LeaseOptions{}); err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if _, err := repo.LeaseJob(ctx, models.DefaultQueue, -time.Minute, LeaseOptions{}); err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+
+	reclaimed, err := repo.ReclaimExpiredLeases(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reclaimed != 1 {
+		t.Fatalf("expected 1 reclaimed job, got %d", reclaimed)
+	}
+
+	expired, err := repo.GetJobByID(ctx, "job-2")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if expired.Status != models.StatusPending || expired.LeaseExpiresAt != nil {
+		t.Errorf("expected expired job to be PENDING without a lease, got %s", expired.Status)
+	}
+
+	active, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if active.Status != models.StatusRunning {
+		t.Errorf("expected job with a live lease to stay RUNNING, got %s", active.Status)
+	}
+}
+
+func TestSQLiteRepository_ConcurrentWorkers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	// Each worker process opens its own repository on the same file
+	var repos []*SQLiteRepository
+	for i := 0; i < 4; i++ {
+		repo, err := NewSQLiteRepository(path)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		repos = append(repos, repo)
+	}
+
+	const jobCount = 200
+	for i := 0; i < jobCount; i++ {
+		seedJob(t, repos[0], fmt.Sprintf("job-%d", i), "tenant-1", "")
+	}
+
+	var mu sync.Mutex
+	leased := make(map[string]int)
+	errs := make(chan error, len(repos)*4)
+
+	var wg sync.WaitGroup
+	for _, repo := range repos {
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(repo *SQLiteRepository) {
+				defer wg.Done()
+				for {
+					job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, LeaseOptions{})
+					if err != nil {
+						errs <- err
+						return
+					}
+					if job == nil {
+						return
+					}
+					if err := repo.UpdateJobStatus(ctx, job.ID, models.StatusDone); err != nil {
+						errs <- err
+						return
+					}
+
+					mu.Lock()
+					leased[job.ID]++
+					mu.Unlock()
+				}
+			}(repo)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("expected no lock errors, got %v", err)
+	}
+
+	if len(leased) != jobCount {
+		t.Errorf("expected all %d jobs to be leased, got %d", jobCount, len(leased))
+	}
+	for id, count := range leased {
+		if count != 1 {
+			t.Errorf("expected %s to be leased once, got %d", id, count)
+		}
+	}
+}
+
+func TestSQLiteRepository_LeaseJob_BypassLimits(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	limits := LeaseOptions{MaxRunningPerTenant: 1}
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	seedJob(t, repo, "job-2", "tenant-1", "")
+	urgent := &models.Job{ID: "urgent", TenantID: "tenant-1", Payload: "urgent", Status: models.StatusPending, BypassLimits: true}
+	if err := repo.CreateJob(ctx, urgent); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	if job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, limits); err != nil || job == nil || job.ID != "job-1" {
+		t.Fatalf("expected job-1 to be leased, got %v, %v", job, err)
+	}
+
+	// The tenant is at its limit, so only the job that bypasses it is leased
+	job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, limits)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job == nil || job.ID != "urgent" || !job.BypassLimits {
+		t.Fatalf("expected the urgent job to be leased past the limit, got %+v", job)
+	}
+
+	if job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, limits); err != nil || job != nil {
+		t.Errorf("expected job-2 to wait for the limit, got %v, %v", job, err)
+	}
+}
+
+func TestSQLiteRepository_LeaseJob_TenantRunningLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	var repos []*SQLiteRepository
+	for i := 0; i < 4; i++ {
+		repo, err := NewSQLiteRepository(path)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		repos = append(repos, repo)
+	}
+
+	const submissions = 50
+	limits := LeaseOptions{MaxRunningPerTenant: 3, TenantMaxRunning: map[string]int{"tenant-2": 1}}
+
+	seedJob(t, repos[0], "other-1", "tenant-2", "")
+	seedJob(t, repos[0], "other-2", "tenant-2", "")
+
+	// Submit while workers are already leasing so the count is contended
+	var submitters sync.WaitGroup
+	var submitted atomic.Bool
+	errs := make(chan error, submissions+len(repos)*2)
+	for i := 0; i < submissions; i++ {
+		submitters.Add(1)
+		go func(i int) {
+			defer submitters.Done()
+			job := &models.Job{ID: fmt.Sprintf("job-%d", i), TenantID: "tenant-1", Payload: "burst", Status: models.StatusPending}
+			if err := repos[i%len(repos)].CreateJob(ctx, job); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+
+	var mu sync.Mutex
+	leased := make(map[string]int)
+
+	var workers sync.WaitGroup
+	for _, repo := range repos {
+		for g := 0; g < 2; g++ {
+			workers.Add(1)
+			go func(repo *SQLiteRepository) {
+				defer workers.Done()
+				for {
+					done := submitted.Load()
+					job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, limits)
+					if err != nil {
+						errs <- err
+						return
+					}
+					if job == nil {
+						if done {
+							return
+						}
+						time.Sleep(time.Millisecond)
+						continue
+					}
+
+					mu.Lock()
+					leased[job.TenantID]++
+					mu.Unlock()
+				}
+			}(repo)
+		}
+	}
+
+	submitters.Wait()
+	submitted.Store(true)
+	workers.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if leased["tenant-1"] != 3 {
+		t.Errorf("expected tenant-1 to be capped at 3 running jobs, leased %d", leased["tenant-1"])
+	}
+	if leased["tenant-2"] != 1 {
+		t.Errorf("expected the tenant-2 override of 1 running job, leased %d", leased["tenant-2"])
+	}
+
+	running, err := repos[0].GetRunningJobsCountByTenant(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("failed to count running jobs: %v", err)
+	}
+	if running != 3 {
+		t.Errorf("expected 3 RUNNING jobs for tenant-1, got %d", running)
+	}
+
+	// Finishing a job frees a slot for the next one
+	var runningJob string
+	jobs, err := repos[0].ListJobsByStatus(ctx, models.StatusRunning)
+	if err != nil {
+		t.Fatalf("failed to list running jobs: %v", err)
+	}
+	for _, job := range jobs {
+		if job.TenantID == "tenant-1" {
+			runningJob = job.ID
+			break
+		}
+	}
+	if err := repos[0].UpdateJobStatus(ctx, runningJob, models.StatusDone); err != nil {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+
+	job, err := repos[0].LeaseJob(ctx, models.DefaultQueue, time.Minute, limits)
+	if err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if job == nil || job.TenantID != "tenant-1" {
+		t.Errorf("expected a tenant-1 job to be leased once a slot freed up, got %v", job)
+	}
+}
+
+func TestSQLiteRepository_LeaseJob_FairScheduling(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// A noisy tenant submitted a burst before two quiet tenants submitted one job each
+	base := time.Now().Add(-time.Hour).UnixMilli()
+	seeds := []struct {
+		id, tenantID string
+		age          int64
+	}{
+		{"noisy-0", "noisy", 0}, {"noisy-1", "noisy", 1}, {"noisy-2", "noisy", 2},
+		{"quiet-a", "tenant-a", 10}, {"quiet-b", "tenant-b", 20},
+	}
+	for _, seed := range seeds {
+		seedJob(t, repo, seed.id, seed.tenantID, "")
+		if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = ? WHERE id = ?`, base+seed.age, seed.id); err != nil {
+			t.Fatalf("failed to backdate job: %v", err)
+		}
+	}
+
+	lease := func(opts LeaseOptions) string {
+		t.Helper()
+		job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, opts)
+		if err != nil {
+			t.Fatalf("failed to lease job: %v", err)
+		}
+		if job == nil {
+			t.Fatal("expected a job to be leased")
+		}
+		return job.ID
+	}
+
+	// FIFO keeps serving the noisy tenant's backlog
+	if id := lease(LeaseOptions{}); id != "noisy-0" {
+		t.Errorf("expected FIFO to lease noisy-0 first, got %s", id)
+	}
+
+	// Fair scheduling serves the tenants that have not been served yet before the noisy one again
+	var order []string
+	for i := 0; i < 4; i++ {
+		order = append(order, lease(LeaseOptions{FairScheduling: true}))
+	}
+	want := []string{"noisy-1", "quiet-a", "quiet-b", "noisy-2"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected fair lease order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestSQLiteRepository_ProcessingTimestamps(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+
+	job, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.StartedAt != nil || job.FinishedAt != nil {
+		t.Fatalf("expected a new job to have no processing timestamps")
+	}
+
+	if _, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, LeaseOptions{}); err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if err := repo.UpdateJobStatus(ctx, "job-1", models.StatusDone); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	job, err = repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Fatalf("expected started_at and finished_at to be set, got %v and %v", job.StartedAt, job.FinishedAt)
+	}
+	if job.FinishedAt.Before(*job.StartedAt) {
+		t.Errorf("expected finished_at not to precede started_at")
+	}
+
+	// A retry clears finished_at until the job reaches a terminal status again
+	if err := repo.UpdateJobStatus(ctx, "job-1", models.StatusPending); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+	job, err = repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.FinishedAt != nil {
+		t.Errorf("expected finished_at to be cleared on retry, got %v", job.FinishedAt)
+	}
+}
+
+func TestSQLiteRepository_SearchJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "send invoice 42", Status: models.StatusPending},
+		{ID: "job-2", TenantID: "tenant-1", Payload: "send receipt", Status: models.StatusPending},
+		{ID: "job-3", TenantID: "tenant-1", Payload: "discount 100%", Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	jobs, err := repo.SearchJobs(ctx, "", "invoice", 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job-1" {
+		t.Errorf("expected job-1 to match, got %d jobs", len(jobs))
+	}
+
+	jobs, err = repo.SearchJobs(ctx, "", "send", 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("expected the limit to cap results at 1, got %d", len(jobs))
+	}
+
+	// Wildcards in the query are matched literally
+	jobs, err = repo.SearchJobs(ctx, "", "0%", 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job-3" {
+		t.Errorf("expected only job-3 to match a literal %%, got %d jobs", len(jobs))
+	}
+
+	// A tenant only finds its own jobs
+	if err := repo.CreateJob(ctx, &models.Job{ID: "job-4", TenantID: "tenant-2", Payload: "send invoice 43", Status: models.StatusPending}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	jobs, err = repo.SearchJobs(ctx, "tenant-2", "invoice", 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job-4" {
+		t.Errorf("expected only tenant-2's job-4 to match, got %d jobs", len(jobs))
+	}
+}
+
+func TestSQLiteRepository_UpdateJobStatusBatch(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	seedJob(t, repo, "job-2", "tenant-1", "")
+	if err := repo.UpdateJobStatus(ctx, "job-2", models.StatusDone); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	from := []models.JobStatus{models.StatusPending, models.StatusRunning}
+	updated, err := repo.UpdateJobStatusBatch(ctx, []string{"job-1", "job-2", "missing"}, from, models.StatusCancelled)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(updated) != 3 || !updated[0] || updated[1] || updated[2] {
+		t.Fatalf("expected only job-1 to be updated, got %v", updated)
+	}
+
+	job, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.Status != models.StatusCancelled || job.FinishedAt == nil {
+		t.Errorf("expected CANCELLED with finished_at set, got %s", job.Status)
+	}
+
+	job, err = repo.GetJobByID(ctx, "job-2")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.Status != models.StatusDone {
+		t.Errorf("expected the DONE job to be left alone, got %s", job.Status)
+	}
+}
+
+func TestSQLiteRepository_UpdateJobStatusBatch_SkipsDeadLetteredJobs(t *testing.T) {
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), SQLiteOptions{KeepFailedJobs: true})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	ctx := context.Background()
+
+	job := seedJob(t, repo, "job-1", "tenant-1", "")
+	if err := repo.MoveToDeadLetterQueue(ctx, job, "max retries exceeded"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+
+	from := []models.JobStatus{models.StatusFailed, models.StatusCancelled}
+	updated, err := repo.UpdateJobStatusBatch(ctx, []string{"job-1"}, from, models.StatusPending)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(updated) != 1 || updated[0] {
+		t.Fatalf("expected the kept row not to be requeued, got %v", updated)
+	}
+
+	kept, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if kept.Status != models.StatusFailed || kept.DeadLetterID == "" {
+		t.Errorf("expected the kept row to stay FAILED in the DLQ, got %s (dead_letter_id %q)", kept.Status, kept.DeadLetterID)
+	}
+}
+
+func TestSQLiteRepository_UpdateJobStatusIf(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+
+	ok, err := repo.UpdateJobStatusIf(ctx, "job-1", models.StatusRunning, models.StatusDone)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ok {
+		t.Error("expected transition from RUNNING to fail for a PENDING job")
+	}
+
+	ok, err = repo.UpdateJobStatusIf(ctx, "job-1", models.StatusPending, models.StatusDone)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !ok {
+		t.Fatal("expected transition from PENDING to succeed")
+	}
+
+	job, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.Status != models.StatusDone || job.FinishedAt == nil {
+		t.Errorf("expected DONE with finished_at set, got %s", job.Status)
+	}
+}
+
+func TestSQLiteRepository_LeaseJob_LeasedBy(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	leased, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{WorkerID: "worker-a"})
+	if err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if leased == nil || leased.LeasedBy != "worker-a" {
+		t.Fatalf("expected the leased job to name its worker, got %+v", leased)
+	}
+
+	job, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.LeasedBy != "worker-a" {
+		t.Errorf("expected leased_by worker-a, got %q", job.LeasedBy)
+	}
+
+	// The worker stays recorded after the job finishes, for tracing what ran it
+	if ok, err := repo.CompleteJob(ctx, "job-1", ""); err != nil || !ok {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+	job, err = repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.LeasedBy != "worker-a" {
+		t.Errorf("expected leased_by to be kept after completion, got %q", job.LeasedBy)
+	}
+}
+
+func TestSQLiteRepository_RescheduleJob(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	if _, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{}); err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+	if ok, err := repo.RescheduleJob(ctx, "job-1", at); err != nil || !ok {
+		t.Fatalf("expected the RUNNING job to be rescheduled, got %t, %v", ok, err)
+	}
+	if ok, err := repo.RescheduleJob(ctx, "job-1", at); err != nil || ok {
+		t.Errorf("expected a PENDING job not to be rescheduled, got %t, %v", ok, err)
+	}
+
+	job, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.Status != models.StatusPending || job.RetryCount != 0 {
+		t.Errorf("expected PENDING with no retries, got %s with %d", job.Status, job.RetryCount)
+	}
+	if job.NextRetryAt == nil || !job.NextRetryAt.Equal(at) {
+		t.Errorf("expected next_retry_at %s, got %v", at, job.NextRetryAt)
+	}
+
+	// The rescheduled job is skipped until its time comes, while newer jobs are leased
+	seedJob(t, repo, "job-2", "tenant-1", "")
+	leased, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if leased == nil || leased.ID != "job-2" {
+		t.Fatalf("expected job-2 to be leased ahead of the rescheduled job, got %+v", leased)
+	}
+	if leased, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{}); err != nil || leased != nil {
+		t.Fatalf("expected no leasable job, got %+v, %v", leased, err)
+	}
+
+	if _, err := repo.db.ExecContext(ctx, "UPDATE jobs SET next_retry_at = ? WHERE id = 'job-1'", time.Now().Add(-time.Second).UnixMilli()); err != nil {
+		t.Fatalf("failed to move next_retry_at: %v", err)
+	}
+	leased, err = repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if leased == nil || leased.ID != "job-1" || leased.NextRetryAt != nil {
+		t.Fatalf("expected job-1 to be leased with next_retry_at cleared, got %+v", leased)
+	}
+	if job, err := repo.GetJobByID(ctx, "job-1"); err != nil || job.NextRetryAt != nil {
+		t.Errorf("expected next_retry_at to be cleared when leased, got %+v, %v", job, err)
+	}
+}
+
+func TestSQLiteRepository_ExpiredJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	soon := time.Now().Add(50 * time.Millisecond).UTC().Truncate(time.Millisecond)
+	later := time.Now().Add(time.Hour)
+	for id, expiresAt := range map[string]*time.Time{"expiring": &soon, "later": &later, "forever": nil} {
+		job := &models.Job{ID: id, TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3, ExpiresAt: expiresAt}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job %s: %v", id, err)
+		}
+	}
+
+	job, err := repo.GetJobByID(ctx, "expiring")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.ExpiresAt == nil || !job.ExpiresAt.Equal(soon) {
+		t.Errorf("expected expires_at %s, got %v", soon, job.ExpiresAt)
+	}
+
+	if expired, err := repo.ListExpiredJobs(ctx, time.Now()); err != nil || len(expired) != 0 {
+		t.Fatalf("expected no expired jobs yet, got %d, %v", len(expired), err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	expired, err := repo.ListExpiredJobs(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "expiring" {
+		t.Fatalf("expected only the expiring job, got %+v", expired)
+	}
+
+	// The expired job is the oldest but is never leased
+	leased, err := repo.LeaseJobs(ctx, models.DefaultQueue, 3, 30*time.Second, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("failed to lease jobs: %v", err)
+	}
+	if len(leased) != 2 {
+		t.Fatalf("expected the 2 unexpired jobs to be leased, got %d", len(leased))
+	}
+	for _, job := range leased {
+		if job.ID == "expiring" {
+			t.Error("expected the expired job not to be leased")
+		}
+	}
+}
+
+func TestSQLiteRepository_ListJobTransitions(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	if _, err := repo.LeaseJob(ctx, models.DefaultQueue, 30*time.Second, LeaseOptions{WorkerID: "worker-a"}); err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if ok, err := repo.CompleteJob(ctx, "job-1", "ok"); err != nil || !ok {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+	// A write that leaves the status unchanged is not a transition
+	if err := repo.UpdateJobStatus(ctx, "job-1", models.StatusDone); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	transitions, err := repo.ListJobTransitions(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []models.JobTransition{
+		{JobID: "job-1", TenantID: "tenant-1", ToStatus: models.StatusPending},
+		{JobID: "job-1", TenantID: "tenant-1", FromStatus: models.StatusPending, ToStatus: models.StatusRunning, WorkerID: "worker-a"},
+		{JobID: "job-1", TenantID: "tenant-1", FromStatus: models.StatusRunning, ToStatus: models.StatusDone, WorkerID: "worker-a"},
+	}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected %d transitions, got %+v", len(expected), transitions)
+	}
+	for i, want := range expected {
+		got := transitions[i]
+		got.At = time.Time{}
+		if got != want {
+			t.Errorf("transition %d: expected %+v, got %+v", i, want, got)
+		}
+		if transitions[i].At.IsZero() {
+			t.Errorf("transition %d: expected a timestamp", i)
+		}
+	}
+
+	// The trail outlives the job and cannot be rewritten
+	seedJob(t, repo, "job-2", "tenant-1", "")
+	job, err := repo.GetJobByID(ctx, "job-2")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if err := repo.MoveToDeadLetterQueue(ctx, job, "dependency failed"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+	transitions, err = repo.ListJobTransitions(ctx, "job-2")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(transitions) != 2 || transitions[1].ToStatus != models.StatusFailed {
+		t.Errorf("expected the dead lettered job to end FAILED, got %+v", transitions)
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `UPDATE job_events SET to_status = 'DONE'`); err == nil {
+		t.Error("expected job_events to reject updates")
+	}
+	if _, err := repo.db.ExecContext(ctx, `DELETE FROM job_events`); err == nil {
+		t.Error("expected job_events to reject deletes")
+	}
+}
+
+func TestSQLiteRepository_MoveToDeadLetterQueue_Attempts(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	job := seedJob(t, repo, "job-1", "tenant-1", "")
+	for i, reason := range []string{"timeout", "connection refused"} {
+		attempt := &models.JobAttempt{Attempt: i + 1, Reason: reason, At: time.Now()}
+		if err := repo.RecordJobAttempt(ctx, job.ID, attempt); err != nil {
+			t.Fatalf("failed to record attempt: %v", err)
+		}
+	}
+
+	if err := repo.MoveToDeadLetterQueue(ctx, job, "max retries exceeded"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+
+	dlqJobs, err := repo.ListDeadLetterJobs(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(dlqJobs) != 1 {
+		t.Fatalf("expected 1 DLQ job, got %d", len(dlqJobs))
+	}
+
+	attempts := dlqJobs[0].Attempts
+	if len(attempts) != 2 || attempts[0].Reason != "timeout" || attempts[1].Reason != "connection refused" {
+		t.Errorf("expected both attempts in order, got %+v", attempts)
+	}
+}
+
+// seedDependentJob inserts a PENDING job that depends on the given jobs
+func seedDependentJob(t *testing.T, repo *SQLiteRepository, id string, dependsOn ...string) *models.Job {
+	t.Helper()
+
+	job := &models.Job{ID: id, TenantID: "tenant-1", Payload: "payload-" + id, Status: models.StatusPending, MaxRetries: 3, DependsOn: dependsOn}
+	if err := repo.CreateJob(context.Background(), job); err != nil {
+		t.Fatalf("failed to create job %s: %v", id, err)
+	}
+	return job
+}
+
+func TestSQLiteRepository_LeaseJob_Dependencies(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "parent", "tenant-1", "")
+	seedDependentJob(t, repo, "child", "parent")
+
+	lease := func() *models.Job {
+		t.Helper()
+		job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, LeaseOptions{})
+		if err != nil {
+			t.Fatalf("failed to lease job: %v", err)
+		}
+		return job
+	}
+
+	if job := lease(); job == nil || job.ID != "parent" {
+		t.Fatalf("expected the parent to be leased first, got %+v", job)
+	}
+	if job := lease(); job != nil {
+		t.Fatalf("expected the child to wait for its RUNNING parent, got %s", job.ID)
+	}
+
+	if _, err := repo.CompleteJob(ctx, "parent", ""); err != nil {
+		t.Fatalf("failed to complete parent: %v", err)
+	}
+	job := lease()
+	if job == nil || job.ID != "child" {
+		t.Fatalf("expected the child once its parent is DONE, got %+v", job)
+	}
+	if len(job.DependsOn) != 1 || job.DependsOn[0] != "parent" {
+		t.Errorf("expected depends_on to round-trip, got %v", job.DependsOn)
+	}
+}
+
+func TestSQLiteRepository_MoveToDeadLetterQueue_Dependents(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	parent := seedJob(t, repo, "parent", "tenant-1", "")
+	seedDependentJob(t, repo, "child", "parent")
+	seedDependentJob(t, repo, "grandchild", "child")
+	seedJob(t, repo, "unrelated", "tenant-1", "")
+
+	if err := repo.MoveToDeadLetterQueue(ctx, parent, "max retries exceeded"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+
+	reasons := make(map[string]string)
+	dlqJobs, err := repo.ListDeadLetterJobs(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, dlqJob := range dlqJobs {
+		reasons[dlqJob.JobID] = dlqJob.FailureReason
+	}
+
+	expected := map[string]string{
+		"parent":     "max retries exceeded",
+		"child":      "dependency failed: job parent",
+		"grandchild": "dependency failed: job child",
+	}
+	if len(reasons) != len(expected) {
+		t.Fatalf("expected %d DLQ jobs, got %v", len(expected), reasons)
+	}
+	for id, reason := range expected {
+		if reasons[id] != reason {
+			t.Errorf("expected %s in the DLQ with reason %q, got %q", id, reason, reasons[id])
+		}
+	}
+
+	if _, err := repo.GetJobByID(ctx, "unrelated"); err != nil {
+		t.Errorf("expected the unrelated job to stay, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_FailExpiredJob(t *testing.T) {
+	for _, deadLetter := range []bool{false, true} {
+		repo := newTestRepository(t)
+		ctx := context.Background()
+
+		parent := seedJob(t, repo, "parent", "tenant-1", "")
+		seedDependentJob(t, repo, "child", "parent")
+		seedDependentJob(t, repo, "grandchild", "child")
+
+		attempt := &models.JobAttempt{Attempt: 1, Reason: "expired before execution", At: time.Now()}
+		ok, err := repo.FailExpiredJob(ctx, parent, attempt, deadLetter)
+		if err != nil || !ok {
+			t.Fatalf("dead_letter=%t: expected the pending job to be failed, got %t (err %v)", deadLetter, ok, err)
+		}
+
+		reasons := make(map[string]string)
+		dlqJobs, err := repo.ListDeadLetterJobs(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, dlqJob := range dlqJobs {
+			reasons[dlqJob.JobID] = dlqJob.FailureReason
+		}
+
+		// Dependents can never run, so they go to the DLQ whether or not the expired job does
+		expected := map[string]string{
+			"child":      "dependency failed: job parent",
+			"grandchild": "dependency failed: job child",
+		}
+		if deadLetter {
+			expected["parent"] = attempt.Reason
+		} else {
+			job, err := repo.GetJobByID(ctx, "parent")
+			if err != nil || job.Status != models.StatusFailed {
+				t.Errorf("expected the expired job FAILED outside the DLQ, got %+v (err %v)", job, err)
+			}
+			attempts, err := repo.ListJobAttempts(ctx, "parent")
+			if err != nil || len(attempts) != 1 || attempts[0].Reason != attempt.Reason {
+				t.Errorf("expected the expiry recorded as an attempt, got %+v (err %v)", attempts, err)
+			}
+		}
+		if len(reasons) != len(expected) {
+			t.Fatalf("dead_letter=%t: expected %d DLQ jobs, got %v", deadLetter, len(expected), reasons)
+		}
+		for id, reason := range expected {
+			if reasons[id] != reason {
+				t.Errorf("dead_letter=%t: expected %s in the DLQ with reason %q, got %q", deadLetter, id, reason, reasons[id])
+			}
+		}
+	}
+
+	// A job that is no longer PENDING is left alone
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	job := seedJob(t, repo, "running", "tenant-1", "")
+	if err := repo.UpdateJobStatus(ctx, "running", models.StatusRunning); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+	ok, err := repo.FailExpiredJob(ctx, job, &models.JobAttempt{Attempt: 1, Reason: "expired before execution", At: time.Now()}, true)
+	if err != nil || ok {
+		t.Errorf("expected a running job to be left alone, got %t (err %v)", ok, err)
+	}
+	if attempts, err := repo.ListJobAttempts(ctx, "running"); err != nil || len(attempts) != 0 {
+		t.Errorf("expected no attempt recorded for a running job, got %+v (err %v)", attempts, err)
+	}
+}
+
+func TestSQLiteRepository_RetryJob(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	attempt := &models.JobAttempt{Attempt: 1, Reason: "boom", At: time.Now()}
+
+	// A job that is not RUNNING is neither retried nor given an attempt
+	ok, err := repo.RetryJob(ctx, "job-1", attempt)
+	if err != nil || ok {
+		t.Fatalf("expected a pending job to be left alone, got %t (err %v)", ok, err)
+	}
+	if attempts, err := repo.ListJobAttempts(ctx, "job-1"); err != nil || len(attempts) != 0 {
+		t.Errorf("expected no attempt recorded for a pending job, got %+v (err %v)", attempts, err)
+	}
+
+	if err := repo.UpdateJobStatus(ctx, "job-1", models.StatusRunning); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+	ok, err = repo.RetryJob(ctx, "job-1", attempt)
+	if err != nil || !ok {
+		t.Fatalf("expected the running job to be retried, got %t (err %v)", ok, err)
+	}
+
+	job, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job.Status != models.StatusPending || job.RetryCount != 1 {
+		t.Errorf("expected PENDING with 1 retry, got %s with %d", job.Status, job.RetryCount)
+	}
+	attempts, err := repo.ListJobAttempts(ctx, "job-1")
+	if err != nil || len(attempts) != 1 || attempts[0].Reason != "boom" {
+		t.Errorf("expected the failure recorded as an attempt, got %+v (err %v)", attempts, err)
+	}
+}
+
+func TestSQLiteRepository_FailRunningJob(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	parent := seedJob(t, repo, "parent", "tenant-1", "")
+	seedDependentJob(t, repo, "child", "parent")
+	attempt := &models.JobAttempt{Attempt: 1, Reason: "bad payload", At: time.Now()}
+
+	// A job that is not RUNNING stays where it is
+	ok, err := repo.FailRunningJob(ctx, parent, attempt, "permanent failure: bad payload")
+	if err != nil || ok {
+		t.Fatalf("expected a pending job to be left alone, got %t (err %v)", ok, err)
+	}
+	if dlqJobs, err := repo.ListDeadLetterJobs(ctx); err != nil || len(dlqJobs) != 0 {
+		t.Errorf("expected an empty DLQ, got %+v (err %v)", dlqJobs, err)
+	}
+	if attempts, err := repo.ListJobAttempts(ctx, "parent"); err != nil || len(attempts) != 0 {
+		t.Errorf("expected no attempt recorded for a pending job, got %+v (err %v)", attempts, err)
+	}
+
+	if err := repo.UpdateJobStatus(ctx, "parent", models.StatusRunning); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+	ok, err = repo.FailRunningJob(ctx, parent, attempt, "permanent failure: bad payload")
+	if err != nil || !ok {
+		t.Fatalf("expected the running job to be dead-lettered, got %t (err %v)", ok, err)
+	}
+
+	reasons := make(map[string]string)
+	dlqJobs, err := repo.ListDeadLetterJobs(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, dlqJob := range dlqJobs {
+		reasons[dlqJob.JobID] = dlqJob.FailureReason
+	}
+	expected := map[string]string{
+		"parent": "permanent failure: bad payload",
+		"child":  "dependency failed: job parent",
+	}
+	if len(reasons) != len(expected) {
+		t.Fatalf("expected %d DLQ jobs, got %v", len(expected), reasons)
+	}
+	for id, reason := range expected {
+		if reasons[id] != reason {
+			t.Errorf("expected %s in the DLQ with reason %q, got %q", id, reason, reasons[id])
+		}
+	}
+}
+
+func TestSQLiteRepository_MoveToDeadLetterQueue_KeepFailedJobs(t *testing.T) {
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), SQLiteOptions{KeepFailedJobs: true})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	ctx := context.Background()
+
+	parent := seedJob(t, repo, "parent", "tenant-1", "")
+	seedDependentJob(t, repo, "child", "parent")
+	if err := repo.RecordJobAttempt(ctx, parent.ID, &models.JobAttempt{Attempt: 1, Reason: "timeout", At: time.Now()}); err != nil {
+		t.Fatalf("failed to record attempt: %v", err)
+	}
+
+	if err := repo.MoveToDeadLetterQueue(ctx, parent, "max retries exceeded"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+
+	dlqJobs, err := repo.ListDeadLetterJobs(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	entries := make(map[string]string)
+	for _, dlqJob := range dlqJobs {
+		entries[dlqJob.JobID] = dlqJob.ID
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected parent and child in the DLQ, got %v", entries)
+	}
+
+	for _, id := range []string{"parent", "child"} {
+		job, err := repo.GetJobByID(ctx, id)
+		if err != nil {
+			t.Fatalf("expected %s to be kept, got %v", id, err)
+		}
+		if job.Status != models.StatusFailed {
+			t.Errorf("expected %s to be FAILED, got %s", id, job.Status)
+		}
+		if job.DeadLetterID != entries[id] {
+			t.Errorf("expected %s to link to %q, got %q", id, entries[id], job.DeadLetterID)
+		}
+		if job.DeletedAt == nil {
+			t.Errorf("expected %s to carry a deleted_at tombstone", id)
+		}
+	}
+
+	attempts, err := repo.ListJobAttempts(ctx, parent.ID)
+	if err != nil {
+		t.Fatalf("failed to list attempts: %v", err)
+	}
+	if len(attempts) != 1 {
+		t.Errorf("expected the kept job's attempt history to stay, got %d attempts", len(attempts))
+	}
+
+	failed, err := repo.GetFailedJobsCount(ctx)
+	if err != nil {
+		t.Fatalf("failed to count failed jobs: %v", err)
+	}
+	if failed != 2 {
+		t.Errorf("expected kept jobs not to be counted twice, got %d failed", failed)
+	}
+}
+
+func TestSQLiteRepository_ListJobsByStatusPage(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		seedJob(t, repo, fmt.Sprintf("job-%d", i), "tenant-1", "")
+	}
+	if err := repo.UpdateJobStatus(ctx, "job-3", models.StatusDone); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	// Walking the cursor visits every PENDING job once, in creation order
+	var seen []string
+	page := JobPage{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("expected the cursor to run out, saw %v", seen)
+		}
+		jobs, next, err := repo.ListJobsByStatusPage(ctx, page, models.StatusPending)
+		if err != nil {
+			t.Fatalf("failed to list jobs: %v", err)
+		}
+		for _, job := range jobs {
+			seen = append(seen, job.ID)
+		}
+		if next == nil {
+			break
+		}
+		parsed, err := ParseJobCursor(next.String())
+		if err != nil || parsed != *next {
+			t.Fatalf("expected cursor %v to round-trip, got %v, %v", next, parsed, err)
+		}
+		page.After = &parsed
+	}
+	if strings.Join(seen, ",") != "job-1,job-2,job-4,job-5" {
+		t.Errorf("expected job-1,job-2,job-4,job-5, got %v", seen)
+	}
+
+	// Offset pagination still works without a cursor
+	jobs, next, err := repo.ListJobsByStatusPage(ctx, JobPage{Offset: 3, Limit: 2}, models.StatusPending)
+	if err != nil {
+		t.Fatalf("failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job-5" || next != nil {
+		t.Errorf("expected only job-5 and no cursor, got %d jobs and cursor %v", len(jobs), next)
+	}
+
+	// A descending cursor walks newest first
+	seen = nil
+	page = JobPage{Limit: 3, Descending: true}
+	for pages := 0; ; pages++ {
+		if pages > 2 {
+			t.Fatalf("expected the descending cursor to run out, saw %v", seen)
+		}
+		jobs, next, err := repo.ListJobsByStatusPage(ctx, page, models.StatusPending)
+		if err != nil {
+			t.Fatalf("failed to list jobs: %v", err)
+		}
+		for _, job := range jobs {
+			seen = append(seen, job.ID)
+		}
+		if next == nil {
+			break
+		}
+		page.After = next
+	}
+	if strings.Join(seen, ",") != "job-5,job-4,job-2,job-1" {
+		t.Errorf("expected job-5,job-4,job-2,job-1, got %v", seen)
+	}
+
+	if _, err := ParseJobCursor("not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_Metadata(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "a", Metadata: map[string]string{"user_id": "42", "source": "web"}, Status: models.StatusPending},
+		{ID: "job-2", TenantID: "tenant-1", Payload: "b", Metadata: map[string]string{"user_id": "42", "source": "cron"}, Status: models.StatusPending},
+		{ID: "job-3", TenantID: "tenant-1", Payload: "c", Metadata: map[string]string{"user_id": "7"}, Status: models.StatusPending},
+		{ID: "job-4", TenantID: "tenant-1", Payload: "d", Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	job, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if len(job.Metadata) != 2 || job.Metadata["user_id"] != "42" || job.Metadata["source"] != "web" {
+		t.Errorf("expected metadata to round-trip, got %v", job.Metadata)
+	}
+	if job, err := repo.GetJobByID(ctx, "job-4"); err != nil || job.Metadata != nil {
+		t.Errorf("expected no metadata on job-4, got %v, %v", job.Metadata, err)
+	}
+
+	tests := []struct {
+		filter   map[string]string
+		expected string
+	}{
+		{map[string]string{"user_id": "42"}, "job-1,job-2"},
+		{map[string]string{"user_id": "42", "source": "cron"}, "job-2"},
+		{map[string]string{"user_id": "4"}, ""},
+		{map[string]string{"missing": "42"}, ""},
+	}
+	for _, tt := range tests {
+		jobs, _, err := repo.ListJobsByStatusPage(ctx, JobPage{Metadata: tt.filter}, models.StatusPending)
+		if err != nil {
+			t.Fatalf("failed to list jobs: %v", err)
+		}
+		var ids []string
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		if strings.Join(ids, ",") != tt.expected {
+			t.Errorf("%v: expected %q, got %v", tt.filter, tt.expected, ids)
+		}
+	}
+}
+
+func TestSQLiteRepository_ListJobsByTag(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, job := range []*models.Job{
+		{ID: "job-1", TenantID: "tenant-1", Payload: "a", Tags: []string{"email", "nightly"}, Status: models.StatusPending},
+		{ID: "job-2", TenantID: "tenant-2", Payload: "b", Tags: []string{"email"}, Status: models.StatusPending},
+		{ID: "job-3", TenantID: "tenant-1", Payload: "c", Tags: []string{"emails"}, Status: models.StatusPending},
+		{ID: "job-4", TenantID: "tenant-1", Payload: "d", Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+	if err := repo.UpdateJobStatus(ctx, "job-2", models.StatusDone); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	jobs, err := repo.ListJobsByTag(ctx, "", "email")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "job-1" || jobs[1].ID != "job-2" {
+		t.Fatalf("expected job-1 and job-2 to be tagged email, got %d jobs", len(jobs))
+	}
+	if len(jobs[0].Tags) != 2 || jobs[0].Tags[1] != "nightly" {
+		t.Errorf("expected tags to round-trip, got %v", jobs[0].Tags)
+	}
+
+	jobs, err = repo.ListJobsByTag(ctx, "", "email", models.StatusDone)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job-2" {
+		t.Errorf("expected only DONE job-2, got %d jobs", len(jobs))
+	}
+
+	jobs, err = repo.ListJobsByTag(ctx, "", "email", models.StatusPending, models.StatusDone)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Errorf("expected job-1 and job-2, got %d jobs", len(jobs))
+	}
+
+	jobs, err = repo.ListJobsByTag(ctx, "tenant-2", "email")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job-2" {
+		t.Errorf("expected only tenant-2's job-2, got %d jobs", len(jobs))
+	}
+}
+
+func TestSQLiteRepository_ListJobsByStatusPage_TenantID(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	seedJob(t, repo, "job-2", "tenant-2", "")
+	seedJob(t, repo, "job-3", "tenant-1", "")
+
+	jobs, _, err := repo.ListJobsByStatusPage(ctx, JobPage{TenantID: "tenant-1"}, models.StatusPending)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "job-1" || jobs[1].ID != "job-3" {
+		t.Errorf("expected only tenant-1's jobs, got %d jobs", len(jobs))
+	}
+}
+
+func TestSQLiteRepository_ListSchedules_TenantID(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, schedule := range []*models.Schedule{
+		{ID: "schedule-1", TenantID: "tenant-1", CronExpr: "* * * * *", Payload: "a", NextFireAt: time.Now()},
+		{ID: "schedule-2", TenantID: "tenant-2", CronExpr: "* * * * *", Payload: "b", NextFireAt: time.Now()},
+	} {
+		if err := repo.CreateSchedule(ctx, schedule); err != nil {
+			t.Fatalf("failed to create schedule: %v", err)
+		}
+	}
+
+	schedules, err := repo.ListSchedules(ctx, "")
+	if err != nil || len(schedules) != 2 {
+		t.Fatalf("expected every schedule, got %d (err %v)", len(schedules), err)
+	}
+	schedules, err = repo.ListSchedules(ctx, "tenant-2")
+	if err != nil || len(schedules) != 1 || schedules[0].ID != "schedule-2" {
+		t.Errorf("expected only tenant-2's schedule, got %d (err %v)", len(schedules), err)
+	}
+}
+
+func TestSQLiteRepository_ListJobsByStatus_Multiple(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i, status := range []models.JobStatus{models.StatusPending, models.StatusRunning, models.StatusDone, models.StatusPending} {
+		id := fmt.Sprintf("job-%d", i+1)
+		seedJob(t, repo, id, "tenant-1", "")
+		if err := repo.UpdateJobStatus(ctx, id, status); err != nil {
+			t.Fatalf("failed to update job: %v", err)
+		}
+	}
+
+	jobs, err := repo.ListJobsByStatus(ctx, models.StatusPending, models.StatusRunning)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var ids []string
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	if strings.Join(ids, ",") != "job-1,job-2,job-4" {
+		t.Errorf("expected job-1, job-2 and job-4 in submission order, got %v", ids)
+	}
+
+	jobs, err = repo.ListJobsByStatus(ctx, models.StatusDone)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job-3" {
+		t.Errorf("expected only DONE job-3, got %d jobs", len(jobs))
+	}
+}
+
+func TestSQLiteRepository_CountJobsAheadOf(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// Jobs created in the same millisecond are ordered by seq
+	var jobs []*models.Job
+	for i := 1; i <= 4; i++ {
+		jobs = append(jobs, seedJob(t, repo, fmt.Sprintf("job-%d", i), "tenant-1", ""))
+	}
+	if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = 1000`); err != nil {
+		t.Fatalf("failed to align created_at: %v", err)
+	}
+	if err := repo.UpdateJobStatus(ctx, "job-2", models.StatusRunning); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	for i, expected := range []int{0, 1, 1, 2} {
+		job, err := repo.GetJobByID(ctx, jobs[i].ID)
+		if err != nil {
+			t.Fatalf("failed to get job: %v", err)
+		}
+		ahead, err := repo.CountJobsAheadOf(ctx, job)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if ahead != expected {
+			t.Errorf("%s: expected %d jobs ahead, got %d", job.ID, expected, ahead)
+		}
+	}
+}
+
+func TestSQLiteRepository_MigrateFreshDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+	latest := schemaMigrations[len(schemaMigrations)-1].version
+
+	repo, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	version, err := repo.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to read schema version: %v", err)
+	}
+	if version != latest {
+		t.Errorf("expected schema version %d, got %d", latest, version)
+	}
+	repo.Close()
+
+	// Reopening applies nothing new
+	repo, err = NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("failed to reopen repository: %v", err)
+	}
+	defer repo.Close()
+
+	var applied int
+	if err := repo.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("failed to count migrations: %v", err)
+	}
+	if applied != len(schemaMigrations) {
+		t.Errorf("expected %d applied migrations, got %d", len(schemaMigrations), applied)
+	}
+}
+
+func TestSQLiteRepository_MigrateBaselineDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	// A database created before the migration runner existed, with only the baseline schema
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	baseline, err := migrations.FS.ReadFile("0001_baseline.sql")
+	if err != nil {
+		t.Fatalf("failed to read baseline: %v", err)
+	}
+	if _, err := db.Exec(string(baseline)); err != nil {
+		t.Fatalf("failed to create baseline schema: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO jobs (id, tenant_id, payload, status, created_at, updated_at) VALUES ('old-job', 'tenant-1', 'legacy', 'PENDING', 1700000000, 1700000000)`)
+	if err != nil {
+		t.Fatalf("failed to insert legacy job: %v", err)
+	}
+	db.Close()
+
+	repo, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("failed to migrate baseline database: %v", err)
+	}
+	defer repo.Close()
+
+	job, err := repo.GetJobByID(ctx, "old-job")
+	if err != nil {
+		t.Fatalf("failed to read legacy job: %v", err)
+	}
+	if job.Queue != models.DefaultQueue || len(job.Tags) != 0 {
+		t.Errorf("expected new columns to take their defaults, got queue=%q tags=%v", job.Queue, job.Tags)
+	}
+	if !job.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected created_at in seconds to survive the switch to milliseconds, got %s", job.CreatedAt)
+	}
+
+	leased, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, LeaseOptions{})
+	if err != nil || leased == nil || leased.ID != "old-job" {
+		t.Errorf("expected the legacy job to be leasable after migrating, got %v (err %v)", leased, err)
+	}
+}
+
+func TestSchemaMigrations_Ordered(t *testing.T) {
+	versions := make(map[int]bool)
+	for i, m := range schemaMigrations {
+		if m.version != i+1 {
+			t.Errorf("expected migration %d to have version %d, got %d", i, i+1, m.version)
+		}
+		versions[m.version] = true
+	}
+
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("failed to list migration files: %v", err)
+	}
+	for _, file := range files {
+		version, err := strconv.Atoi(strings.SplitN(file, "_", 2)[0])
+		if err != nil || !versions[version] {
+			t.Errorf("migration file %s does not match a registered version", file)
+		}
+	}
+}
+
+func TestSQLiteRepository_CountJobsGroupedByStatus(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "job-1", "tenant-1", "")
+	seedJob(t, repo, "job-2", "tenant-1", "")
+	seedJob(t, repo, "job-3", "tenant-2", "")
+	if err := repo.UpdateJobStatus(ctx, "job-3", models.StatusDone); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	counts, err := repo.CountJobsGroupedByStatus(ctx)
+	if err != nil {
+		t.Fatalf("failed to count jobs: %v", err)
+	}
+	if len(counts) != 2 || counts[models.StatusPending] != 2 || counts[models.StatusDone] != 1 {
+		t.Errorf("expected 2 PENDING and 1 DONE, got %v", counts)
+	}
+}
+
+func TestSQLiteRepository_CountDeadLetterByReason(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, seed := range []struct{ id, reason string }{
+		{"job-1", "max retries exceeded: timeout"},
+		{"job-2", "max retries exceeded: connection refused"},
+		{"job-3", "permanent failure: invalid payload"},
+		{"job-4", "dependency failed: job job-1"},
+		{"job-5", "dependency failed: job job-2"},
+		{"job-6", "cancelled"},
+	} {
+		job := seedJob(t, repo, seed.id, "tenant-1", "")
+		if err := repo.MoveToDeadLetterQueue(ctx, job, seed.reason); err != nil {
+			t.Fatalf("failed to move %s to DLQ: %v", seed.id, err)
+		}
+	}
+
+	counts, err := repo.CountDeadLetterByReason(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to count dead letter jobs: %v", err)
+	}
+	expected := map[string]int{
+		"max retries exceeded": 2,
+		"permanent failure":    1,
+		"dependency failed":    2,
+		"cancelled":            1,
+	}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+	for reason, count := range expected {
+		if counts[reason] != count {
+			t.Errorf("expected %d for %q, got %d", count, reason, counts[reason])
+		}
+	}
+
+	other := seedJob(t, repo, "job-7", "tenant-2", "")
+	if err := repo.MoveToDeadLetterQueue(ctx, other, "permanent failure: bad input"); err != nil {
+		t.Fatalf("failed to move job-7 to DLQ: %v", err)
+	}
+	counts, err = repo.CountDeadLetterByReason(ctx, "tenant-2")
+	if err != nil {
+		t.Fatalf("failed to count dead letter jobs: %v", err)
+	}
+	if len(counts) != 1 || counts["permanent failure"] != 1 {
+		t.Errorf("expected only tenant-2's permanent failure, got %v", counts)
+	}
+}
+
+func TestSQLiteRepository_TimestampPrecision(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	first := seedJob(t, repo, "first", "tenant-1", "")
+	time.Sleep(5 * time.Millisecond)
+	second := seedJob(t, repo, "second", "tenant-1", "")
+
+	got, err := repo.GetJobByID(ctx, "second")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if !got.CreatedAt.Equal(second.CreatedAt) || got.CreatedAt.Location() != time.UTC {
+		t.Errorf("expected created_at %s in UTC to round-trip, got %s", second.CreatedAt, got.CreatedAt)
+	}
+	if !got.CreatedAt.After(first.CreatedAt) {
+		t.Errorf("expected sub-second precision, got %s and %s", first.CreatedAt, got.CreatedAt)
+	}
+}
+
+func TestSQLiteRepository_LeaseJob_SubmissionOrder(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// A burst submitted within the same millisecond is still leased in submission order
+	for i := 0; i < 20; i++ {
+		seedJob(t, repo, fmt.Sprintf("job-%02d", 19-i), "tenant-1", "")
+	}
+	if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = 1`); err != nil {
+		t.Fatalf("failed to align created_at: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		job, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, LeaseOptions{})
+		if err != nil || job == nil {
+			t.Fatalf("failed to lease job: %v", err)
+		}
+		if want := fmt.Sprintf("job-%02d", 19-i); job.ID != want {
+			t.Fatalf("expected %s to be leased next, got %s", want, job.ID)
+		}
+	}
+}
+
+func TestSQLiteRepository_GetJobByTenantAndIdempotencyKey_Since(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	seedJob(t, repo, "first", "tenant-1", "key-1")
+	if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = ? WHERE id = 'first'`, time.Now().Add(-48*time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("failed to backdate job: %v", err)
+	}
+
+	// The key is no longer unique in the database, so it can be reused
+	seedJob(t, repo, "second", "tenant-1", "key-1")
+
+	job, err := repo.GetJobByTenantAndIdempotencyKey(ctx, "tenant-1", "key-1", time.Time{})
+	if err != nil || job == nil || job.ID != "second" {
+		t.Fatalf("expected the newest job with the key, got %v (err %v)", job, err)
+	}
+
+	job, err = repo.GetJobByTenantAndIdempotencyKey(ctx, "tenant-1", "key-1", time.Now().Add(-time.Hour))
+	if err != nil || job == nil || job.ID != "second" {
+		t.Fatalf("expected the recent job within the window, got %v (err %v)", job, err)
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `DELETE FROM jobs WHERE id = 'second'`); err != nil {
+		t.Fatalf("failed to delete job: %v", err)
+	}
+	job, err = repo.GetJobByTenantAndIdempotencyKey(ctx, "tenant-1", "key-1", time.Now().Add(-time.Hour))
+	if err != nil || job != nil {
+		t.Errorf("expected no job once the key is older than the window, got %v (err %v)", job, err)
+	}
+}
+
+func TestSQLiteRepository_GetJobByTenantAndDedupHash(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, job := range []*models.Job{
+		{ID: "old", TenantID: "tenant-1", Payload: "p", Status: models.StatusDone, DedupHash: "hash-1"},
+		{ID: "other-tenant", TenantID: "tenant-2", Payload: "p", Status: models.StatusPending, DedupHash: "hash-1"},
+		{ID: "no-hash", TenantID: "tenant-1", Payload: "p", Status: models.StatusPending},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+	if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = ? WHERE id = 'old'`, time.Now().Add(-time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("failed to backdate job: %v", err)
+	}
+
+	job, err := repo.GetJobByTenantAndDedupHash(ctx, "tenant-1", "hash-1", time.Now().Add(-2*time.Hour))
+	if err != nil || job == nil || job.ID != "old" {
+		t.Fatalf("expected the tenant's job with the hash, got %v (err %v)", job, err)
+	}
+
+	job, err = repo.GetJobByTenantAndDedupHash(ctx, "tenant-1", "hash-1", time.Now().Add(-5*time.Minute))
+	if err != nil || job != nil {
+		t.Errorf("expected no job once it is older than the window, got %v (err %v)", job, err)
+	}
+}
+
+func TestSQLiteRepository_UpdatePayload_DedupHash(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	if err := repo.CreateJob(ctx, &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "typo", Status: models.StatusPending, DedupHash: "hash-typo"}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	ok, err := repo.UpdatePayload(ctx, "job-1", "fixed", false, "hash-fixed")
+	if err != nil || !ok {
+		t.Fatalf("expected the pending job to be updated, got %t (err %v)", ok, err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	if job, err := repo.GetJobByTenantAndDedupHash(ctx, "tenant-1", "hash-fixed", since); err != nil || job == nil || job.ID != "job-1" {
+		t.Errorf("expected the job under its new hash, got %v (err %v)", job, err)
+	}
+	if job, err := repo.GetJobByTenantAndDedupHash(ctx, "tenant-1", "hash-typo", since); err != nil || job != nil {
+		t.Errorf("expected no job under the old hash, got %v (err %v)", job, err)
+	}
+}
+
+func TestSQLiteRepository_PayloadJSON(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: `{"a":1}`, PayloadJSON: true, Status: models.StatusPending}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	seedJob(t, repo, "job-2", "tenant-1", "")
+
+	got, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if !got.PayloadJSON || got.Payload != `{"a":1}` {
+		t.Errorf("expected JSON payload {\"a\":1}, got %q (json=%t)", got.Payload, got.PayloadJSON)
+	}
+	if plain, _ := repo.GetJobByID(ctx, "job-2"); plain.PayloadJSON {
+		t.Error("expected a string payload to stay a string")
+	}
+
+	if err := repo.MoveToDeadLetterQueue(ctx, got, "failed"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+	dlqJobs, err := repo.ListDeadLetterJobs(ctx)
+	if err != nil {
+		t.Fatalf("failed to list dead letter jobs: %v", err)
+	}
+	if len(dlqJobs) != 1 || !dlqJobs[0].PayloadJSON {
+		t.Errorf("expected the dead letter job to keep its JSON payload, got %+v", dlqJobs)
+	}
+}
+
+func TestSQLiteRepository_PayloadEncoding(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "AAEC/w==", PayloadEncoding: models.PayloadEncodingBase64, Status: models.StatusPending}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	seedJob(t, repo, "job-2", "tenant-1", "")
+
+	got, err := repo.GetJobByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if got.PayloadEncoding != models.PayloadEncodingBase64 || got.Payload != "AAEC/w==" {
+		t.Errorf("expected base64 payload AAEC/w==, got %q (%s)", got.Payload, got.PayloadEncoding)
+	}
+	if plain, _ := repo.GetJobByID(ctx, "job-2"); plain.PayloadEncoding != models.PayloadEncodingUTF8 {
+		t.Errorf("expected payload_encoding to default to utf8, got %q", plain.PayloadEncoding)
+	}
+
+	if err := repo.MoveToDeadLetterQueue(ctx, got, "failed"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+	dlqJobs, err := repo.ListDeadLetterJobs(ctx)
+	if err != nil {
+		t.Fatalf("failed to list dead letter jobs: %v", err)
+	}
+	if len(dlqJobs) != 1 || dlqJobs[0].PayloadEncoding != models.PayloadEncodingBase64 {
+		t.Errorf("expected the dead letter job to keep its payload encoding, got %+v", dlqJobs)
+	}
+}
+
+func TestSQLiteRepository_LeaseJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		seedJob(t, repo, fmt.Sprintf("job-%d", i), "tenant-1", "")
+	}
+	seedJob(t, repo, "other-1", "tenant-2", "")
+
+	// tenant-1 may only run 2 jobs, so the batch skips its later jobs for tenant-2
+	jobs, err := repo.LeaseJobs(ctx, models.DefaultQueue, 3, time.Minute, LeaseOptions{MaxRunningPerTenant: 2})
+	if err != nil {
+		t.Fatalf("failed to lease jobs: %v", err)
+	}
+
+	var ids []string
+	for _, job := range jobs {
+		if job.Status != models.StatusRunning || job.LeaseExpiresAt == nil {
+			t.Errorf("expected job %s to be leased, got status %s", job.ID, job.Status)
+		}
+		ids = append(ids, job.ID)
+	}
+	if got := strings.Join(ids, ","); got != "job-0,job-1,other-1" {
+		t.Fatalf("expected job-0,job-1,other-1 to be leased, got %s", got)
+	}
+
+	// Only jobs over the limit are left
+	jobs, err = repo.LeaseJobs(ctx, models.DefaultQueue, 3, time.Minute, LeaseOptions{MaxRunningPerTenant: 2})
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("expected no more leasable jobs, got %d (err %v)", len(jobs), err)
+	}
+
+	running, err := repo.CountJobsByStatus(ctx, models.StatusRunning)
+	if err != nil || running != 3 {
+		t.Errorf("expected 3 RUNNING jobs, got %d (err %v)", running, err)
+	}
+}
+
+// benchmarkLease leases b.N jobs batch at a time and reports the transactions used per job
+func benchmarkLease(b *testing.B, batch int) {
+	repo, err := NewSQLiteRepository(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	ctx := context.Background()
+
+	jobs := make([]*models.Job, b.N)
+	for i := range jobs {
+		jobs[i] = &models.Job{ID: fmt.Sprintf("job-%d", i), TenantID: "tenant-1", Payload: "bench", Status: models.StatusPending}
+	}
+	if _, err := repo.CreateJobsBatch(ctx, jobs); err != nil {
+		b.Fatalf("failed to create jobs: %v", err)
+	}
+
+	b.ResetTimer()
+	leased, transactions := 0, 0
+	for leased < b.N {
+		got, err := repo.LeaseJobs(ctx, models.DefaultQueue, batch, time.Minute, LeaseOptions{})
+		if err != nil {
+			b.Fatalf("failed to lease jobs: %v", err)
+		}
+		if len(got) == 0 {
+			b.Fatalf("ran out of jobs after %d", leased)
+		}
+		leased += len(got)
+		transactions++
+	}
+	b.ReportMetric(float64(transactions)/float64(b.N), "tx/job")
+}
+
+func BenchmarkSQLiteRepository_LeaseJob(b *testing.B)    { benchmarkLease(b, 1) }
+func BenchmarkSQLiteRepository_LeaseJobs10(b *testing.B) { benchmarkLease(b, 10) }
+
+func TestSQLiteRepository_QueryTimeout(t *testing.T) {
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), SQLiteOptions{QueryTimeout: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	if _, err := repo.ListJobsByStatus(context.Background(), models.StatusPending); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected ListJobsByStatus to fail with context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := repo.LeaseJob(context.Background(), models.DefaultQueue, time.Minute, LeaseOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected LeaseJob to fail with context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_ConnectionOptions(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), SQLiteOptions{
+		BusyTimeout: 250 * time.Millisecond,
+		JournalMode: "memory",
+	})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	var journalMode string
+	var busyTimeout int
+	if err := repo.db.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("failed to read journal mode: %v", err)
+	}
+	if err := repo.db.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatalf("failed to read busy timeout: %v", err)
+	}
+	if journalMode != "memory" || busyTimeout != 250 {
+		t.Errorf("expected journal mode memory and busy timeout 250ms, got %s and %dms", journalMode, busyTimeout)
+	}
+
+	// The defaults keep WAL and a 5s busy timeout
+	defaults := newTestRepository(t)
+	if err := defaults.db.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("failed to read journal mode: %v", err)
+	}
+	if err := defaults.db.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatalf("failed to read busy timeout: %v", err)
+	}
+	if journalMode != "wal" || busyTimeout != 5000 {
+		t.Errorf("expected journal mode wal and busy timeout 5000ms, got %s and %dms", journalMode, busyTimeout)
+	}
+
+	for _, options := range []SQLiteOptions{{JournalMode: "fast"}, {BusyTimeout: -time.Second}} {
+		if _, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), options); err == nil {
+			t.Errorf("expected %+v to be rejected", options)
+		}
+	}
+}
+
+func TestSQLiteRepository_PendingJobAgePercentiles(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	ages, err := repo.PendingJobAgePercentiles(ctx, []float64{0.5, 0.95})
+	if err != nil {
+		t.Fatalf("failed to get percentiles: %v", err)
+	}
+	if ages[0] != 0 || ages[1] != 0 {
+		t.Errorf("expected zero ages with nothing pending, got %v", ages)
+	}
+
+	// Pending jobs aged 1 to 100 minutes, plus a running job that must not count
+	now := time.Now()
+	for i := 1; i <= 100; i++ {
+		job := &models.Job{ID: fmt.Sprintf("job-%d", i), Payload: "p", Status: models.StatusPending}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = ? WHERE id = ?`, now.Add(-time.Duration(i)*time.Minute).UnixMilli(), job.ID); err != nil {
+			t.Fatalf("failed to backdate job: %v", err)
+		}
+	}
+	running := &models.Job{ID: "running", Payload: "p", Status: models.StatusRunning}
+	if err := repo.CreateJob(ctx, running); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	if _, err := repo.db.ExecContext(ctx, `UPDATE jobs SET created_at = ? WHERE id = 'running'`, now.Add(-24*time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("failed to backdate job: %v", err)
+	}
+
+	ages, err = repo.PendingJobAgePercentiles(ctx, []float64{0, 0.5, 0.95, 1})
+	if err != nil {
+		t.Fatalf("failed to get percentiles: %v", err)
+	}
+	for i, want := range []time.Duration{time.Minute, 50 * time.Minute, 95 * time.Minute, 100 * time.Minute} {
+		if ages[i] < want || ages[i] > want+5*time.Second {
+			t.Errorf("percentile %d: expected about %s, got %s", i, want, ages[i])
+		}
+	}
+
+	if _, err := repo.PendingJobAgePercentiles(ctx, []float64{1.5}); err == nil {
+		t.Error("expected a percentile above 1 to be rejected")
+	}
+
+	// A capped sample only covers the oldest jobs, here those aged 51 to 100 minutes
+	ages, err = repo.pendingJobAgePercentiles(ctx, []float64{0, 1}, 50)
+	if err != nil {
+		t.Fatalf("failed to get percentiles: %v", err)
+	}
+	for i, want := range []time.Duration{51 * time.Minute, 100 * time.Minute} {
+		if ages[i] < want || ages[i] > want+5*time.Second {
+			t.Errorf("sampled percentile %d: expected about %s, got %s", i, want, ages[i])
+		}
+	}
+}
+
+func TestSQLiteRepository_WriteUnavailable(t *testing.T) {
+	ctx := context.Background()
+	var writeErrors atomic.Int32
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), SQLiteOptions{
+		OnWriteUnavailable: func(error) { writeErrors.Add(1) },
+	})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	// A single query-only connection makes every write fail as it would on a read-only file
+	repo.db.SetMaxOpenConns(1)
+	if _, err := repo.db.ExecContext(ctx, "PRAGMA query_only = 1"); err != nil {
+		t.Fatalf("failed to make the database query-only: %v", err)
+	}
+
+	err = repo.CreateJob(ctx, &models.Job{ID: "job-1", Payload: "p", Status: models.StatusPending})
+	if !IsWriteUnavailable(err) {
+		t.Fatalf("expected a write unavailable error, got %v", err)
+	}
+	if writeErrors.Load() != 1 {
+		t.Errorf("expected OnWriteUnavailable to be called once, got %d", writeErrors.Load())
+	}
+	if err := repo.Ping(ctx); !errors.Is(err, ErrWriteUnavailable) {
+		t.Errorf("expected Ping to fail with ErrWriteUnavailable, got %v", err)
+	}
+
+	// Ping writes to find out that the database recovered
+	if _, err := repo.db.ExecContext(ctx, "PRAGMA query_only = 0"); err != nil {
+		t.Fatalf("failed to make the database writable: %v", err)
+	}
+	if err := repo.Ping(ctx); err != nil {
+		t.Errorf("expected Ping to succeed once writes work again, got %v", err)
+	}
+	if IsWriteUnavailable(errors.New("some other error")) {
+		t.Error("expected an unrelated error not to be reported as write unavailable")
+	}
+}
+
+func TestSQLiteRepository_ReadPath(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// A second database stands in for a replica, so reads can be told apart from the primary
+	replica, err := NewSQLiteRepository(filepath.Join(dir, "replica.db"))
+	if err != nil {
+		t.Fatalf("failed to create replica: %v", err)
+	}
+	replicaJob := &models.Job{ID: "replica-job", Payload: "replica", Status: models.StatusPending, CreatedAt: time.Now()}
+	if err := replica.CreateJob(ctx, replicaJob); err != nil {
+		t.Fatalf("failed to create replica job: %v", err)
+	}
+	replica.Close()
+
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(dir, "primary.db"), SQLiteOptions{ReadPath: filepath.Join(dir, "replica.db")})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	primaryJob := &models.Job{ID: "primary-job", Payload: "primary", Status: models.StatusPending, CreatedAt: time.Now()}
+	if err := repo.CreateJob(ctx, primaryJob); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	// Lookups, listings and counts are served by the read pool
+	if _, err := repo.GetJobByID(ctx, "replica-job"); err != nil {
+		t.Errorf("expected the replica job to be read from the read pool: %v", err)
+	}
+	if _, err := repo.GetJobByID(ctx, "primary-job"); err != sql.ErrNoRows {
+		t.Errorf("expected the primary job not to be visible to the read pool, got %v", err)
+	}
+	jobs, err := repo.ListJobsByStatus(ctx, models.StatusPending)
+	if err != nil {
+		t.Fatalf("failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "replica-job" {
+		t.Errorf("expected the listing to come from the read pool, got %+v", jobs)
+	}
+
+	// Leases still go to the primary
+	leased, err := repo.LeaseJob(ctx, models.DefaultQueue, time.Minute, LeaseOptions{})
+	if err != nil {
+		t.Fatalf("failed to lease job: %v", err)
+	}
+	if leased.ID != "primary-job" {
+		t.Errorf("expected to lease the primary job, got %s", leased.ID)
+	}
+
+	// The read pool never writes
+	if _, err := repo.readDB.ExecContext(ctx, "DELETE FROM jobs"); err == nil {
+		t.Error("expected the read pool to reject writes")
+	}
+}
+
+func TestSQLiteRepository_PayloadCompression(t *testing.T) {
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test.db"), SQLiteOptions{CompressPayloadBytes: 100})
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	ctx := context.Background()
+
+	large := strings.Repeat("compressible payload ", 50)
+	for _, job := range []*models.Job{
+		{ID: "small", TenantID: "tenant-1", Payload: "short", Status: models.StatusPending, MaxRetries: 3},
+		{ID: "large", TenantID: "tenant-1", Payload: large, Status: models.StatusPending, MaxRetries: 3},
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	stored := func(table, column, id string) (compressed bool, size int) {
+		t.Helper()
+		query := fmt.Sprintf("SELECT compressed, length(CAST(payload AS BLOB)) FROM %s WHERE %s = ?", table, column)
+		if err := repo.db.QueryRowContext(ctx, query, id).Scan(&compressed, &size); err != nil {
+			t.Fatalf("failed to read stored payload of %s: %v", id, err)
+		}
+		return compressed, size
+	}
+
+	if compressed, _ := stored("jobs", "id", "small"); compressed {
+		t.Error("expected a payload under the threshold to be stored as text")
+	}
+	if compressed, size := stored("jobs", "id", "large"); !compressed || size >= len(large) {
+		t.Errorf("expected the large payload to be stored compressed, compressed=%t size=%d", compressed, size)
+	}
+
+	job, err := repo.GetJobByID(ctx, "large")
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if job.Payload != large {
+		t.Error("expected GetJobByID to return the decompressed payload")
+	}
+
+	// The DLQ entry keeps the compressed bytes and decompresses on read
+	if err := repo.MoveToDeadLetterQueue(ctx, job, "permanent failure"); err != nil {
+		t.Fatalf("failed to move job to DLQ: %v", err)
+	}
+	if compressed, size := stored("dead_letter_jobs", "job_id", "large"); !compressed || size >= len(large) {
+		t.Errorf("expected the DLQ entry to stay compressed, compressed=%t size=%d", compressed, size)
+	}
+	dlqJob, err := repo.GetDeadLetterJobByJobID(ctx, "large")
+	if err != nil || dlqJob == nil {
+		t.Fatalf("failed to get dead letter job: %v", err)
+	}
+	if dlqJob.Payload != large {
+		t.Error("expected the dead letter job to return the decompressed payload")
+	}
+}
+
+func TestSQLiteRepository_WithBusyRetry(t *testing.T) {
+	repo := &SQLiteRepository{options: SQLiteOptions{BusyRetries: 2}}
+	ctx := context.Background()
+	busy := fmt.Errorf("failed to update job status: %w", sqlite3.Error{Code: sqlite3.ErrBusy})
+
+	calls := 0
+	err := repo.withBusyRetry(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return busy
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on the last retry, got err=%v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = repo.withBusyRetry(ctx, func() error {
+		calls++
+		return busy
+	})
+	if !isBusy(err) || calls != 3 {
+		t.Errorf("expected the busy error after 1 try and 2 retries, got err=%v after %d calls", err, calls)
+	}
+
+	// Other errors surface immediately
+	calls = 0
+	constraint := sqlite3.Error{Code: sqlite3.ErrConstraint}
+	err = repo.withBusyRetry(ctx, func() error {
+		calls++
+		return constraint
+	})
+	if !errors.Is(err, constraint) || calls != 1 {
+		t.Errorf("expected a constraint error without retries, got err=%v after %d calls", err, calls)
+	}
+
+	count, err := withBusyRetryResult(ctx, repo, func() (int, error) { return 42, nil })
+	if err != nil || count != 42 {
+		t.Errorf("expected the result to be passed through, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"job-queue/internal/models"
+	"time"
+)
+
+// CreateSchedule creates a new recurring schedule
+func (r *SQLiteRepository) CreateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		query := `
+			INSERT INTO schedules (id, tenant_id, cron_expr, payload, max_retries, next_fire_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`
+
+		now := time.Now()
+		schedule.CreatedAt = now
+		schedule.UpdatedAt = now
+
+		_, err := r.db.ExecContext(ctx, query,
+			schedule.ID,
+			schedule.TenantID,
+			schedule.CronExpr,
+			schedule.Payload,
+			schedule.MaxRetries,
+			schedule.NextFireAt.Unix(),
+			schedule.CreatedAt.Unix(),
+			schedule.UpdatedAt.Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create schedule: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ListSchedules retrieves all schedules, or only one tenant's when tenantID is not empty
+func (r *SQLiteRepository) ListSchedules(ctx context.Context, tenantID string) ([]*models.Schedule, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	where := ""
+	var args []interface{}
+	if tenantID != "" {
+		where = "WHERE tenant_id = ?"
+		args = append(args, tenantID)
+	}
+
+	query := `
+		SELECT id, tenant_id, cron_expr, payload, max_retries, next_fire_at, last_fired_at, created_at, updated_at
+		FROM schedules
+		` + where + `
+		ORDER BY created_at ASC
+	`
+
+	return r.querySchedules(ctx, query, args...)
+}
+
+// ListDueSchedules retrieves schedules whose next fire time has passed
+func (r *SQLiteRepository) ListDueSchedules(ctx context.Context, now time.Time) ([]*models.Schedule, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, tenant_id, cron_expr, payload, max_retries, next_fire_at, last_fired_at, created_at, updated_at
+		FROM schedules
+		WHERE next_fire_at <= ?
+		ORDER BY next_fire_at ASC
+	`
+
+	return r.querySchedules(ctx, query, now.Unix())
+}
+
+// FireSchedule advances a schedule to its next fire time and enqueues the job in a single transaction.
+// It returns false without creating the job if another worker already fired this occurrence.
+func (r *SQLiteRepository) FireSchedule(ctx context.Context, schedule *models.Schedule, job *models.Job, nextFireAt time.Time) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return withBusyRetryResult(ctx, r, func() (bool, error) {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		now := time.Now()
+
+		// Only advance if the schedule still points at the occurrence we are firing
+		updateQuery := `
+			UPDATE schedules
+			SET next_fire_at = ?, last_fired_at = ?, updated_at = ?
+			WHERE id = ? AND next_fire_at = ?
+		`
+
+		result, err := tx.ExecContext(ctx, updateQuery,
+			nextFireAt.Unix(),
+			now.Unix(),
+			now.Unix(),
+			schedule.ID,
+			schedule.NextFireAt.Unix(),
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to advance schedule: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to check schedule update: %w", err)
+		}
+		if rows == 0 {
+			return false, nil
+		}
+
+		if err := insertJob(ctx, tx, job, r.options.CompressPayloadBytes); err != nil {
+			return false, fmt.Errorf("failed to create scheduled job: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		r.jobsReady.notify()
+
+		schedule.LastFiredAt = &now
+		schedule.NextFireAt = nextFireAt
+		schedule.UpdatedAt = now
+
+		return true, nil
+	})
+}
+
+// querySchedules runs a schedule query and scans the resulting rows
+func (r *SQLiteRepository) querySchedules(ctx context.Context, query string, args ...interface{}) ([]*models.Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*models.Schedule
+	for rows.Next() {
+		var schedule models.Schedule
+		var lastFiredAt sql.NullInt64
+		var nextFireAt, createdAt, updatedAt int64
+
+		err := rows.Scan(
+			&schedule.ID,
+			&schedule.TenantID,
+			&schedule.CronExpr,
+			&schedule.Payload,
+			&schedule.MaxRetries,
+			&nextFireAt,
+			&lastFiredAt,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+
+		schedule.NextFireAt = time.Unix(nextFireAt, 0)
+		schedule.CreatedAt = time.Unix(createdAt, 0)
+		schedule.UpdatedAt = time.Unix(updatedAt, 0)
+
+		if lastFiredAt.Valid {
+			t := time.Unix(lastFiredAt.Int64, 0)
+			schedule.LastFiredAt = &t
+		}
+
+		schedules = append(schedules, &schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate schedules: %w", err)
+	}
+
+	return schedules, nil
+}
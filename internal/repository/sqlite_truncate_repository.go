@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// truncatedTables are emptied by TruncateAll. Schedules, settings and metrics snapshots
+// are not job data and are kept, and job_events is append-only by design, so its audit
+// trail outlives truncation too.
+var truncatedTables = []string{"jobs", "dead_letter_jobs", "job_attempts", "tenant_leases"}
+
+// TruncateAll deletes all rows from the job tables in one transaction. It is meant for
+// tests only; see TruncateRepository.
+func (r *SQLiteRepository) TruncateAll(ctx context.Context) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.withBusyRetry(ctx, func() error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		for _, table := range truncatedTables {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return fmt.Errorf("failed to truncate %s: %w", table, err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	})
+}
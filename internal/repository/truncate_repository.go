@@ -0,0 +1,12 @@
+package repository
+
+import "context"
+
+// TruncateRepository empties a repository between integration test cases without
+// recreating its database. It is deliberately not part of JobRepository: production code
+// holds a JobRepository, so it cannot reach TruncateAll without asserting to this interface.
+type TruncateRepository interface {
+	// TruncateAll deletes every job and dead letter job with their attempt history; the
+	// append-only audit trail is kept
+	TruncateAll(ctx context.Context) error
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"job-queue/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ActiveJobs tracks the jobs a worker is processing and when each one started, so a
+// worker stuck on a job can be spotted from outside the process
+type ActiveJobs struct {
+	mu   sync.Mutex
+	jobs map[string]models.ActiveJob
+}
+
+// NewActiveJobs creates an empty active job tracker
+func NewActiveJobs() *ActiveJobs {
+	return &ActiveJobs{
+		jobs: make(map[string]models.ActiveJob),
+	}
+}
+
+// Start records that processing of a job has begun. The returned function removes it and
+// must be called once the job is finished with.
+func (a *ActiveJobs) Start(job *models.Job) func() {
+	a.mu.Lock()
+	a.jobs[job.ID] = models.ActiveJob{
+		JobID:     job.ID,
+		TenantID:  job.TenantID,
+		Queue:     job.Queue,
+		Attempt:   job.Attempt(),
+		StartedAt: time.Now(),
+	}
+	a.mu.Unlock()
+
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		delete(a.jobs, job.ID)
+	}
+}
+
+// List returns the active jobs with how long each has been running at now, longest running first
+func (a *ActiveJobs) List(now time.Time) []models.ActiveJob {
+	a.mu.Lock()
+	jobs := make([]models.ActiveJob, 0, len(a.jobs))
+	for _, job := range a.jobs {
+		job.RunningSeconds = now.Sub(job.StartedAt).Seconds()
+		jobs = append(jobs, job)
+	}
+	a.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].StartedAt.Equal(jobs[j].StartedAt) {
+			return jobs[i].StartedAt.Before(jobs[j].StartedAt)
+		}
+		return jobs[i].JobID < jobs[j].JobID
+	})
+	return jobs
+}
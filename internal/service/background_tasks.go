@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// BackgroundTask is a housekeeping loop, such as RunReclaimer or JanitorService.Run, that
+// does its work every interval until the context is cancelled
+type BackgroundTask func(ctx context.Context, interval time.Duration) error
+
+// BackgroundTasks runs a worker's housekeeping loops in their own goroutines, each on its own
+// interval, so reclaiming, retention and schedule firing never delay the lease loop or each other
+type BackgroundTasks struct {
+	tasks []backgroundTask
+	wg    sync.WaitGroup
+}
+
+// backgroundTask is a task registered with Add
+type backgroundTask struct {
+	name     string
+	interval time.Duration
+	run      BackgroundTask
+}
+
+// NewBackgroundTasks creates an empty background task runner
+func NewBackgroundTasks() *BackgroundTasks {
+	return &BackgroundTasks{}
+}
+
+// Add registers a task to run every interval once Start is called. A task with an interval
+// of zero or less is disabled.
+func (b *BackgroundTasks) Add(name string, interval time.Duration, run BackgroundTask) {
+	b.tasks = append(b.tasks, backgroundTask{name: name, interval: interval, run: run})
+}
+
+// Start runs every enabled task in its own goroutine until ctx is cancelled
+func (b *BackgroundTasks) Start(ctx context.Context) {
+	for _, task := range b.tasks {
+		if task.interval <= 0 {
+			log.Printf("%s disabled", task.name)
+			continue
+		}
+
+		b.wg.Add(1)
+		go func(task backgroundTask) {
+			defer b.wg.Done()
+			log.Printf("%s started, running every %s", task.name, task.interval)
+			if err := task.run(ctx, task.interval); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("%s error: %v", task.name, err)
+			}
+		}(task)
+	}
+}
+
+// Wait blocks until every started task has returned, so shutdown can close the database
+// only after housekeeping has stopped using it
+func (b *BackgroundTasks) Wait() {
+	b.wg.Wait()
+}
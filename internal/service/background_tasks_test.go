@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackgroundTasks(t *testing.T) {
+	var fast, slow, disabled atomic.Int32
+	tick := func(counter *atomic.Int32) BackgroundTask {
+		return func(ctx context.Context, interval time.Duration) error {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-ticker.C:
+					counter.Add(1)
+				}
+			}
+		}
+	}
+
+	tasks := NewBackgroundTasks()
+	tasks.Add("fast", 5*time.Millisecond, tick(&fast))
+	tasks.Add("slow", time.Hour, tick(&slow))
+	tasks.Add("disabled", 0, tick(&disabled))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tasks.Start(ctx)
+
+	// The slow task must not hold up the fast one
+	deadline := time.Now().Add(time.Second)
+	for fast.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if fast.Load() < 3 {
+		t.Errorf("expected the fast task to run on its own interval, it ran %d times", fast.Load())
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Wait to return once the context was cancelled")
+	}
+
+	if slow.Load() != 0 || disabled.Load() != 0 {
+		t.Errorf("expected the slow and disabled tasks not to run, got %d and %d", slow.Load(), disabled.Load())
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// CancelRegistry tracks the handler contexts of jobs running in this process so that
+// cancelling a job can stop its handler. It only reaches workers in the same process;
+// a worker in another process notices the cancellation only when it tries to finish the job.
+// A nil *CancelRegistry is valid and tracks nothing.
+type CancelRegistry struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+// NewCancelRegistry creates a new cancel registry
+func NewCancelRegistry() *CancelRegistry {
+	return &CancelRegistry{
+		cancels: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Register records the cancel function of a running job. The returned function removes it
+// and must be called once the job's handler returns.
+func (r *CancelRegistry) Register(jobID string, cancel context.CancelCauseFunc) func() {
+	if r == nil {
+		return func() {}
+	}
+
+	r.mu.Lock()
+	r.cancels[jobID] = cancel
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.cancels, jobID)
+	}
+}
+
+// Cancel stops the handler of a running job with ErrJobCancelled as the cause.
+// It reports whether the job was running in this process.
+func (r *CancelRegistry) Cancel(jobID string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.Lock()
+	cancel, ok := r.cancels[jobID]
+	r.mu.Unlock()
+
+	if ok {
+		cancel(ErrJobCancelled)
+	}
+	return ok
+}
@@ -0,0 +1,58 @@
+//go:build chaos
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"job-queue/internal/models"
+)
+
+// ChaosHandler fails jobs on purpose so retries and the dead letter queue can be exercised
+// in staging. It is only compiled into builds with the chaos tag. Each job's payload decides
+// how it fails; jobs whose payload is not a chaos directive complete immediately.
+type ChaosHandler struct{}
+
+// chaosDirective is the payload understood by ChaosHandler
+type chaosDirective struct {
+	// FailAttempts fails the first FailAttempts attempts of the job, then lets it succeed
+	FailAttempts int `json:"fail_attempts"`
+	// FailRate fails each attempt with this probability. The outcome is derived from the
+	// job ID and attempt number, so replaying the same jobs fails the same attempts.
+	FailRate float64 `json:"fail_rate"`
+	// Permanent makes the injected failures permanent, skipping the remaining retries
+	Permanent bool `json:"permanent"`
+}
+
+// Handle fails the attempt when the job's chaos directive says so
+func (ChaosHandler) Handle(ctx context.Context, job *models.Job) (string, error) {
+	var directive chaosDirective
+	if !job.PayloadJSON || json.Unmarshal([]byte(job.Payload), &directive) != nil {
+		return "", nil
+	}
+
+	attempt := job.RetryCount + 1
+	var err error
+	switch {
+	case attempt <= directive.FailAttempts:
+		err = fmt.Errorf("chaos: failing attempt %d of %d", attempt, directive.FailAttempts)
+	case directive.FailRate > 0 && chaosRoll(job.ID, attempt) < directive.FailRate:
+		err = fmt.Errorf("chaos: failing attempt %d at rate %g", attempt, directive.FailRate)
+	default:
+		return "", nil
+	}
+
+	if directive.Permanent {
+		return "", Permanent(err)
+	}
+	return "", err
+}
+
+// chaosRoll returns a number in [0, 1) that is fixed for a job's attempt
+func chaosRoll(jobID string, attempt int) float64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%d", jobID, attempt)
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
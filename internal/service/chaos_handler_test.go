@@ -0,0 +1,61 @@
+//go:build chaos
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"job-queue/internal/models"
+	"testing"
+)
+
+func TestChaosHandler_FailAttempts(t *testing.T) {
+	job := &models.Job{ID: "job-1", Payload: `{"fail_attempts":2}`, PayloadJSON: true}
+
+	for retryCount, shouldFail := range []bool{true, true, false} {
+		job.RetryCount = retryCount
+		_, err := ChaosHandler{}.Handle(context.Background(), job)
+		if (err != nil) != shouldFail {
+			t.Errorf("attempt %d: expected failure %t, got %v", retryCount+1, shouldFail, err)
+		}
+		if IsPermanent(err) {
+			t.Errorf("attempt %d: expected a retryable failure", retryCount+1)
+		}
+	}
+}
+
+func TestChaosHandler_FailRate(t *testing.T) {
+	failures := 0
+	for i := 0; i < 1000; i++ {
+		job := &models.Job{ID: fmt.Sprintf("job-%d", i), Payload: `{"fail_rate":0.3}`, PayloadJSON: true}
+		_, first := ChaosHandler{}.Handle(context.Background(), job)
+		_, again := ChaosHandler{}.Handle(context.Background(), job)
+		if (first == nil) != (again == nil) {
+			t.Fatalf("expected the same outcome when replaying %s", job.ID)
+		}
+		if first != nil {
+			failures++
+		}
+	}
+
+	if failures < 200 || failures > 400 {
+		t.Errorf("expected about 300 of 1000 attempts to fail, got %d", failures)
+	}
+}
+
+func TestChaosHandler_PermanentAndPlainPayloads(t *testing.T) {
+	job := &models.Job{ID: "job-1", Payload: `{"fail_attempts":1,"permanent":true}`, PayloadJSON: true}
+	if _, err := (ChaosHandler{}).Handle(context.Background(), job); !IsPermanent(err) {
+		t.Errorf("expected a permanent failure, got %v", err)
+	}
+
+	for _, job := range []*models.Job{
+		{ID: "job-2", Payload: "fail"},
+		{ID: "job-3", Payload: `{"fail_attempts":1}`},
+		{ID: "job-4", Payload: `{"command":"true"}`, PayloadJSON: true},
+	} {
+		if _, err := (ChaosHandler{}).Handle(context.Background(), job); err != nil {
+			t.Errorf("expected payload %s to complete, got %v", job.Payload, err)
+		}
+	}
+}
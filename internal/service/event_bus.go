@@ -0,0 +1,69 @@
+package service
+
+import (
+	"job-queue/internal/models"
+	"sync"
+)
+
+// eventBufferSize is how many undelivered events a subscriber can fall behind by before events are dropped
+const eventBufferSize = 16
+
+// EventBus is an in-process pub/sub for job status transitions.
+// A nil *EventBus is valid and discards published events.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan models.JobEvent]struct{}
+}
+
+// NewEventBus creates a new event bus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[string]map[chan models.JobEvent]struct{}),
+	}
+}
+
+// Subscribe registers for status events of a job. The returned function unsubscribes and closes the channel.
+func (b *EventBus) Subscribe(jobID string) (<-chan models.JobEvent, func()) {
+	ch := make(chan models.JobEvent, eventBufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[jobID] == nil {
+		b.subscribers[jobID] = make(map[chan models.JobEvent]struct{})
+	}
+	b.subscribers[jobID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subscribers[jobID], ch)
+			if len(b.subscribers[jobID]) == 0 {
+				delete(b.subscribers, jobID)
+			}
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to all subscribers of the job without blocking the publisher
+func (b *EventBus) Publish(event models.JobEvent) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[event.JobID] {
+		select {
+		case ch <- event:
+		default:
+			// Slow subscriber; drop rather than stall the worker
+		}
+	}
+}
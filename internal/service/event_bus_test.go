@@ -0,0 +1,54 @@
+package service
+
+import (
+	"job-queue/internal/models"
+	"testing"
+	"time"
+)
+
+func TestEventBus_PublishSubscribe(t *testing.T) {
+	bus := NewEventBus()
+
+	events, unsubscribe := bus.Subscribe("job-1")
+	defer unsubscribe()
+
+	other, unsubscribeOther := bus.Subscribe("job-2")
+	defer unsubscribeOther()
+
+	bus.Publish(models.JobEvent{JobID: "job-1", Status: models.StatusRunning, At: time.Now()})
+
+	select {
+	case event := <-events:
+		if event.Status != models.StatusRunning {
+			t.Errorf("expected status RUNNING, got %s", event.Status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected event to be delivered")
+	}
+
+	select {
+	case event := <-other:
+		t.Errorf("expected no event for job-2, got %+v", event)
+	default:
+	}
+}
+
+func TestEventBus_Unsubscribe(t *testing.T) {
+	bus := NewEventBus()
+
+	events, unsubscribe := bus.Subscribe("job-1")
+	unsubscribe()
+	unsubscribe() // safe to call twice
+
+	if _, ok := <-events; ok {
+		t.Error("expected channel to be closed after unsubscribe")
+	}
+
+	// Publishing with no subscribers must not block or panic
+	bus.Publish(models.JobEvent{JobID: "job-1", Status: models.StatusDone})
+}
+
+func TestEventBus_NilIsNoop(t *testing.T) {
+	var bus *EventBus
+	bus.Publish(models.JobEvent{JobID: "job-1", Status: models.StatusDone})
+}
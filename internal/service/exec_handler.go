@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"job-queue/internal/models"
+	"os/exec"
+	"strings"
+)
+
+// DefaultMaxExecOutputBytes caps how much of a command's stdout and stderr is kept
+const DefaultMaxExecOutputBytes = 64 * 1024
+
+// ExecCommand is the payload format of jobs run by ExecHandler
+type ExecCommand struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// ExecResult is the result stored on jobs completed by ExecHandler
+type ExecResult struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// ExecHandler runs the command named in a job's payload. The command is executed directly,
+// not through a shell, and only if it is on the allow list.
+type ExecHandler struct {
+	allowed        map[string]bool
+	maxOutputBytes int
+}
+
+// NewExecHandler creates an exec handler that may only run the given commands
+func NewExecHandler(allowedCommands []string) *ExecHandler {
+	allowed := make(map[string]bool, len(allowedCommands))
+	for _, command := range allowedCommands {
+		allowed[command] = true
+	}
+
+	return &ExecHandler{
+		allowed:        allowed,
+		maxOutputBytes: DefaultMaxExecOutputBytes,
+	}
+}
+
+// Handle runs the job's command until it exits or ctx is done. A non-zero exit fails the job
+// with the exit code and the end of stderr as the reason.
+func (h *ExecHandler) Handle(ctx context.Context, job *models.Job) (string, error) {
+	var cmd ExecCommand
+	if err := json.Unmarshal([]byte(job.Payload), &cmd); err != nil {
+		return "", Permanent(fmt.Errorf("invalid exec payload: %w", err))
+	}
+	if cmd.Command == "" {
+		return "", Permanent(errors.New("invalid exec payload: command is required"))
+	}
+	if !h.allowed[cmd.Command] {
+		return "", Permanent(fmt.Errorf("command %q is not allowed", cmd.Command))
+	}
+
+	stdout := &cappedBuffer{max: h.maxOutputBytes}
+	stderr := &cappedBuffer{max: h.maxOutputBytes}
+
+	c := exec.CommandContext(ctx, cmd.Command, cmd.Args...)
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	runErr := c.Run()
+
+	result := ExecResult{
+		ExitCode: c.ProcessState.ExitCode(),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	}
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return "", fmt.Errorf("failed to run %s: %w", cmd.Command, runErr)
+		}
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("command %s was stopped: %w", cmd.Command, ctx.Err())
+		}
+		return "", fmt.Errorf("command %s exited with code %d: %s", cmd.Command, result.ExitCode, lastLine(result.Stderr))
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// lastLine returns the last non-empty line of s, which is usually the most useful part of stderr
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// cappedBuffer keeps the first max bytes written to it and silently drops the rest,
+// so a chatty command cannot exhaust memory or bloat the jobs table
+type cappedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"job-queue/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func execJob(command string, args ...string) *models.Job {
+	payload, _ := json.Marshal(ExecCommand{Command: command, Args: args})
+	return &models.Job{ID: "job-1", Payload: string(payload)}
+}
+
+func TestExecHandler_CapturesOutput(t *testing.T) {
+	h := NewExecHandler([]string{"sh"})
+
+	out, err := h.Handle(context.Background(), execJob("sh", "-c", "echo hello; echo warning >&2"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result ExecResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out)
+	}
+	if result.ExitCode != 0 || result.Stdout != "hello\n" || result.Stderr != "warning\n" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestExecHandler_NonZeroExit(t *testing.T) {
+	h := NewExecHandler([]string{"sh"})
+
+	_, err := h.Handle(context.Background(), execJob("sh", "-c", "echo starting; echo disk full >&2; exit 3"))
+	if err == nil {
+		t.Fatal("expected an error for a non-zero exit")
+	}
+	if !strings.Contains(err.Error(), "exited with code 3") || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("expected the exit code and stderr in the error, got %v", err)
+	}
+}
+
+func TestExecHandler_RejectsCommands(t *testing.T) {
+	h := NewExecHandler([]string{"sh"})
+
+	tests := []struct {
+		name string
+		job  *models.Job
+		want string
+	}{
+		{"not allowed", execJob("rm", "-rf", "/tmp/x"), "not allowed"},
+		{"missing command", execJob(""), "command is required"},
+		{"not json", &models.Job{Payload: "echo hi"}, "invalid exec payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Handle(context.Background(), tt.job)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+			if !IsPermanent(err) {
+				t.Errorf("expected a rejected command to fail permanently, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExecHandler_StopsOnContextDeadline(t *testing.T) {
+	h := NewExecHandler([]string{"sleep"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := h.Handle(ctx, execJob("sleep", "5"))
+	if err == nil || !strings.Contains(err.Error(), "stopped") {
+		t.Errorf("expected the command to be stopped, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the command to be killed promptly, took %s", elapsed)
+	}
+}
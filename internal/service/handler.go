@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"job-queue/internal/models"
+	"time"
+)
+
+// Handler processes a leased job. The returned result is stored on the job when it completes.
+// For a job with payload_encoding base64, Payload holds the decoded bytes and the result is
+// base64 encoded before it is stored.
+// Returning an error fails the attempt, which is then retried or moved to the dead letter queue.
+// Wrap an error with Permanent when retrying cannot help, to skip the remaining retries.
+// Handlers should return promptly once ctx is done: it is cancelled when the attempt times out,
+// the job is cancelled, or the worker shuts down.
+type Handler interface {
+	Handle(ctx context.Context, job *models.Job) (string, error)
+}
+
+// HandlerFunc adapts an ordinary function to the Handler interface
+type HandlerFunc func(ctx context.Context, job *models.Job) (string, error)
+
+// Handle calls f(ctx, job)
+func (f HandlerFunc) Handle(ctx context.Context, job *models.Job) (string, error) {
+	return f(ctx, job)
+}
+
+// PermanentError is a handler error that retrying cannot fix, such as an invalid payload.
+// The job skips its remaining retries and moves straight to the dead letter queue.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err in a PermanentError so the job fails without retrying. It returns nil for a nil err.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err, or any error it wraps, is a PermanentError
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// RescheduleError is returned by a handler that cannot run the job yet, for example because
+// an external API is rate limiting it. The job goes back to PENDING and is not leased again
+// until After has passed. Unlike a failure it does not use up a retry.
+type RescheduleError struct {
+	After time.Duration
+}
+
+func (e *RescheduleError) Error() string {
+	return fmt.Sprintf("rescheduled to run in %s", e.After)
+}
+
+// SimulatedFailurePayload is the payload a SimulatedHandler with FailOnPayload fails
+const SimulatedFailurePayload = "fail"
+
+// NoopHandler completes every job immediately without a result. Workers use it when no
+// handler is configured.
+type NoopHandler struct{}
+
+// Handle completes the job
+func (NoopHandler) Handle(ctx context.Context, job *models.Job) (string, error) {
+	return "", nil
+}
+
+// SimulatedHandler pretends to do work for testing and demos: it sleeps for Delay and,
+// with FailOnPayload, fails jobs whose payload is SimulatedFailurePayload
+type SimulatedHandler struct {
+	Delay         time.Duration
+	FailOnPayload bool
+}
+
+// Handle simulates processing the job, stopping early if ctx is done
+func (h *SimulatedHandler) Handle(ctx context.Context, job *models.Job) (string, error) {
+	if h.Delay > 0 {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(h.Delay):
+		}
+	}
+
+	if h.FailOnPayload && job.Payload == SimulatedFailurePayload {
+		return "", fmt.Errorf("payload is %q", SimulatedFailurePayload)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"job-queue/internal/models"
+	"testing"
+)
+
+func TestSimulatedHandler_FailOnPayload(t *testing.T) {
+	ctx := context.Background()
+	job := &models.Job{ID: "job-1", Payload: SimulatedFailurePayload}
+
+	if _, err := (NoopHandler{}).Handle(ctx, job); err != nil {
+		t.Errorf("expected the noop handler to complete every job, got %v", err)
+	}
+
+	if _, err := (&SimulatedHandler{}).Handle(ctx, job); err != nil {
+		t.Errorf("expected no failure without FailOnPayload, got %v", err)
+	}
+
+	if _, err := (&SimulatedHandler{FailOnPayload: true}).Handle(ctx, job); err == nil {
+		t.Error("expected the fail payload to fail with FailOnPayload")
+	}
+
+	job.Payload = "work"
+	if _, err := (&SimulatedHandler{FailOnPayload: true}).Handle(ctx, job); err != nil {
+		t.Errorf("expected other payloads to succeed, got %v", err)
+	}
+}
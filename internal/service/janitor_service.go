@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"job-queue/internal/models"
+	"job-queue/internal/repository"
+	"log"
+	"os"
+	"time"
+)
+
+// ExpiredReason is the failure reason recorded for a job that expired before it ran
+const ExpiredReason = "expired before execution"
+
+// JanitorConfig holds the retention settings of the janitor. A TTL of zero keeps
+// those jobs forever.
+type JanitorConfig struct {
+	// DoneTTL is how long DONE jobs are kept
+	DoneTTL time.Duration
+	// DeadLetterTTL is how long jobs are kept in the dead letter queue
+	DeadLetterTTL time.Duration
+	// DeadLetterArchive is a file that purged dead letter jobs are appended to as
+	// JSON lines before they are deleted; empty deletes them without a copy
+	DeadLetterArchive string
+	// ExpiredToDeadLetter moves PENDING jobs past their expires_at to the dead letter
+	// queue instead of only marking them FAILED; the jobs that depend on them go to the
+	// dead letter queue either way
+	ExpiredToDeadLetter bool
+}
+
+// JanitorService purges completed and dead letter jobs once they are older than their retention TTL,
+// and fails PENDING jobs that expired before they ran
+type JanitorService struct {
+	repo   repository.JobRepository
+	config JanitorConfig
+}
+
+// NewJanitorService creates a janitor that only purges DONE jobs
+func NewJanitorService(repo repository.JobRepository, ttl time.Duration) *JanitorService {
+	return NewJanitorServiceWithConfig(repo, JanitorConfig{DoneTTL: ttl})
+}
+
+// NewJanitorServiceWithConfig creates a janitor with the given retention settings
+func NewJanitorServiceWithConfig(repo repository.JobRepository, config JanitorConfig) *JanitorService {
+	return &JanitorService{
+		repo:   repo,
+		config: config,
+	}
+}
+
+// Run sweeps on every tick until the context is cancelled
+func (s *JanitorService) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := s.SweepExpired(ctx); err != nil {
+				log.Printf("error failing expired jobs: %v", err)
+			}
+			if s.config.DoneTTL > 0 {
+				if _, err := s.Sweep(ctx); err != nil {
+					log.Printf("error sweeping completed jobs: %v", err)
+				}
+			}
+			if s.config.DeadLetterTTL > 0 {
+				if _, err := s.SweepDeadLetters(ctx); err != nil {
+					log.Printf("error sweeping dead letter jobs: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Sweep deletes DONE jobs that finished before the TTL and returns how many were removed
+func (s *JanitorService) Sweep(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-s.config.DoneTTL)
+
+	deleted, err := s.repo.DeleteJobsOlderThan(ctx, models.StatusDone, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete completed jobs: %w", err)
+	}
+
+	log.Printf("janitor: removed %d completed jobs older than %s", deleted, cutoff.Format(time.RFC3339))
+
+	return deleted, nil
+}
+
+// SweepExpired fails PENDING jobs whose expires_at has passed, recording ExpiredReason as
+// their failed attempt, and returns how many it failed. The jobs that depend on them go to
+// the dead letter queue. Jobs leased or cancelled in the meantime are left alone.
+func (s *JanitorService) SweepExpired(ctx context.Context) (int, error) {
+	jobs, err := s.repo.ListExpiredJobs(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired jobs: %w", err)
+	}
+
+	failed := 0
+	for _, job := range jobs {
+		attempt := &models.JobAttempt{Attempt: job.RetryCount + 1, Reason: ExpiredReason, At: time.Now()}
+		ok, err := s.repo.FailExpiredJob(ctx, job, attempt, s.config.ExpiredToDeadLetter)
+		if err != nil {
+			return failed, fmt.Errorf("failed to fail expired job %s: %w", job.ID, err)
+		}
+		if ok {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		log.Printf("janitor: failed %d jobs that expired before execution", failed)
+	}
+
+	return failed, nil
+}
+
+// SweepDeadLetters deletes dead letter jobs that failed before the dead letter TTL and returns
+// how many were removed. With an archive configured, nothing is deleted unless it was archived first.
+func (s *JanitorService) SweepDeadLetters(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-s.config.DeadLetterTTL)
+
+	if s.config.DeadLetterArchive != "" {
+		expired, err := s.repo.ListDeadLetterJobsOlderThan(ctx, cutoff)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list expired dead letter jobs: %w", err)
+		}
+		if len(expired) == 0 {
+			return 0, nil
+		}
+		if err := appendDeadLetterArchive(s.config.DeadLetterArchive, expired); err != nil {
+			return 0, err
+		}
+		// Only delete what was archived; jobs moved to the DLQ since are newer than the cutoff
+		cutoff = expired[len(expired)-1].FailedAt.Add(time.Millisecond)
+	}
+
+	deleted, err := s.repo.DeleteDeadLetterJobsOlderThan(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dead letter jobs: %w", err)
+	}
+
+	log.Printf("janitor: removed %d dead letter jobs older than %s", deleted, cutoff.Format(time.RFC3339))
+
+	return deleted, nil
+}
+
+// appendDeadLetterArchive appends the jobs to the archive file as JSON lines and syncs it to disk
+func appendDeadLetterArchive(path string, jobs []*models.DeadLetterJob) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter archive: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, job := range jobs {
+		if err := enc.Encode(job); err != nil {
+			return fmt.Errorf("failed to archive dead letter job %s: %w", job.ID, err)
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync dead letter archive: %w", err)
+	}
+	return f.Close()
+}
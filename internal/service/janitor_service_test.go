@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"job-queue/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJanitorService_Sweep(t *testing.T) {
+	repo := newMockRepository()
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	repo.jobs["old-done"] = &models.Job{ID: "old-done", Status: models.StatusDone, UpdatedAt: old}
+	repo.jobs["recent-done"] = &models.Job{ID: "recent-done", Status: models.StatusDone, UpdatedAt: recent}
+	repo.jobs["old-pending"] = &models.Job{ID: "old-pending", Status: models.StatusPending, UpdatedAt: old}
+
+	janitor := NewJanitorService(repo, 24*time.Hour)
+
+	deleted, err := janitor.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if deleted != 1 {
+		t.Errorf("expected 1 job removed, got %d", deleted)
+	}
+
+	if _, ok := repo.jobs["old-done"]; ok {
+		t.Error("expected old DONE job to be removed")
+	}
+
+	for _, id := range []string{"recent-done", "old-pending"} {
+		if _, ok := repo.jobs[id]; !ok {
+			t.Errorf("expected %s to be kept", id)
+		}
+	}
+}
+
+func TestJanitorService_SweepDeadLetters(t *testing.T) {
+	repo := newMockRepository()
+	now := time.Now()
+	repo.dlqJobs = []*models.DeadLetterJob{
+		{ID: "dlq-old", JobID: "job-old", FailedAt: now.Add(-72 * time.Hour)},
+		{ID: "dlq-older", JobID: "job-older", FailedAt: now.Add(-96 * time.Hour)},
+		{ID: "dlq-recent", JobID: "job-recent", FailedAt: now.Add(-time.Hour)},
+	}
+
+	archive := filepath.Join(t.TempDir(), "dlq.jsonl")
+	janitor := NewJanitorServiceWithConfig(repo, JanitorConfig{DeadLetterTTL: 48 * time.Hour, DeadLetterArchive: archive})
+
+	deleted, err := janitor.SweepDeadLetters(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 dead letter jobs removed, got %d", deleted)
+	}
+	if len(repo.dlqJobs) != 1 || repo.dlqJobs[0].ID != "dlq-recent" {
+		t.Errorf("expected only dlq-recent to be kept, got %d jobs", len(repo.dlqJobs))
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatalf("expected an archive file: %v", err)
+	}
+	defer f.Close()
+
+	var archived []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var job models.DeadLetterJob
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			t.Fatalf("invalid archive line %q: %v", scanner.Text(), err)
+		}
+		archived = append(archived, job.ID)
+	}
+	if len(archived) != 2 || archived[0] != "dlq-older" || archived[1] != "dlq-old" {
+		t.Errorf("expected dlq-older and dlq-old archived oldest first, got %v", archived)
+	}
+}
+
+func TestJanitorService_SweepExpired(t *testing.T) {
+	for _, toDLQ := range []bool{false, true} {
+		repo := newMockRepository()
+		past := time.Now().Add(-time.Minute)
+		future := time.Now().Add(time.Hour)
+		repo.jobs["expired"] = &models.Job{ID: "expired", Status: models.StatusPending, ExpiresAt: &past}
+		repo.jobs["running"] = &models.Job{ID: "running", Status: models.StatusRunning, ExpiresAt: &past}
+		repo.jobs["pending"] = &models.Job{ID: "pending", Status: models.StatusPending, ExpiresAt: &future}
+
+		janitor := NewJanitorServiceWithConfig(repo, JanitorConfig{ExpiredToDeadLetter: toDLQ})
+
+		failed, err := janitor.SweepExpired(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if failed != 1 {
+			t.Errorf("to_dlq=%t: expected 1 expired job, got %d", toDLQ, failed)
+		}
+
+		if toDLQ {
+			if _, ok := repo.jobs["expired"]; ok || len(repo.dlqJobs) != 1 || repo.dlqJobs[0].FailureReason != ExpiredReason {
+				t.Errorf("expected the expired job in the DLQ with reason %q, got %+v", ExpiredReason, repo.dlqJobs)
+			}
+		} else {
+			if got := repo.jobs["expired"].Status; got != models.StatusFailed || len(repo.dlqJobs) != 0 {
+				t.Errorf("expected the expired job FAILED outside the DLQ, got %s with %d DLQ jobs", got, len(repo.dlqJobs))
+			}
+		}
+		if repo.jobs["running"].Status != models.StatusRunning || repo.jobs["pending"].Status != models.StatusPending {
+			t.Errorf("to_dlq=%t: expected running and unexpired jobs to be left alone", toDLQ)
+		}
+	}
+}
@@ -2,104 +2,862 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"job-queue/internal/metrics"
 	"job-queue/internal/models"
 	"job-queue/internal/repository"
 	"log"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrJobNotFound       = errors.New("job not found")
-	ErrRateLimitExceeded = errors.New("rate limit exceeded")
-	ErrDuplicateJob      = errors.New("job with same idempotency key already exists")
+	ErrJobNotFound         = errors.New("job not found")
+	ErrRateLimitExceeded   = errors.New("rate limit exceeded")
+	ErrDuplicateJob        = errors.New("job with same idempotency key already exists")
+	ErrPayloadRequired     = errors.New("payload is required")
+	ErrBatchTooLarge       = fmt.Errorf("batch exceeds maximum size of %d jobs", MaxBatchSize)
+	ErrInvalidTags         = errors.New("invalid tags")
+	ErrInvalidMetadata     = errors.New("invalid metadata")
+	ErrSearchQueryTooShort = fmt.Errorf("search query must be at least %d characters", MinSearchQueryLength)
+	ErrJobNotCancellable   = errors.New("only PENDING or RUNNING jobs can be cancelled")
+	ErrJobCancelled        = errors.New("job was cancelled")
+	ErrJobNotPending       = errors.New("only PENDING jobs can be updated")
+	ErrInvalidMaxRetries   = errors.New("max_retries must not be negative")
+	ErrInvalidDependencies = errors.New("invalid depends_on")
+	ErrDependencyCycle     = errors.New("depends_on would create a dependency cycle")
+	ErrInvalidJobID        = fmt.Errorf("id must be 1 to %d letters, digits or the characters - _ . :", MaxJobIDLength)
+	ErrInvalidTargetStatus = errors.New("status must be CANCELLED or PENDING")
+	ErrMalformedJSON       = errors.New("payload declared as JSON is not valid JSON")
+	ErrInvalidExpiresAt    = errors.New("expires_at must be in the future")
+	ErrInvalidEncoding     = errors.New(`payload_encoding must be "utf8" or "base64"`)
+	ErrInvalidBase64       = errors.New("payload with payload_encoding base64 must be a string of valid base64")
+	ErrQueueFull           = errors.New("queue is full")
+	ErrInvalidIDPrefix     = fmt.Errorf("id prefix must be 1 to %d letters, digits or the characters - _ . :", MaxIDPrefixLength)
+	ErrInvalidDedupWindow  = fmt.Errorf("dedup_window must be a positive duration such as 5m, at most %s", MaxDedupWindow)
 )
 
+// MaxBatchSize is the maximum number of jobs accepted in a single batch submission
+const MaxBatchSize = 100
+
+// QueueFullRetryAfter is how long a client rejected with ErrQueueFull is asked to wait
+const QueueFullRetryAfter = 5 * time.Second
+
+// MaxTagsPerJob is the most tags a single job may carry
+const MaxTagsPerJob = 10
+
+// MaxTagLength is the longest tag accepted, in bytes
+const MaxTagLength = 64
+
+// MaxMetadataKeys is the most metadata pairs a single job may carry
+const MaxMetadataKeys = 16
+
+// MaxMetadataKeyLength is the longest metadata key accepted, in bytes
+const MaxMetadataKeyLength = 64
+
+// MaxMetadataValueLength is the longest metadata value accepted, in bytes
+const MaxMetadataValueLength = 256
+
+// MaxJobIDLength is the longest job ID a client may supply
+const MaxJobIDLength = 128
+
+// MaxIDPrefixLength is the longest tenant ID prefix, which leaves room in MaxJobIDLength for
+// the separator and a UUID
+const MaxIDPrefixLength = MaxJobIDLength - 1 - 36
+
+// MaxDedupWindow is the longest dedup_window accepted
+const MaxDedupWindow = 24 * time.Hour
+
+// MaxDependencies is the most jobs a single job may depend on
+const MaxDependencies = 20
+
+// MinSearchQueryLength is the shortest payload search accepted, so searches stay selective
+const MinSearchQueryLength = 3
+
+// MaxSearchResults caps how many jobs a payload search returns
+const MaxSearchResults = 100
+
+// DefaultMaxPayloadBytes is the largest payload accepted when no limit is configured
+const DefaultMaxPayloadBytes = 64 * 1024
+
+// ErrPayloadTooLarge is returned when a job payload exceeds the configured maximum size
+type ErrPayloadTooLarge struct {
+	Size     int
+	MaxBytes int
+}
+
+func (e *ErrPayloadTooLarge) Error() string {
+	return fmt.Sprintf("payload of %d bytes exceeds maximum size of %d bytes", e.Size, e.MaxBytes)
+}
+
+// defaultEventPollInterval is how often WatchJob re-reads a job to catch transitions made by other processes
+const defaultEventPollInterval = 1 * time.Second
+
+// JobServiceConfig holds the tunable settings of the job service
+type JobServiceConfig struct {
+	MaxPayloadBytes int
+	// IdempotencyTTL is how long an idempotency key maps to its job; a later submission with the
+	// same key creates a new job. Zero keeps keys forever.
+	IdempotencyTTL time.Duration
+	// ValidateJSONPayloads rejects string payloads whose content_type is JSON but that do
+	// not parse as JSON. Payloads of other content types are not inspected.
+	ValidateJSONPayloads bool
+	// MaxPendingJobs rejects submissions with ErrQueueFull while this many jobs are PENDING
+	// across all tenants, to bound the backlog. Zero disables the limit.
+	// TODO: allow per-tenant limits in the -tenant-limits file
+	MaxPendingJobs int
+	// TenantIDPrefixes maps a tenant ID to a prefix for the IDs generated for its jobs, so
+	// tenant acme with prefix "acme" gets IDs like acme_<uuid>. Client-supplied IDs and
+	// tenants without a prefix are unaffected. Prefixes are checked with ValidateIDPrefix.
+	TenantIDPrefixes map[string]string
+}
+
+// withDefaults fills unset fields with their default values
+func (c JobServiceConfig) withDefaults() JobServiceConfig {
+	if c.MaxPayloadBytes <= 0 {
+		c.MaxPayloadBytes = DefaultMaxPayloadBytes
+	}
+	return c
+}
+
 // JobService handles job business logic
 type JobService struct {
-	repo        repository.JobRepository
-	rateLimiter *RateLimiter
-	metrics     *metrics.Metrics
+	repo              repository.JobRepository
+	rateLimiter       *RateLimiter
+	metrics           *metrics.Metrics
+	events            *EventBus
+	cancels           *CancelRegistry
+	eventPollInterval time.Duration
+	config            JobServiceConfig
+
+	// idempotencyMu serializes the duplicate check and insert of submissions with an idempotency
+	// key, which the database no longer enforces because keys may be reused after their TTL
+	idempotencyMu sync.Mutex
 }
 
-// NewJobService creates a new job service
+// NewJobService creates a new job service with the default configuration
 func NewJobService(repo repository.JobRepository, rateLimiter *RateLimiter, metrics *metrics.Metrics) *JobService {
+	return NewJobServiceWithConfig(repo, rateLimiter, metrics, JobServiceConfig{})
+}
+
+// NewJobServiceWithConfig creates a new job service with the given configuration
+func NewJobServiceWithConfig(repo repository.JobRepository, rateLimiter *RateLimiter, metrics *metrics.Metrics, config JobServiceConfig) *JobService {
 	return &JobService{
-		repo:        repo,
-		rateLimiter: rateLimiter,
-		metrics:     metrics,
+		repo:              repo,
+		rateLimiter:       rateLimiter,
+		metrics:           metrics,
+		eventPollInterval: defaultEventPollInterval,
+		config:            config.withDefaults(),
 	}
 }
 
-// CreateJob creates a new job
-func (s *JobService) CreateJob(ctx context.Context, req *models.CreateJobRequest) (*models.Job, error) {
-	// Check submission rate limit
-	if err := s.rateLimiter.CheckSubmissionRate(ctx, req.TenantID); err != nil {
-		return nil, err
+// SetEventBus sets the bus used to publish and watch job status transitions
+func (s *JobService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// SetCancelRegistry sets the registry used to stop the handlers of cancelled jobs
+// run by workers in the same process
+func (s *JobService) SetCancelRegistry(cancels *CancelRegistry) {
+	s.cancels = cancels
+}
+
+// CreateJob creates a new job. The returned bool is false when an existing job with the
+// same idempotency key, or an identical job within the request's dedup window, was
+// returned instead.
+func (s *JobService) CreateJob(ctx context.Context, req *models.CreateJobRequest) (*models.Job, bool, error) {
+	payload, payloadJSON, err := models.DecodePayload(req.Payload)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.checkPayloadSize(payload); err != nil {
+		return nil, false, err
+	}
+	if err := s.checkPayloadContentType(req.ContentType, payload, payloadJSON); err != nil {
+		return nil, false, err
+	}
+	if err := checkPayloadEncoding(req.PayloadEncoding, payload, payloadJSON); err != nil {
+		return nil, false, err
+	}
+
+	if err := validateTags(req.Tags); err != nil {
+		return nil, false, err
+	}
+	if err := validateMetadata(req.Metadata); err != nil {
+		return nil, false, err
+	}
+
+	if req.ID != "" && !validJobID(req.ID) {
+		return nil, false, ErrInvalidJobID
+	}
+
+	if !validExpiresAt(req.ExpiresAt) {
+		return nil, false, ErrInvalidExpiresAt
+	}
+
+	dedupWindow, err := parseDedupWindow(req.DedupWindow)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Check submission rate limit, unless an operator is injecting an urgent job
+	if !req.BypassLimits {
+		if err := s.rateLimiter.CheckSubmissionRate(ctx, req.TenantID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Create job. The concurrent running limit is enforced when workers lease jobs.
+	job := newJobFromRequest(req, s.config.TenantIDPrefixes[req.TenantID])
+
+	if req.IdempotencyKey != "" || dedupWindow > 0 {
+		s.idempotencyMu.Lock()
+		defer s.idempotencyMu.Unlock()
 	}
 
 	// Check idempotency
 	if req.IdempotencyKey != "" {
-		existing, err := s.repo.GetJobByTenantAndIdempotencyKey(ctx, req.TenantID, req.IdempotencyKey)
+		existing, err := s.repo.GetJobByTenantAndIdempotencyKey(ctx, req.TenantID, req.IdempotencyKey, s.idempotencySince())
 		if err != nil {
-			return nil, fmt.Errorf("failed to check idempotency: %w", err)
+			return nil, false, fmt.Errorf("failed to check idempotency: %w", err)
 		}
 		if existing != nil {
 			log.Printf("job_id=%s: duplicate job detected with idempotency_key=%s", existing.ID, req.IdempotencyKey)
-			return existing, nil
+			return existing, false, nil
 		}
 	}
 
-	// Check concurrent running limit
-	runningCount, err := s.repo.GetRunningJobsCountByTenant(ctx, req.TenantID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get running jobs count: %w", err)
-	}
-
-	if err := s.rateLimiter.CheckConcurrentLimit(ctx, req.TenantID, runningCount); err != nil {
-		return nil, err
+	// Check for an identical job within the dedup window
+	if dedupWindow > 0 {
+		existing, err := s.repo.GetJobByTenantAndDedupHash(ctx, job.TenantID, job.DedupHash, time.Now().Add(-dedupWindow))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to check dedup window: %w", err)
+		}
+		if existing != nil {
+			log.Printf("job_id=%s: identical job submitted within dedup_window=%s", existing.ID, dedupWindow)
+			return existing, false, nil
+		}
 	}
 
-	// Create job
-	maxRetries := 3
-	if req.MaxRetries != nil {
-		maxRetries = *req.MaxRetries
+	if err := s.checkQueueCapacity(ctx); err != nil {
+		return nil, false, err
 	}
 
-	job := &models.Job{
-		ID:             uuid.New().String(),
-		TenantID:       req.TenantID,
-		IdempotencyKey: req.IdempotencyKey,
-		Payload:        req.Payload,
-		Status:         models.StatusPending,
-		MaxRetries:     maxRetries,
-		RetryCount:     0,
+	if err := s.checkDependencies(ctx, job); err != nil {
+		return nil, false, err
 	}
 
 	if err := s.repo.CreateJob(ctx, job); err != nil {
 		// Handle duplicate idempotency key (race condition)
 		if dupErr, ok := err.(*repository.ErrDuplicateIdempotencyKey); ok {
 			// Fetch the existing job
-			existing, fetchErr := s.repo.GetJobByTenantAndIdempotencyKey(ctx, dupErr.TenantID, dupErr.IdempotencyKey)
+			existing, fetchErr := s.repo.GetJobByTenantAndIdempotencyKey(ctx, dupErr.TenantID, dupErr.IdempotencyKey, s.idempotencySince())
 			if fetchErr != nil {
-				return nil, fmt.Errorf("failed to fetch existing job: %w", fetchErr)
+				return nil, false, fmt.Errorf("failed to fetch existing job: %w", fetchErr)
 			}
 			if existing != nil {
 				log.Printf("job_id=%s: duplicate job detected with idempotency_key=%s (race condition)", existing.ID, dupErr.IdempotencyKey)
-				return existing, nil
+				return existing, false, nil
 			}
 		}
-		return nil, fmt.Errorf("failed to create job: %w", err)
+		return nil, false, fmt.Errorf("failed to create job: %w", err)
 	}
 
 	s.metrics.IncrementTotalJobs()
 	log.Printf("job_id=%s: job submitted, tenant_id=%s, payload=%s", job.ID, job.TenantID, job.Payload)
+	logBypassLimits(job)
 
-	return job, nil
+	return job, true, nil
+}
+
+// CreateJobsBatch creates multiple jobs in a single repository transaction.
+// Failures are reported per item so one bad request does not fail the whole batch.
+func (s *JobService) CreateJobsBatch(ctx context.Context, reqs []*models.CreateJobRequest) ([]*models.BatchJobResult, error) {
+	if len(reqs) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]*models.BatchJobResult, len(reqs))
+	dedupWindows := make([]time.Duration, len(reqs))
+	tenantItems := make(map[string][]int)
+	for i, req := range reqs {
+		results[i] = &models.BatchJobResult{Index: i}
+
+		if req.TenantID == "" {
+			results[i].Error = "tenant_id is required"
+			continue
+		}
+		payload, payloadJSON, err := models.DecodePayload(req.Payload)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if payload == "" {
+			results[i].Error = ErrPayloadRequired.Error()
+			continue
+		}
+		if err := s.checkPayloadSize(payload); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err := s.checkPayloadContentType(req.ContentType, payload, payloadJSON); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err := checkPayloadEncoding(req.PayloadEncoding, payload, payloadJSON); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err := validateTags(req.Tags); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err := validateMetadata(req.Metadata); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if req.ID != "" && !validJobID(req.ID) {
+			results[i].Error = ErrInvalidJobID.Error()
+			continue
+		}
+		if !validExpiresAt(req.ExpiresAt) {
+			results[i].Error = ErrInvalidExpiresAt.Error()
+			continue
+		}
+		if dedupWindows[i], err = parseDedupWindow(req.DedupWindow); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		// Urgent jobs are not counted against the tenant's submission rate
+		if req.BypassLimits {
+			continue
+		}
+		tenantItems[req.TenantID] = append(tenantItems[req.TenantID], i)
+	}
+
+	// Apply rate limits per tenant against the number of jobs it submitted in this batch
+	for tenantID, items := range tenantItems {
+		if limitErr := s.rateLimiter.CheckSubmissionRateN(ctx, tenantID, len(items)); limitErr != nil {
+			for _, i := range items {
+				results[i].Error = limitErr.Error()
+			}
+		}
+	}
+
+	s.idempotencyMu.Lock()
+	defer s.idempotencyMu.Unlock()
+
+	since := s.idempotencySince()
+	batchKeys := make(map[string]bool)
+	// batchHashes maps the content hash of each job queued for insert to its index in jobs,
+	// and dedupOf maps an item deduplicated against one of them to that index
+	batchHashes := make(map[string]int)
+	dedupOf := make(map[int]int)
+
+	// Items past the remaining room of a capped queue are rejected; -1 means unlimited
+	room := -1
+	if s.config.MaxPendingJobs > 0 {
+		pending, err := s.repo.CountJobsByStatus(ctx, models.StatusPending)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count pending jobs: %w", err)
+		}
+		room = max(s.config.MaxPendingJobs-pending, 0)
+	}
+
+	var jobs []*models.Job
+	var jobItems []int
+	for i, req := range reqs {
+		if results[i].Error != "" {
+			continue
+		}
+
+		// Check idempotency
+		if req.IdempotencyKey != "" {
+			if batchKeys[req.TenantID+"/"+req.IdempotencyKey] {
+				results[i].Error = "duplicate idempotency key"
+				continue
+			}
+			batchKeys[req.TenantID+"/"+req.IdempotencyKey] = true
+
+			existing, err := s.repo.GetJobByTenantAndIdempotencyKey(ctx, req.TenantID, req.IdempotencyKey, since)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check idempotency: %w", err)
+			}
+			if existing != nil {
+				log.Printf("job_id=%s: duplicate job detected with idempotency_key=%s", existing.ID, req.IdempotencyKey)
+				results[i].ID = existing.ID
+				continue
+			}
+		}
+
+		job := newJobFromRequest(req, s.config.TenantIDPrefixes[req.TenantID])
+
+		// Check for an identical job within the dedup window, earlier in this batch or stored
+		if dedupWindows[i] > 0 {
+			if j, ok := batchHashes[job.DedupHash]; ok {
+				dedupOf[i] = j
+				continue
+			}
+			existing, err := s.repo.GetJobByTenantAndDedupHash(ctx, job.TenantID, job.DedupHash, time.Now().Add(-dedupWindows[i]))
+			if err != nil {
+				return nil, fmt.Errorf("failed to check dedup window: %w", err)
+			}
+			if existing != nil {
+				log.Printf("job_id=%s: identical job submitted within dedup_window=%s", existing.ID, dedupWindows[i])
+				results[i].ID = existing.ID
+				continue
+			}
+		}
+
+		if room == 0 {
+			results[i].Error = ErrQueueFull.Error()
+			continue
+		}
+
+		if err := s.checkDependencies(ctx, job); err != nil {
+			if !errors.Is(err, ErrInvalidDependencies) && !errors.Is(err, ErrDependencyCycle) {
+				return nil, err
+			}
+			results[i].Error = err.Error()
+			continue
+		}
+		if room > 0 {
+			room--
+		}
+
+		batchHashes[job.DedupHash] = len(jobs)
+		jobs = append(jobs, job)
+		jobItems = append(jobItems, i)
+	}
+
+	if len(jobs) == 0 {
+		return results, nil
+	}
+
+	errs, err := s.repo.CreateJobsBatch(ctx, jobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jobs: %w", err)
+	}
+
+	for j, job := range jobs {
+		result := results[jobItems[j]]
+		if errs[j] != nil {
+			var dupErr *repository.ErrDuplicateIdempotencyKey
+			if errors.As(errs[j], &dupErr) {
+				result.Error = "duplicate idempotency key"
+			} else {
+				result.Error = errs[j].Error()
+			}
+			continue
+		}
+
+		result.ID = job.ID
+		s.metrics.IncrementTotalJobs()
+		log.Printf("job_id=%s: job submitted in batch, tenant_id=%s, payload=%s", job.ID, job.TenantID, job.Payload)
+		logBypassLimits(job)
+	}
+
+	// Items deduplicated against a job of this batch share its outcome
+	for i, j := range dedupOf {
+		results[i].ID = results[jobItems[j]].ID
+		results[i].Error = results[jobItems[j]].Error
+	}
+
+	return results, nil
+}
+
+// parseDedupWindow parses a request's dedup_window, returning zero when it is empty
+func parseDedupWindow(window string) (time.Duration, error) {
+	if window == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 || d > MaxDedupWindow {
+		return 0, ErrInvalidDedupWindow
+	}
+	return d, nil
+}
+
+// dedupHash identifies a job's content for dedup_window: its tenant, queue, payload
+// encoding and payload, with structured and string payloads kept apart
+func dedupHash(job *models.Job) string {
+	h := sha256.New()
+	for _, field := range []string{job.TenantID, job.Queue, job.PayloadEncoding, strconv.FormatBool(job.PayloadJSON), job.Payload} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencySince returns the creation time before which a job no longer holds its idempotency key
+func (s *JobService) idempotencySince() time.Time {
+	if s.config.IdempotencyTTL <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-s.config.IdempotencyTTL)
+}
+
+// checkQueueCapacity rejects a submission with ErrQueueFull once MaxPendingJobs jobs are PENDING
+func (s *JobService) checkQueueCapacity(ctx context.Context) error {
+	if s.config.MaxPendingJobs <= 0 {
+		return nil
+	}
+
+	pending, err := s.repo.CountJobsByStatus(ctx, models.StatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to count pending jobs: %w", err)
+	}
+	if pending >= s.config.MaxPendingJobs {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// checkPayloadSize rejects payloads larger than the configured maximum
+func (s *JobService) checkPayloadSize(payload string) error {
+	if len(payload) > s.config.MaxPayloadBytes {
+		return &ErrPayloadTooLarge{Size: len(payload), MaxBytes: s.config.MaxPayloadBytes}
+	}
+	return nil
+}
+
+// checkPayloadContentType rejects a string payload declared as JSON that does not parse, when
+// JSON payload validation is enabled. Structured payloads are valid JSON already.
+func (s *JobService) checkPayloadContentType(contentType, payload string, payloadJSON bool) error {
+	if !s.config.ValidateJSONPayloads || payloadJSON || !isJSONContentType(contentType) {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(payload), &value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return fmt.Errorf("%w: %v at offset %d", ErrMalformedJSON, err, syntaxErr.Offset)
+		}
+		return fmt.Errorf("%w: %v", ErrMalformedJSON, err)
+	}
+	return nil
+}
+
+// checkPayloadEncoding rejects an unknown payload encoding, and a base64 payload that is not
+// a string of standard base64
+func checkPayloadEncoding(encoding, payload string, payloadJSON bool) error {
+	switch encoding {
+	case "", models.PayloadEncodingUTF8:
+		return nil
+	case models.PayloadEncodingBase64:
+		if payloadJSON {
+			return ErrInvalidBase64
+		}
+		if _, err := base64.StdEncoding.DecodeString(payload); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+		}
+		return nil
+	default:
+		return ErrInvalidEncoding
+	}
+}
+
+// isJSONContentType reports whether a media type is application/json or a +json type
+// such as application/ld+json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// ValidateCreateJobRequest checks every field of a create request and returns all problems
+// found, so a client can fix them in one round trip. It returns nil for a valid request.
+func (s *JobService) ValidateCreateJobRequest(req *models.CreateJobRequest) []models.FieldError {
+	var errs []models.FieldError
+
+	if req.TenantID == "" {
+		errs = append(errs, models.FieldError{Field: "tenant_id", Message: "tenant_id is required"})
+	}
+
+	if payload, payloadJSON, err := models.DecodePayload(req.Payload); err != nil {
+		errs = append(errs, models.FieldError{Field: "payload", Message: err.Error()})
+	} else if payload == "" {
+		errs = append(errs, models.FieldError{Field: "payload", Message: ErrPayloadRequired.Error()})
+	} else if err := s.checkPayloadSize(payload); err != nil {
+		errs = append(errs, models.FieldError{Field: "payload", Message: err.Error()})
+	} else if err := s.checkPayloadContentType(req.ContentType, payload, payloadJSON); err != nil {
+		errs = append(errs, models.FieldError{Field: "payload", Message: err.Error()})
+	} else if err := checkPayloadEncoding(req.PayloadEncoding, payload, payloadJSON); errors.Is(err, ErrInvalidBase64) {
+		errs = append(errs, models.FieldError{Field: "payload", Message: err.Error()})
+	}
+
+	if err := checkPayloadEncoding(req.PayloadEncoding, "", false); errors.Is(err, ErrInvalidEncoding) {
+		errs = append(errs, models.FieldError{Field: "payload_encoding", Message: err.Error()})
+	}
+
+	if req.ID != "" && !validJobID(req.ID) {
+		errs = append(errs, models.FieldError{Field: "id", Message: ErrInvalidJobID.Error()})
+	}
+
+	if req.MaxRetries != nil && *req.MaxRetries < 0 {
+		errs = append(errs, models.FieldError{Field: "max_retries", Message: ErrInvalidMaxRetries.Error()})
+	}
+
+	if !validExpiresAt(req.ExpiresAt) {
+		errs = append(errs, models.FieldError{Field: "expires_at", Message: ErrInvalidExpiresAt.Error()})
+	}
+
+	if _, err := parseDedupWindow(req.DedupWindow); err != nil {
+		errs = append(errs, models.FieldError{Field: "dedup_window", Message: err.Error()})
+	}
+
+	for _, problem := range tagProblems(req.Tags) {
+		errs = append(errs, models.FieldError{Field: "tags", Message: problem})
+	}
+
+	for _, problem := range metadataProblems(req.Metadata) {
+		errs = append(errs, models.FieldError{Field: "metadata", Message: problem})
+	}
+
+	for _, problem := range dependencyProblems(req.ID, req.DependsOn) {
+		errs = append(errs, models.FieldError{Field: "depends_on", Message: problem})
+	}
+
+	return errs
+}
+
+// validExpiresAt reports whether an optional expiry leaves the job time to run
+func validExpiresAt(expiresAt *time.Time) bool {
+	return expiresAt == nil || expiresAt.After(time.Now())
+}
+
+// validJobID reports whether a client-supplied job ID is short enough and only uses characters
+// that are safe in URL paths
+func validJobID(id string) bool {
+	if id == "" || len(id) > MaxJobIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateIDPrefix checks a tenant's job ID prefix: it must be a valid job ID short enough
+// that a prefixed UUID still fits in MaxJobIDLength
+func ValidateIDPrefix(prefix string) error {
+	if len(prefix) > MaxIDPrefixLength || !validJobID(prefix) {
+		return ErrInvalidIDPrefix
+	}
+	return nil
+}
+
+// validateTags rejects too many tags, empty or overlong tags, and duplicates
+func validateTags(tags []string) error {
+	if problems := tagProblems(tags); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidTags, problems[0])
+	}
+	return nil
+}
+
+// validateMetadata rejects too many metadata pairs and empty or overlong keys and values
+func validateMetadata(metadata map[string]string) error {
+	if problems := metadataProblems(metadata); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidMetadata, problems[0])
+	}
+	return nil
+}
+
+// metadataProblems lists everything wrong with a job's metadata, in key order
+func metadataProblems(metadata map[string]string) []string {
+	var problems []string
+	if len(metadata) > MaxMetadataKeys {
+		problems = append(problems, fmt.Sprintf("at most %d metadata keys are allowed", MaxMetadataKeys))
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		switch {
+		case key == "":
+			problems = append(problems, "metadata keys must not be empty")
+		case len(key) > MaxMetadataKeyLength:
+			problems = append(problems, fmt.Sprintf("metadata key %q exceeds %d bytes", key, MaxMetadataKeyLength))
+		case len(metadata[key]) > MaxMetadataValueLength:
+			problems = append(problems, fmt.Sprintf("metadata value of %q exceeds %d bytes", key, MaxMetadataValueLength))
+		}
+	}
+	return problems
+}
+
+// tagProblems lists everything wrong with a job's tags
+func tagProblems(tags []string) []string {
+	var problems []string
+	if len(tags) > MaxTagsPerJob {
+		problems = append(problems, fmt.Sprintf("at most %d tags are allowed", MaxTagsPerJob))
+	}
+
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		switch {
+		case tag == "":
+			problems = append(problems, "tags must not be empty")
+		case len(tag) > MaxTagLength:
+			problems = append(problems, fmt.Sprintf("tag %q exceeds %d bytes", tag, MaxTagLength))
+		case seen[tag]:
+			problems = append(problems, fmt.Sprintf("duplicate tag %q", tag))
+		}
+		seen[tag] = true
+	}
+
+	return problems
+}
+
+// dependencyProblems lists everything wrong with a job's dependencies that can be told
+// without looking them up
+func dependencyProblems(id string, dependsOn []string) []string {
+	var problems []string
+	if len(dependsOn) > MaxDependencies {
+		problems = append(problems, fmt.Sprintf("at most %d dependencies are allowed", MaxDependencies))
+	}
+
+	seen := make(map[string]bool, len(dependsOn))
+	for _, dependency := range dependsOn {
+		switch {
+		case dependency == "":
+			problems = append(problems, "dependencies must not be empty")
+		case id != "" && dependency == id:
+			problems = append(problems, "a job cannot depend on itself")
+		case seen[dependency]:
+			problems = append(problems, fmt.Sprintf("duplicate dependency %q", dependency))
+		}
+		seen[dependency] = true
+	}
+
+	return problems
+}
+
+// checkDependencies rejects dependencies that do not exist, belong to another tenant or can no
+// longer complete, and dependencies that lead back to the job itself
+func (s *JobService) checkDependencies(ctx context.Context, job *models.Job) error {
+	if problems := dependencyProblems(job.ID, job.DependsOn); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidDependencies, problems[0])
+	}
+
+	// Walk the dependency graph; a job ID can be reused once its job was purged, so
+	// existing jobs may already depend on the new one
+	visited := make(map[string]bool)
+	pending := append([]string(nil), job.DependsOn...)
+	direct := len(pending)
+	for i := 0; i < len(pending); i++ {
+		id := pending[i]
+		if id == job.ID {
+			return ErrDependencyCycle
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		dependency, err := s.repo.GetJobByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			if i < direct {
+				return fmt.Errorf("%w: job %s does not exist", ErrInvalidDependencies, id)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check dependencies: %w", err)
+		}
+		// Another tenant's job is reported like a missing one, so IDs cannot be probed
+		if i < direct && dependency.TenantID != job.TenantID {
+			return fmt.Errorf("%w: job %s does not exist", ErrInvalidDependencies, id)
+		}
+		if i < direct && (dependency.Status == models.StatusFailed || dependency.Status == models.StatusCancelled) {
+			return fmt.Errorf("%w: job %s is %s", ErrInvalidDependencies, id, dependency.Status)
+		}
+
+		pending = append(pending, dependency.DependsOn...)
+	}
+
+	return nil
+}
+
+// logBypassLimits records a job created past its tenant's limits, so such jobs stand out in the logs
+func logBypassLimits(job *models.Job) {
+	if job.BypassLimits {
+		log.Printf("job_id=%s: BYPASS_LIMITS job submitted, tenant_id=%s: submission rate and concurrent running limits skipped", job.ID, job.TenantID)
+	}
+}
+
+// newJobFromRequest builds a new PENDING job from a create request. A generated ID starts
+// with idPrefix and an underscore unless idPrefix is empty.
+func newJobFromRequest(req *models.CreateJobRequest, idPrefix string) *models.Job {
+	maxRetries := 3
+	if req.MaxRetries != nil {
+		maxRetries = *req.MaxRetries
+	}
+
+	queue := req.Queue
+	if queue == "" {
+		queue = models.DefaultQueue
+	}
+
+	// The payload was checked before the job is built, so it decodes without error
+	payload, payloadJSON, _ := models.DecodePayload(req.Payload)
+
+	id := req.ID
+	if id == "" {
+		id = uuid.New().String()
+		if idPrefix != "" {
+			id = idPrefix + "_" + id
+		}
+	}
+
+	encoding := req.PayloadEncoding
+	if encoding == "" {
+		encoding = models.PayloadEncodingUTF8
+	}
+
+	job := &models.Job{
+		ID:              id,
+		TenantID:        req.TenantID,
+		Queue:           queue,
+		IdempotencyKey:  req.IdempotencyKey,
+		Payload:         payload,
+		PayloadJSON:     payloadJSON,
+		PayloadEncoding: encoding,
+		Tags:            req.Tags,
+		Metadata:        req.Metadata,
+		DependsOn:       req.DependsOn,
+		Status:          models.StatusPending,
+		MaxRetries:      maxRetries,
+		RetryCount:      0,
+		ExpiresAt:       req.ExpiresAt,
+		BypassLimits:    req.BypassLimits,
+	}
+	// Every job records its hash, so a later submission with dedup_window finds it
+	job.DedupHash = dedupHash(job)
+	return job
 }
 
 // GetJob retrieves a job by ID
@@ -114,15 +872,406 @@ func (s *JobService) GetJob(ctx context.Context, id string) (*models.Job, error)
 	return job, nil
 }
 
-// ListJobsByStatus retrieves jobs by status
-func (s *JobService) ListJobsByStatus(ctx context.Context, status models.JobStatus) ([]*models.Job, error) {
-	jobs, err := s.repo.ListJobsByStatus(ctx, status)
+// GetJobTransitions returns the audit trail of a job's status changes in order, along with
+// the job's tenant. The trail is kept after the job is purged or moved to the dead letter queue.
+func (s *JobService) GetJobTransitions(ctx context.Context, id string) (tenantID string, transitions []models.JobTransition, err error) {
+	transitions, err = s.repo.ListJobTransitions(ctx, id)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list job events: %w", err)
+	}
+	if len(transitions) == 0 {
+		return "", nil, ErrJobNotFound
+	}
+	return transitions[0].TenantID, transitions, nil
+}
+
+// GetJobAttempts returns the failed attempts of a job in order, along with the job's tenant.
+// A job that was moved to the dead letter queue is resolved from its DLQ entry.
+func (s *JobService) GetJobAttempts(ctx context.Context, id string) (tenantID string, attempts []models.JobAttempt, err error) {
+	job, err := s.repo.GetJobByID(ctx, id)
+	if err == nil {
+		attempts, err := s.repo.ListJobAttempts(ctx, id)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list job attempts: %w", err)
+		}
+		return job.TenantID, attempts, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	dlqJob, err := s.repo.GetDeadLetterJobByJobID(ctx, id)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get dead letter job: %w", err)
+	}
+	if dlqJob == nil {
+		return "", nil, ErrJobNotFound
+	}
+	return dlqJob.TenantID, dlqJob.Attempts, nil
+}
+
+// GetJobPosition reports how many PENDING jobs of the same queue are ahead of a job. A job
+// that is no longer PENDING has none ahead.
+func (s *JobService) GetJobPosition(ctx context.Context, id string) (*models.JobPosition, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	position := &models.JobPosition{
+		JobID:    job.ID,
+		TenantID: job.TenantID,
+		Queue:    job.Queue,
+		Status:   job.Status,
+	}
+	if job.Status != models.StatusPending {
+		return position, nil
+	}
+
+	position.JobsAhead, err = s.repo.CountJobsAheadOf(ctx, job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job position: %w", err)
+	}
+	return position, nil
+}
+
+// CancelJob cancels a PENDING or RUNNING job. A RUNNING job's handler is stopped if it runs
+// in this process; a worker elsewhere discards its result when it tries to finish the job.
+func (s *JobService) CancelJob(ctx context.Context, id string) (*models.Job, error) {
+	ok, err := s.repo.CancelJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	if !ok {
+		// Tell a missing job apart from one that already finished
+		if _, err := s.GetJob(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrJobNotCancellable
+	}
+
+	if s.cancels.Cancel(id) {
+		log.Printf("job_id=%s: stopped running handler", id)
+	}
+	s.events.Publish(models.JobEvent{JobID: id, Status: models.StatusCancelled, At: time.Now()})
+	log.Printf("job_id=%s: job cancelled", id)
+
+	return s.GetJob(ctx, id)
+}
+
+// batchStatusSources lists the statuses jobs may be moved to in bulk, each with the statuses
+// a job must be in to be moved there
+var batchStatusSources = map[models.JobStatus][]models.JobStatus{
+	models.StatusCancelled: {models.StatusPending, models.StatusRunning},
+	models.StatusPending:   {models.StatusFailed, models.StatusCancelled},
+}
+
+// UpdateJobStatusBatch moves every listed job to the given status in one transaction and reports
+// the outcome per job. Jobs not in an allowed source status are left untouched. A non-empty
+// tenantID restricts the change to that tenant's jobs; others are reported as not found.
+func (s *JobService) UpdateJobStatusBatch(ctx context.Context, tenantID string, ids []string, status models.JobStatus) ([]*models.JobStatusUpdateResult, error) {
+	from, ok := batchStatusSources[status]
+	if !ok {
+		return nil, ErrInvalidTargetStatus
+	}
+	if len(ids) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]*models.JobStatusUpdateResult, len(ids))
+	var pending []int
+	for i, id := range ids {
+		results[i] = &models.JobStatusUpdateResult{ID: id}
+		if tenantID != "" {
+			job, err := s.GetJob(ctx, id)
+			if errors.Is(err, ErrJobNotFound) || (err == nil && job.TenantID != tenantID) {
+				results[i].Error = ErrJobNotFound.Error()
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	pendingIDs := make([]string, len(pending))
+	for j, i := range pending {
+		pendingIDs[j] = ids[i]
+	}
+
+	updated, err := s.repo.UpdateJobStatusBatch(ctx, pendingIDs, from, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job statuses: %w", err)
+	}
+
+	for j, i := range pending {
+		id := ids[i]
+		if !updated[j] {
+			// Tell a missing job apart from one in a status it cannot be moved out of
+			job, err := s.GetJob(ctx, id)
+			if err != nil {
+				if !errors.Is(err, ErrJobNotFound) {
+					return nil, err
+				}
+				results[i].Error = err.Error()
+				continue
+			}
+			if job.DeadLetterID != "" {
+				// Requeuing a kept row would run the job while its DLQ entry stays behind
+				results[i].Error = "job is in the dead letter queue"
+				continue
+			}
+			results[i].Error = fmt.Sprintf("cannot move a %s job to %s", job.Status, status)
+			continue
+		}
+
+		results[i].Updated = true
+		if status == models.StatusCancelled && s.cancels.Cancel(id) {
+			log.Printf("job_id=%s: stopped running handler", id)
+		}
+		s.events.Publish(models.JobEvent{JobID: id, Status: status, At: time.Now()})
+		log.Printf("job_id=%s: status set to %s", id, status)
+	}
+
+	return results, nil
+}
+
+// UpdateMaxRetries changes how many times a PENDING job may be retried
+func (s *JobService) UpdateMaxRetries(ctx context.Context, id string, maxRetries int) (*models.Job, error) {
+	if maxRetries < 0 {
+		return nil, ErrInvalidMaxRetries
+	}
+
+	ok, err := s.repo.UpdateMaxRetries(ctx, id, maxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job: %w", err)
+	}
+
+	if !ok {
+		// Tell a missing job apart from one that is already running or finished
+		if _, err := s.GetJob(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrJobNotPending
+	}
+
+	log.Printf("job_id=%s: max_retries set to %d", id, maxRetries)
+
+	return s.GetJob(ctx, id)
+}
+
+// UpdatePayload replaces the payload of a PENDING job, keeping its ID, so a mistaken
+// submission can be corrected without cancelling and resubmitting it. The new payload must not
+// be empty and is checked against the size limit and the job's payload encoding.
+func (s *JobService) UpdatePayload(ctx context.Context, id string, raw json.RawMessage) (*models.Job, error) {
+	payload, payloadJSON, err := models.DecodePayload(raw)
+	if err != nil {
+		return nil, err
+	}
+	if payload == "" {
+		return nil, ErrPayloadRequired
+	}
+	if err := s.checkPayloadSize(payload); err != nil {
+		return nil, err
+	}
+
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.StatusPending {
+		return nil, ErrJobNotPending
+	}
+	if err := checkPayloadEncoding(job.PayloadEncoding, payload, payloadJSON); err != nil {
+		return nil, err
+	}
+
+	// The content hash follows the payload, so dedup_window matches what the job will now run
+	updated := *job
+	updated.Payload, updated.PayloadJSON = payload, payloadJSON
+	ok, err := s.repo.UpdatePayload(ctx, id, payload, payloadJSON, dedupHash(&updated))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job: %w", err)
+	}
+	if !ok {
+		// The job was leased or cancelled since it was read
+		return nil, ErrJobNotPending
+	}
+
+	log.Printf("job_id=%s: payload replaced, payload=%s", id, payload)
+
+	return s.GetJob(ctx, id)
+}
+
+// GetStats returns the number of jobs in every status and in the dead letter queue
+func (s *JobService) GetStats(ctx context.Context) (models.JobStats, error) {
+	counts, err := s.repo.CountJobsGroupedByStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job stats: %w", err)
+	}
+
+	dlqCount, err := s.repo.GetDeadLetterQueueCount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job stats: %w", err)
+	}
+
+	paused, err := s.repo.IsPaused(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job stats: %w", err)
+	}
+
+	// Report every status so clients do not have to treat missing keys as zero
+	stats := models.JobStats{
+		string(models.StatusPending):   0,
+		string(models.StatusRunning):   0,
+		string(models.StatusDone):      0,
+		string(models.StatusFailed):    0,
+		string(models.StatusCancelled): 0,
+		models.StatsKeyDLQ:             dlqCount,
+		models.StatsKeyPaused:          0,
+	}
+	for status, count := range counts {
+		stats[string(status)] = count
+	}
+	if paused {
+		stats[models.StatsKeyPaused] = 1
+	}
+
+	return stats, nil
+}
+
+// WatchJob streams status transitions of a job until it reaches a terminal status or the context is cancelled.
+// Transitions published on the in-process event bus are delivered immediately; the job is also polled
+// so transitions made by workers in other processes are still observed. The current status is sent first.
+func (s *JobService) WatchJob(ctx context.Context, id string) (<-chan models.JobEvent, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var busEvents <-chan models.JobEvent
+	unsubscribe := func() {}
+	if s.events != nil {
+		busEvents, unsubscribe = s.events.Subscribe(id)
+	}
+
+	out := make(chan models.JobEvent)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		ticker := time.NewTicker(s.eventPollInterval)
+		defer ticker.Stop()
+
+		var last models.JobStatus
+		emit := func(event models.JobEvent) bool {
+			if event.Status == last {
+				return true
+			}
+			last = event.Status
+			select {
+			case out <- event:
+				return !event.Status.IsTerminal()
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if !emit(models.JobEvent{JobID: job.ID, Status: job.Status, At: job.UpdatedAt}) {
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-busEvents:
+				if !ok {
+					return
+				}
+				if !emit(event) {
+					return
+				}
+			case <-ticker.C:
+				current, err := s.repo.GetJobByID(ctx, id)
+				if err != nil {
+					if errors.Is(err, sql.ErrNoRows) {
+						// The job left the jobs table because it was moved to the dead letter queue
+						emit(models.JobEvent{JobID: id, Status: models.StatusFailed, At: time.Now()})
+						return
+					}
+					log.Printf("job_id=%s: error polling job status: %v", id, err)
+					continue
+				}
+				if !emit(models.JobEvent{JobID: id, Status: current.Status, At: current.UpdatedAt}) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// ListJobsByStatus retrieves jobs in any of the given statuses
+func (s *JobService) ListJobsByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.Job, error) {
+	jobs, err := s.repo.ListJobsByStatus(ctx, statuses...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list jobs: %w", err)
 	}
 	return jobs, nil
 }
 
+// ListJobsByStatusPage retrieves one page of the jobs in any of the given statuses, along
+// with the cursor of the next page or nil after the last page
+func (s *JobService) ListJobsByStatusPage(ctx context.Context, page repository.JobPage, statuses ...models.JobStatus) ([]*models.Job, *repository.JobCursor, error) {
+	jobs, next, err := s.repo.ListJobsByStatusPage(ctx, page, statuses...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	return jobs, next, nil
+}
+
+// ListJobsByTag retrieves jobs carrying a tag, optionally restricted to tenantID's jobs and to
+// some statuses
+func (s *JobService) ListJobsByTag(ctx context.Context, tenantID, tag string, statuses ...models.JobStatus) ([]*models.Job, error) {
+	jobs, err := s.repo.ListJobsByTag(ctx, tenantID, tag, statuses...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// SearchJobs finds jobs whose payload contains the query string, only among tenantID's jobs
+// unless tenantID is empty. A limit of zero or above MaxSearchResults is capped at MaxSearchResults.
+func (s *JobService) SearchJobs(ctx context.Context, tenantID, query string, limit int) ([]*models.Job, error) {
+	if len(query) < MinSearchQueryLength {
+		return nil, ErrSearchQueryTooShort
+	}
+
+	if limit <= 0 || limit > MaxSearchResults {
+		limit = MaxSearchResults
+	}
+
+	jobs, err := s.repo.SearchJobs(ctx, tenantID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ListIdempotencyKeys retrieves the idempotency keys in use by a tenant, each with the job that
+// holds it. Keys older than IdempotencyTTL can be reused and are left out.
+func (s *JobService) ListIdempotencyKeys(ctx context.Context, tenantID string) ([]*models.IdempotencyKeyEntry, error) {
+	entries, err := s.repo.ListIdempotencyKeysByTenant(ctx, tenantID, s.idempotencySince())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list idempotency keys: %w", err)
+	}
+	return entries, nil
+}
+
 // ListDeadLetterJobs retrieves all dead letter jobs
 func (s *JobService) ListDeadLetterJobs(ctx context.Context) ([]*models.DeadLetterJob, error) {
 	dlqJobs, err := s.repo.ListDeadLetterJobs(ctx)
@@ -131,3 +1280,31 @@ func (s *JobService) ListDeadLetterJobs(ctx context.Context) ([]*models.DeadLett
 	}
 	return dlqJobs, nil
 }
+
+// ListDeadLetterJobsFiltered retrieves a page of dead letter jobs and the total number matching the filter
+func (s *JobService) ListDeadLetterJobsFiltered(ctx context.Context, tenantID string, limit, offset int) ([]*models.DeadLetterJob, int, error) {
+	dlqJobs, total, err := s.repo.ListDeadLetterJobsFiltered(ctx, tenantID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letter jobs: %w", err)
+	}
+	return dlqJobs, total, nil
+}
+
+// SetPaused pauses or resumes leasing for every worker. Workers keep polling while paused
+// and jobs in progress finish as usual.
+func (s *JobService) SetPaused(ctx context.Context, paused bool) error {
+	if err := s.repo.SetPaused(ctx, paused); err != nil {
+		return fmt.Errorf("failed to set paused: %w", err)
+	}
+	return nil
+}
+
+// CountDeadLetterByReason returns the number of dead letter jobs per normalized failure reason.
+// A non-empty tenantID counts only that tenant's jobs.
+func (s *JobService) CountDeadLetterByReason(ctx context.Context, tenantID string) (map[string]int, error) {
+	counts, err := s.repo.CountDeadLetterByReason(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count dead letter jobs by reason: %w", err)
+	}
+	return counts, nil
+}
@@ -3,15 +3,23 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"job-queue/internal/metrics"
 	"job-queue/internal/models"
+	"job-queue/internal/repository"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 // mockRepository is a mock implementation of JobRepository
 type mockRepository struct {
+	mu                sync.Mutex // guards jobs for tests that poll from another goroutine
 	jobs              map[string]*models.Job
 	dlqJobs           []*models.DeadLetterJob
 	runningCount      map[string]int
@@ -19,6 +27,8 @@ type mockRepository struct {
 	getJobError       error
 	listJobsError     error
 	idempotencyJob    *models.Job
+	attempts          map[string][]models.JobAttempt
+	paused            bool
 }
 
 func newMockRepository() *mockRepository {
@@ -37,7 +47,25 @@ func (m *mockRepository) CreateJob(ctx context.Context, job *models.Job) error {
 	return nil
 }
 
+func (m *mockRepository) CreateJobsBatch(ctx context.Context, jobs []*models.Job) ([]error, error) {
+	errs := make([]error, len(jobs))
+	seen := make(map[string]bool)
+	for i, job := range jobs {
+		if job.IdempotencyKey != "" {
+			if seen[job.TenantID+"/"+job.IdempotencyKey] {
+				errs[i] = &repository.ErrDuplicateIdempotencyKey{TenantID: job.TenantID, IdempotencyKey: job.IdempotencyKey}
+				continue
+			}
+			seen[job.TenantID+"/"+job.IdempotencyKey] = true
+		}
+		errs[i] = m.CreateJob(ctx, job)
+	}
+	return errs, nil
+}
+
 func (m *mockRepository) GetJobByID(ctx context.Context, id string) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.getJobError != nil {
 		return nil, m.getJobError
 	}
@@ -48,27 +76,97 @@ func (m *mockRepository) GetJobByID(ctx context.Context, id string) (*models.Job
 	return job, nil
 }
 
-func (m *mockRepository) GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string) (*models.Job, error) {
-	if m.idempotencyJob != nil {
+func (m *mockRepository) GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string, since time.Time) (*models.Job, error) {
+	if m.idempotencyJob != nil && !m.idempotencyJob.CreatedAt.Before(since) {
 		return m.idempotencyJob, nil
 	}
 	return nil, nil
 }
 
-func (m *mockRepository) ListJobsByStatus(ctx context.Context, status models.JobStatus) ([]*models.Job, error) {
+func (m *mockRepository) GetJobByTenantAndDedupHash(ctx context.Context, tenantID, dedupHash string, since time.Time) (*models.Job, error) {
+	for _, job := range m.jobs {
+		if job.TenantID == tenantID && job.DedupHash == dedupHash && !job.CreatedAt.Before(since) {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) ListJobsByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.Job, error) {
 	if m.listJobsError != nil {
 		return nil, m.listJobsError
 	}
 	var result []*models.Job
 	for _, job := range m.jobs {
-		if job.Status == status {
+		if slices.Contains(statuses, job.Status) {
 			result = append(result, job)
 		}
 	}
 	return result, nil
 }
 
-func (m *mockRepository) LeaseJob(ctx context.Context, leaseDuration time.Duration) (*models.Job, error) {
+func (m *mockRepository) ListJobsByStatusPage(ctx context.Context, page repository.JobPage, statuses ...models.JobStatus) ([]*models.Job, *repository.JobCursor, error) {
+	jobs, err := m.ListJobsByStatus(ctx, statuses...)
+	return jobs, nil, err
+}
+
+func (m *mockRepository) ListJobsByTag(ctx context.Context, tenantID, tag string, statuses ...models.JobStatus) ([]*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []*models.Job
+	for _, job := range m.jobs {
+		if len(statuses) > 0 && !slices.Contains(statuses, job.Status) {
+			continue
+		}
+		if tenantID != "" && job.TenantID != tenantID {
+			continue
+		}
+		for _, t := range job.Tags {
+			if t == tag {
+				jobs = append(jobs, job)
+				break
+			}
+		}
+	}
+	return jobs, nil
+}
+
+func (m *mockRepository) SearchJobs(ctx context.Context, tenantID, query string, limit int) ([]*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []*models.Job
+	for _, job := range m.jobs {
+		if tenantID != "" && job.TenantID != tenantID {
+			continue
+		}
+		if strings.Contains(job.Payload, query) && len(jobs) < limit {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *mockRepository) ListIdempotencyKeysByTenant(ctx context.Context, tenantID string, since time.Time) ([]*models.IdempotencyKeyEntry, error) {
+	var entries []*models.IdempotencyKeyEntry
+	for _, job := range m.jobs {
+		if job.TenantID == tenantID && job.IdempotencyKey != "" {
+			entries = append(entries, &models.IdempotencyKeyEntry{IdempotencyKey: job.IdempotencyKey, JobID: job.ID, Status: job.Status})
+		}
+	}
+	return entries, nil
+}
+
+func (m *mockRepository) LeaseJob(ctx context.Context, queue string, leaseDuration time.Duration, opts repository.LeaseOptions) (*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) LeaseJobs(ctx context.Context, queue string, n int, leaseDuration time.Duration, opts repository.LeaseOptions) ([]*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) LeaseJobsWait(ctx context.Context, queue string, n int, leaseDuration time.Duration, opts repository.LeaseOptions, wait time.Duration) ([]*models.Job, error) {
 	return nil, nil
 }
 
@@ -80,6 +178,82 @@ func (m *mockRepository) UpdateJobStatus(ctx context.Context, id string, status
 	return errors.New("job not found")
 }
 
+func (m *mockRepository) UpdateJobStatusIf(ctx context.Context, id string, from, to models.JobStatus) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.Status != from {
+		return false, nil
+	}
+	job.Status = to
+	return true, nil
+}
+
+func (m *mockRepository) CompleteJob(ctx context.Context, id, result string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusRunning {
+		return false, nil
+	}
+	job.Status = models.StatusDone
+	job.Result = result
+	return true, nil
+}
+
+func (m *mockRepository) RescheduleJob(ctx context.Context, id string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusRunning {
+		return false, nil
+	}
+	job.Status = models.StatusPending
+	job.NextRetryAt = &at
+	return true, nil
+}
+
+func (m *mockRepository) CancelJob(ctx context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || (job.Status != models.StatusPending && job.Status != models.StatusRunning) {
+		return false, nil
+	}
+	job.Status = models.StatusCancelled
+	return true, nil
+}
+
+func (m *mockRepository) UpdateJobStatusBatch(ctx context.Context, ids []string, from []models.JobStatus, to models.JobStatus) ([]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated := make([]bool, len(ids))
+	for i, id := range ids {
+		if job, exists := m.jobs[id]; exists && slices.Contains(from, job.Status) && job.DeadLetterID == "" {
+			job.Status = to
+			updated[i] = true
+		}
+	}
+	return updated, nil
+}
+
+func (m *mockRepository) UpdateMaxRetries(ctx context.Context, id string, maxRetries int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusPending {
+		return false, nil
+	}
+	job.MaxRetries = maxRetries
+	return true, nil
+}
+
 func (m *mockRepository) IncrementRetryCount(ctx context.Context, id string) error {
 	if job, exists := m.jobs[id]; exists {
 		job.RetryCount++
@@ -88,11 +262,51 @@ func (m *mockRepository) IncrementRetryCount(ctx context.Context, id string) err
 	return errors.New("job not found")
 }
 
+func (m *mockRepository) RetryJob(ctx context.Context, id string, attempt *models.JobAttempt) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusRunning {
+		return false, nil
+	}
+	job.Status = models.StatusPending
+	job.RetryCount++
+	return true, nil
+}
+
 func (m *mockRepository) GetRunningJobsCountByTenant(ctx context.Context, tenantID string) (int, error) {
 	return m.runningCount[tenantID], nil
 }
 
+func (m *mockRepository) RecordJobAttempt(ctx context.Context, jobID string, attempt *models.JobAttempt) error {
+	return nil
+}
+
+func (m *mockRepository) ListJobTransitions(ctx context.Context, jobID string) ([]models.JobTransition, error) {
+	return []models.JobTransition{}, nil
+}
+
+func (m *mockRepository) ListJobAttempts(ctx context.Context, jobID string) ([]models.JobAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.JobAttempt{}, m.attempts[jobID]...), nil
+}
+
+func (m *mockRepository) GetDeadLetterJobByJobID(ctx context.Context, jobID string) (*models.DeadLetterJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, dlqJob := range m.dlqJobs {
+		if dlqJob.JobID == jobID {
+			return dlqJob, nil
+		}
+	}
+	return nil, nil
+}
+
 func (m *mockRepository) MoveToDeadLetterQueue(ctx context.Context, job *models.Job, failureReason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	dlqJob := &models.DeadLetterJob{
 		ID:           "dlq_" + job.ID,
 		JobID:        job.ID,
@@ -106,27 +320,278 @@ func (m *mockRepository) MoveToDeadLetterQueue(ctx context.Context, job *models.
 	return nil
 }
 
+func (m *mockRepository) FailRunningJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, failureReason string) (bool, error) {
+	m.mu.Lock()
+	stored, exists := m.jobs[job.ID]
+	running := exists && stored.Status == models.StatusRunning
+	m.mu.Unlock()
+	if !running {
+		return false, nil
+	}
+	return true, m.MoveToDeadLetterQueue(ctx, job, failureReason)
+}
+
 func (m *mockRepository) ListDeadLetterJobs(ctx context.Context) ([]*models.DeadLetterJob, error) {
 	return m.dlqJobs, nil
 }
 
+func (m *mockRepository) GetTotalJobsCount(ctx context.Context) (int, error) {
+	return len(m.jobs) + len(m.dlqJobs), nil
+}
+
+func (m *mockRepository) GetCompletedJobsCount(ctx context.Context) (int, error) {
+	count := 0
+	for _, job := range m.jobs {
+		if job.Status == models.StatusDone {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockRepository) GetFailedJobsCount(ctx context.Context) (int, error) {
+	count := len(m.dlqJobs)
+	for _, job := range m.jobs {
+		if job.Status == models.StatusFailed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockRepository) ListDeadLetterJobsFiltered(ctx context.Context, tenantID string, limit, offset int) ([]*models.DeadLetterJob, int, error) {
+	var matched []*models.DeadLetterJob
+	for _, dlqJob := range m.dlqJobs {
+		if tenantID == "" || dlqJob.TenantID == tenantID {
+			matched = append(matched, dlqJob)
+		}
+	}
+
+	total := len(matched)
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total, nil
+}
+
+func (m *mockRepository) DeleteJobsOlderThan(ctx context.Context, status models.JobStatus, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, job := range m.jobs {
+		if job.Status == status && job.UpdatedAt.Before(cutoff) {
+			delete(m.jobs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *mockRepository) ListDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) ([]*models.DeadLetterJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []*models.DeadLetterJob
+	for _, dlqJob := range m.dlqJobs {
+		if dlqJob.FailedAt.Before(cutoff) {
+			expired = append(expired, dlqJob)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].FailedAt.Before(expired[j].FailedAt) })
+	return expired, nil
+}
+
+func (m *mockRepository) DeleteDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []*models.DeadLetterJob
+	for _, dlqJob := range m.dlqJobs {
+		if !dlqJob.FailedAt.Before(cutoff) {
+			kept = append(kept, dlqJob)
+		}
+	}
+	deleted := int64(len(m.dlqJobs) - len(kept))
+	m.dlqJobs = kept
+	return deleted, nil
+}
+
+func (m *mockRepository) CountDeadLetterByReason(ctx context.Context, tenantID string) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, dlqJob := range m.dlqJobs {
+		if tenantID != "" && dlqJob.TenantID != tenantID {
+			continue
+		}
+		reason, _, _ := strings.Cut(dlqJob.FailureReason, ":")
+		counts[strings.TrimSpace(reason)]++
+	}
+	return counts, nil
+}
+
+func (m *mockRepository) ListExpiredJobs(ctx context.Context, now time.Time) ([]*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []*models.Job
+	for _, job := range m.jobs {
+		if job.Status == models.StatusPending && job.ExpiresAt != nil && !job.ExpiresAt.After(now) {
+			expired = append(expired, job)
+		}
+	}
+	return expired, nil
+}
+
+func (m *mockRepository) FailExpiredJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, deadLetter bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.jobs[job.ID]
+	if !ok || stored.Status != models.StatusPending {
+		return false, nil
+	}
+	stored.Status = models.StatusFailed
+	if deadLetter {
+		m.dlqJobs = append(m.dlqJobs, &models.DeadLetterJob{ID: "dlq_" + job.ID, JobID: job.ID, TenantID: job.TenantID, Payload: job.Payload, FailureReason: attempt.Reason, FailedAt: time.Now()})
+		delete(m.jobs, job.ID)
+	}
+	return true, nil
+}
+
+func (m *mockRepository) SetPaused(ctx context.Context, paused bool) error {
+	m.paused = paused
+	return nil
+}
+
+func (m *mockRepository) IsPaused(ctx context.Context) (bool, error) {
+	return m.paused, nil
+}
+
+func (m *mockRepository) CountJobsGroupedByStatus(ctx context.Context) (map[models.JobStatus]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[models.JobStatus]int)
+	for _, job := range m.jobs {
+		counts[job.Status]++
+	}
+	return counts, nil
+}
+
+func (m *mockRepository) CountJobsAheadOf(ctx context.Context, job *models.Job) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, other := range m.jobs {
+		if other.ID != job.ID && other.Queue == job.Queue && other.Status == models.StatusPending && other.CreatedAt.Before(job.CreatedAt) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockRepository) CountJobsByStatus(ctx context.Context, status models.JobStatus) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, job := range m.jobs {
+		if job.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockRepository) OldestPendingJobAge(ctx context.Context) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var oldest time.Time
+	for _, job := range m.jobs {
+		if job.Status == models.StatusPending && (oldest.IsZero() || job.CreatedAt.Before(oldest)) {
+			oldest = job.CreatedAt
+		}
+	}
+	if oldest.IsZero() {
+		return 0, nil
+	}
+	return time.Since(oldest), nil
+}
+
+func (m *mockRepository) PendingJobAgePercentiles(ctx context.Context, percentiles []float64) ([]time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ages []time.Duration
+	for _, job := range m.jobs {
+		if job.Status == models.StatusPending {
+			ages = append(ages, time.Since(job.CreatedAt))
+		}
+	}
+	sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+
+	result := make([]time.Duration, len(percentiles))
+	if len(ages) == 0 {
+		return result, nil
+	}
+	for i, p := range percentiles {
+		result[i] = ages[int(p*float64(len(ages)-1))]
+	}
+	return result, nil
+}
+
+func (m *mockRepository) UpdatePayload(ctx context.Context, id string, payload string, payloadJSON bool, dedupHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok || job.Status != models.StatusPending {
+		return false, nil
+	}
+	job.Payload = payload
+	job.PayloadJSON = payloadJSON
+	job.DedupHash = dedupHash
+	return true, nil
+}
+
+func (m *mockRepository) ReclaimExpiredLeases(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockRepository) GetDeadLetterQueueCount(ctx context.Context) (int, error) {
+	return len(m.dlqJobs), nil
+}
+
 func TestJobService_CreateJob_Success(t *testing.T) {
 	repo := newMockRepository()
-	rateLimiter := NewRateLimiter(5, 10)
+	rateLimiter := NewRateLimiter(10)
 	metrics := metrics.NewMetrics()
 	service := NewJobService(repo, rateLimiter, metrics)
 
 	req := &models.CreateJobRequest{
 		TenantID: "tenant-1",
-		Payload:  "test payload",
+		Payload:  models.StringPayload("test payload"),
 	}
 
-	job, err := service.CreateJob(context.Background(), req)
+	job, created, err := service.CreateJob(context.Background(), req)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if job == nil {
+	if job == nil || !created {
 		t.Fatal("expected job to be created")
 	}
 
@@ -134,8 +599,8 @@ func TestJobService_CreateJob_Success(t *testing.T) {
 		t.Errorf("expected tenant_id %s, got %s", req.TenantID, job.TenantID)
 	}
 
-	if job.Payload != req.Payload {
-		t.Errorf("expected payload %s, got %s", req.Payload, job.Payload)
+	if job.Payload != "test payload" || job.PayloadJSON {
+		t.Errorf("expected string payload %q, got %s", "test payload", job.Payload)
 	}
 
 	if job.Status != models.StatusPending {
@@ -149,18 +614,18 @@ func TestJobService_CreateJob_Success(t *testing.T) {
 
 func TestJobService_CreateJob_WithMaxRetries(t *testing.T) {
 	repo := newMockRepository()
-	rateLimiter := NewRateLimiter(5, 10)
+	rateLimiter := NewRateLimiter(10)
 	metrics := metrics.NewMetrics()
 	service := NewJobService(repo, rateLimiter, metrics)
 
 	maxRetries := 5
 	req := &models.CreateJobRequest{
 		TenantID:   "tenant-1",
-		Payload:    "test payload",
+		Payload:    models.StringPayload("test payload"),
 		MaxRetries: &maxRetries,
 	}
 
-	job, err := service.CreateJob(context.Background(), req)
+	job, _, err := service.CreateJob(context.Background(), req)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -170,116 +635,271 @@ func TestJobService_CreateJob_WithMaxRetries(t *testing.T) {
 	}
 }
 
-func TestJobService_CreateJob_RateLimitSubmission(t *testing.T) {
+func TestJobService_CreateJob_TenantIDPrefix(t *testing.T) {
 	repo := newMockRepository()
-	rateLimiter := NewRateLimiter(5, 2) // Max 2 submissions per minute
-	metrics := metrics.NewMetrics()
-	service := NewJobService(repo, rateLimiter, metrics)
+	service := NewJobServiceWithConfig(repo, NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{
+		TenantIDPrefixes: map[string]string{"acme": "acme"},
+	})
+	ctx := context.Background()
 
-	req := &models.CreateJobRequest{
-		TenantID: "tenant-1",
-		Payload:  "test payload",
+	job, _, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "acme", Payload: models.StringPayload("work")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(job.ID, "acme_") || len(job.ID) != len("acme_")+36 {
+		t.Errorf("expected an id like acme_<uuid>, got %s", job.ID)
+	}
+	if found, err := service.GetJob(ctx, job.ID); err != nil || found.ID != job.ID {
+		t.Errorf("expected to get the job by its prefixed id, got %v", err)
 	}
 
-	// Create first job - should succeed
-	_, err := service.CreateJob(context.Background(), req)
+	// Tenants without a prefix and client-supplied IDs keep their IDs as they are
+	other, _, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("work")})
 	if err != nil {
-		t.Fatalf("expected no error for first job, got %v", err)
+		t.Fatalf("expected no error, got %v", err)
 	}
-
-	// Create second job - should succeed
-	_, err = service.CreateJob(context.Background(), req)
+	if strings.Contains(other.ID, "_") || len(other.ID) != 36 {
+		t.Errorf("expected a bare uuid for a tenant without a prefix, got %s", other.ID)
+	}
+	supplied, _, err := service.CreateJob(ctx, &models.CreateJobRequest{ID: "order-42", TenantID: "acme", Payload: models.StringPayload("work")})
 	if err != nil {
-		t.Fatalf("expected no error for second job, got %v", err)
+		t.Fatalf("expected no error, got %v", err)
 	}
-
-	// Create third job - should fail rate limit
-	_, err = service.CreateJob(context.Background(), req)
-	if err != ErrRateLimitExceeded {
-		t.Errorf("expected rate limit error, got %v", err)
+	if supplied.ID != "order-42" {
+		t.Errorf("expected the client-supplied id to be kept, got %s", supplied.ID)
 	}
 }
 
-func TestJobService_CreateJob_RateLimitConcurrent(t *testing.T) {
-	repo := newMockRepository()
-	repo.runningCount["tenant-1"] = 5 // Already at limit
-	rateLimiter := NewRateLimiter(5, 10)
-	metrics := metrics.NewMetrics()
-	service := NewJobService(repo, rateLimiter, metrics)
-
-	req := &models.CreateJobRequest{
-		TenantID: "tenant-1",
-		Payload:  "test payload",
+func TestValidateIDPrefix(t *testing.T) {
+	for _, prefix := range []string{"acme", "team.a-1"} {
+		if err := ValidateIDPrefix(prefix); err != nil {
+			t.Errorf("expected %q to be valid, got %v", prefix, err)
+		}
 	}
-
-	_, err := service.CreateJob(context.Background(), req)
-	if err != ErrRateLimitExceeded {
-		t.Errorf("expected rate limit error, got %v", err)
+	for _, prefix := range []string{"", "has space", "a/b", strings.Repeat("x", MaxIDPrefixLength+1)} {
+		if err := ValidateIDPrefix(prefix); err != ErrInvalidIDPrefix {
+			t.Errorf("expected %q to be rejected, got %v", prefix, err)
+		}
 	}
 }
 
-func TestJobService_CreateJob_Idempotency(t *testing.T) {
+func TestJobService_CreateJob_DedupWindow(t *testing.T) {
 	repo := newMockRepository()
-	existingJob := &models.Job{
-		ID:             "existing-id",
-		TenantID:       "tenant-1",
-		IdempotencyKey: "key-123",
-		Payload:        "original payload",
-		Status:         models.StatusPending,
-	}
-	repo.idempotencyJob = existingJob
+	service := NewJobService(repo, NewRateLimiter(100), metrics.NewMetrics())
+	ctx := context.Background()
 
-	rateLimiter := NewRateLimiter(5, 10)
-	metrics := metrics.NewMetrics()
-	service := NewJobService(repo, rateLimiter, metrics)
-
-	req := &models.CreateJobRequest{
-		TenantID:       "tenant-1",
-		Payload:        "different payload",
-		IdempotencyKey: "key-123",
-	}
+	existing := &models.Job{ID: "existing", TenantID: "tenant-1", Queue: models.DefaultQueue, Payload: "rebuild", PayloadEncoding: models.PayloadEncodingUTF8, CreatedAt: time.Now().Add(-2 * time.Minute)}
+	existing.DedupHash = dedupHash(existing)
+	repo.jobs[existing.ID] = existing
 
-	job, err := service.CreateJob(context.Background(), req)
+	job, created, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("rebuild"), DedupWindow: "5m"})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	if created || job.ID != "existing" {
+		t.Errorf("expected the identical job within the window, got %s (created %t)", job.ID, created)
+	}
 
-	if job.ID != existingJob.ID {
-		t.Errorf("expected existing job ID %s, got %s", existingJob.ID, job.ID)
+	// A shorter window, another queue, another tenant or no dedup_window create a new job
+	for _, req := range []*models.CreateJobRequest{
+		{TenantID: "tenant-1", Payload: models.StringPayload("rebuild"), DedupWindow: "1m"},
+		{TenantID: "tenant-1", Queue: "reports", Payload: models.StringPayload("rebuild"), DedupWindow: "5m"},
+		{TenantID: "tenant-2", Payload: models.StringPayload("rebuild"), DedupWindow: "5m"},
+		{TenantID: "tenant-1", Payload: models.StringPayload("rebuild")},
+	} {
+		job, created, err := service.CreateJob(ctx, req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !created || job.ID == "existing" {
+			t.Errorf("expected a new job for %+v", req)
+		}
 	}
 
-	if job.Payload != existingJob.Payload {
-		t.Errorf("expected original payload, got %s", job.Payload)
+	for _, window := range []string{"soon", "-5m", "48h"} {
+		_, _, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("rebuild"), DedupWindow: window})
+		if err != ErrInvalidDedupWindow {
+			t.Errorf("expected ErrInvalidDedupWindow for %q, got %v", window, err)
+		}
 	}
 }
 
-func TestJobService_GetJob_Success(t *testing.T) {
+func TestJobService_CreateJobsBatch_DedupWindow(t *testing.T) {
 	repo := newMockRepository()
-	expectedJob := &models.Job{
-		ID:       "job-1",
-		TenantID: "tenant-1",
-		Payload:  "test",
-		Status:   models.StatusPending,
-	}
-	repo.jobs["job-1"] = expectedJob
-
-	rateLimiter := NewRateLimiter(5, 10)
-	metrics := metrics.NewMetrics()
-	service := NewJobService(repo, rateLimiter, metrics)
+	service := NewJobService(repo, NewRateLimiter(100), metrics.NewMetrics())
 
-	job, err := service.GetJob(context.Background(), "job-1")
+	results, err := service.CreateJobsBatch(context.Background(), []*models.CreateJobRequest{
+		{TenantID: "tenant-1", Payload: models.StringPayload("rebuild")},
+		{TenantID: "tenant-1", Payload: models.StringPayload("rebuild"), DedupWindow: "5m"},
+		{TenantID: "tenant-1", Payload: models.StringPayload("other"), DedupWindow: "5m"},
+	})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if job.ID != expectedJob.ID {
+	if results[0].ID == "" || results[1].ID != results[0].ID {
+		t.Errorf("expected item 1 to be deduplicated against item 0, got %+v and %+v", results[0], results[1])
+	}
+	if results[2].ID == "" || results[2].ID == results[0].ID {
+		t.Errorf("expected a new job for a different payload, got %+v", results[2])
+	}
+	if len(repo.jobs) != 2 {
+		t.Errorf("expected 2 jobs to be created, got %d", len(repo.jobs))
+	}
+}
+
+func TestJobService_CreateJob_RateLimitSubmission(t *testing.T) {
+	repo := newMockRepository()
+	rateLimiter := NewRateLimiter(2) // Max 2 submissions per minute
+	metrics := metrics.NewMetrics()
+	service := NewJobService(repo, rateLimiter, metrics)
+
+	req := &models.CreateJobRequest{
+		TenantID: "tenant-1",
+		Payload:  models.StringPayload("test payload"),
+	}
+
+	// Create first job - should succeed
+	_, _, err := service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error for first job, got %v", err)
+	}
+
+	// Create second job - should succeed
+	_, _, err = service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error for second job, got %v", err)
+	}
+
+	// Create third job - should fail rate limit
+	_, _, err = service.CreateJob(context.Background(), req)
+	if !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("expected rate limit error, got %v", err)
+	}
+}
+
+func TestJobService_CreateJob_BypassLimitsSkipsRateLimit(t *testing.T) {
+	repo := newMockRepository()
+	service := NewJobService(repo, NewRateLimiter(1), metrics.NewMetrics())
+	ctx := context.Background()
+
+	req := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("test payload")}
+	if _, _, err := service.CreateJob(ctx, req); err != nil {
+		t.Fatalf("expected no error for first job, got %v", err)
+	}
+	if _, _, err := service.CreateJob(ctx, req); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+
+	urgent := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("urgent"), BypassLimits: true}
+	job, _, err := service.CreateJob(ctx, urgent)
+	if err != nil {
+		t.Fatalf("expected an urgent job past the rate limit, got %v", err)
+	}
+	if !job.BypassLimits {
+		t.Error("expected the job to be marked bypass_limits")
+	}
+
+	results, err := service.CreateJobsBatch(ctx, []*models.CreateJobRequest{urgent, req})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results[0].Error != "" {
+		t.Errorf("expected the urgent batch item to be created, got %q", results[0].Error)
+	}
+	if results[1].Error == "" {
+		t.Error("expected the regular batch item to stay rate limited")
+	}
+}
+
+func TestJobService_CreateJob_AcceptedWhileTenantAtRunningLimit(t *testing.T) {
+	repo := newMockRepository()
+	repo.runningCount["tenant-1"] = 5 // Already at the running limit
+	rateLimiter := NewRateLimiter(10)
+	metrics := metrics.NewMetrics()
+	service := NewJobService(repo, rateLimiter, metrics)
+
+	req := &models.CreateJobRequest{
+		TenantID: "tenant-1",
+		Payload:  models.StringPayload("test payload"),
+	}
+
+	// The running limit is enforced at lease time, so the job waits as PENDING
+	job, _, err := service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job.Status != models.StatusPending {
+		t.Errorf("expected status PENDING, got %s", job.Status)
+	}
+}
+
+func TestJobService_CreateJob_Idempotency(t *testing.T) {
+	repo := newMockRepository()
+	existingJob := &models.Job{
+		ID:             "existing-id",
+		TenantID:       "tenant-1",
+		IdempotencyKey: "key-123",
+		Payload:        "original payload",
+		Status:         models.StatusPending,
+	}
+	repo.idempotencyJob = existingJob
+
+	rateLimiter := NewRateLimiter(10)
+	metrics := metrics.NewMetrics()
+	service := NewJobService(repo, rateLimiter, metrics)
+
+	req := &models.CreateJobRequest{
+		TenantID:       "tenant-1",
+		Payload:        models.StringPayload("different payload"),
+		IdempotencyKey: "key-123",
+	}
+
+	job, created, err := service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if created {
+		t.Error("expected existing job to be reported as not created")
+	}
+
+	if job.ID != existingJob.ID {
+		t.Errorf("expected existing job ID %s, got %s", existingJob.ID, job.ID)
+	}
+
+	if job.Payload != existingJob.Payload {
+		t.Errorf("expected original payload, got %s", job.Payload)
+	}
+}
+
+func TestJobService_GetJob_Success(t *testing.T) {
+	repo := newMockRepository()
+	expectedJob := &models.Job{
+		ID:       "job-1",
+		TenantID: "tenant-1",
+		Payload:  "test",
+		Status:   models.StatusPending,
+	}
+	repo.jobs["job-1"] = expectedJob
+
+	rateLimiter := NewRateLimiter(10)
+	metrics := metrics.NewMetrics()
+	service := NewJobService(repo, rateLimiter, metrics)
+
+	job, err := service.GetJob(context.Background(), "job-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if job.ID != expectedJob.ID {
 		t.Errorf("expected job ID %s, got %s", expectedJob.ID, job.ID)
 	}
 }
 
 func TestJobService_GetJob_NotFound(t *testing.T) {
 	repo := newMockRepository()
-	rateLimiter := NewRateLimiter(5, 10)
+	rateLimiter := NewRateLimiter(10)
 	metrics := metrics.NewMetrics()
 	service := NewJobService(repo, rateLimiter, metrics)
 
@@ -289,13 +909,59 @@ func TestJobService_GetJob_NotFound(t *testing.T) {
 	}
 }
 
+func TestJobService_CreateJob_Dependencies(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		dependsOn []string
+		expected  error
+		message   string
+	}{
+		{name: "existing dependency", dependsOn: []string{"parent"}},
+		{name: "missing dependency", dependsOn: []string{"missing"}, expected: ErrInvalidDependencies, message: "invalid depends_on: job missing does not exist"},
+		{name: "another tenant's dependency", dependsOn: []string{"foreign"}, expected: ErrInvalidDependencies, message: "invalid depends_on: job foreign does not exist"},
+		{name: "cancelled dependency", dependsOn: []string{"cancelled"}, expected: ErrInvalidDependencies},
+		{name: "duplicate dependency", dependsOn: []string{"parent", "parent"}, expected: ErrInvalidDependencies},
+		{name: "self dependency", id: "self", dependsOn: []string{"self"}, expected: ErrInvalidDependencies},
+		// waits-on-reused depends on a purged job whose ID is being reused
+		{name: "cycle through a reused ID", id: "purged", dependsOn: []string{"waits-on-reused"}, expected: ErrDependencyCycle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.jobs["parent"] = &models.Job{ID: "parent", TenantID: "tenant-1", Status: models.StatusRunning}
+			repo.jobs["cancelled"] = &models.Job{ID: "cancelled", TenantID: "tenant-1", Status: models.StatusCancelled}
+			repo.jobs["waits-on-reused"] = &models.Job{ID: "waits-on-reused", TenantID: "tenant-1", Status: models.StatusPending, DependsOn: []string{"parent", "purged"}}
+			repo.jobs["foreign"] = &models.Job{ID: "foreign", TenantID: "tenant-2", Status: models.StatusRunning}
+			service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+
+			job, _, err := service.CreateJob(context.Background(), &models.CreateJobRequest{
+				ID:        tt.id,
+				TenantID:  "tenant-1",
+				Payload:   models.StringPayload("work"),
+				DependsOn: tt.dependsOn,
+			})
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			if tt.message != "" && err.Error() != tt.message {
+				t.Errorf("expected %q, got %q", tt.message, err.Error())
+			}
+			if tt.expected == nil && (len(job.DependsOn) != 1 || job.DependsOn[0] != "parent") {
+				t.Errorf("expected the job to depend on parent, got %v", job.DependsOn)
+			}
+		})
+	}
+}
+
 func TestJobService_ListJobsByStatus(t *testing.T) {
 	repo := newMockRepository()
 	repo.jobs["job-1"] = &models.Job{ID: "job-1", Status: models.StatusPending}
 	repo.jobs["job-2"] = &models.Job{ID: "job-2", Status: models.StatusPending}
 	repo.jobs["job-3"] = &models.Job{ID: "job-3", Status: models.StatusDone}
 
-	rateLimiter := NewRateLimiter(5, 10)
+	rateLimiter := NewRateLimiter(10)
 	metrics := metrics.NewMetrics()
 	service := NewJobService(repo, rateLimiter, metrics)
 
@@ -316,7 +982,7 @@ func TestJobService_ListDeadLetterJobs(t *testing.T) {
 		{ID: "dlq-2", JobID: "job-2", TenantID: "tenant-2", FailureReason: "test"},
 	}
 
-	rateLimiter := NewRateLimiter(5, 10)
+	rateLimiter := NewRateLimiter(10)
 	metrics := metrics.NewMetrics()
 	service := NewJobService(repo, rateLimiter, metrics)
 
@@ -329,3 +995,638 @@ func TestJobService_ListDeadLetterJobs(t *testing.T) {
 		t.Errorf("expected 2 DLQ jobs, got %d", len(dlqJobs))
 	}
 }
+
+func TestJobService_CreateJobsBatch_PartialFailure(t *testing.T) {
+	repo := newMockRepository()
+	rateLimiter := NewRateLimiter(10)
+	metrics := metrics.NewMetrics()
+	service := NewJobService(repo, rateLimiter, metrics)
+
+	reqs := []*models.CreateJobRequest{
+		{TenantID: "tenant-1", Payload: models.StringPayload("first"), IdempotencyKey: "key-1"},
+		{TenantID: "tenant-1", Payload: models.StringPayload("second"), IdempotencyKey: "key-1"},
+		{TenantID: "", Payload: models.StringPayload("no tenant")},
+		{TenantID: "tenant-2", Payload: models.StringPayload("third")},
+	}
+
+	results, err := service.CreateJobsBatch(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+
+	if results[0].ID == "" || results[0].Error != "" {
+		t.Errorf("expected first job to be created, got %+v", results[0])
+	}
+
+	if results[1].Error != "duplicate idempotency key" {
+		t.Errorf("expected duplicate idempotency key error, got %+v", results[1])
+	}
+
+	if results[2].Error != "tenant_id is required" {
+		t.Errorf("expected tenant_id validation error, got %+v", results[2])
+	}
+
+	if results[3].ID == "" || results[3].Error != "" {
+		t.Errorf("expected last job to be created, got %+v", results[3])
+	}
+
+	if len(repo.jobs) != 2 {
+		t.Errorf("expected 2 jobs stored, got %d", len(repo.jobs))
+	}
+
+	if metrics.GetSnapshot()["total_jobs"] != 2 {
+		t.Errorf("expected total_jobs 2, got %d", metrics.GetSnapshot()["total_jobs"])
+	}
+}
+
+func TestJobService_CreateJobsBatch_RateLimit(t *testing.T) {
+	repo := newMockRepository()
+	rateLimiter := NewRateLimiter(2) // Max 2 submissions per minute
+	metrics := metrics.NewMetrics()
+	service := NewJobService(repo, rateLimiter, metrics)
+
+	reqs := []*models.CreateJobRequest{
+		{TenantID: "tenant-1", Payload: models.StringPayload("one")},
+		{TenantID: "tenant-1", Payload: models.StringPayload("two")},
+		{TenantID: "tenant-1", Payload: models.StringPayload("three")},
+		{TenantID: "tenant-2", Payload: models.StringPayload("other tenant")},
+	}
+
+	results, err := service.CreateJobsBatch(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if results[i].Error != ErrRateLimitExceeded.Error() {
+			t.Errorf("expected rate limit error for item %d, got %+v", i, results[i])
+		}
+	}
+
+	if results[3].ID == "" {
+		t.Errorf("expected tenant-2 job to be created, got %+v", results[3])
+	}
+}
+
+func TestJobService_CreateJobsBatch_TooLarge(t *testing.T) {
+	repo := newMockRepository()
+	service := NewJobService(repo, NewRateLimiter(1000), metrics.NewMetrics())
+
+	reqs := make([]*models.CreateJobRequest, MaxBatchSize+1)
+	for i := range reqs {
+		reqs[i] = &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("job")}
+	}
+
+	_, err := service.CreateJobsBatch(context.Background(), reqs)
+	if err != ErrBatchTooLarge {
+		t.Errorf("expected ErrBatchTooLarge, got %v", err)
+	}
+}
+
+func TestJobService_WatchJob_StreamsTransitions(t *testing.T) {
+	repo := newMockRepository()
+	repo.jobs["job-1"] = &models.Job{ID: "job-1", TenantID: "tenant-1", Status: models.StatusPending}
+
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+	bus := NewEventBus()
+	service.SetEventBus(bus)
+	service.eventPollInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := service.WatchJob(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	first := <-events
+	if first.Status != models.StatusPending {
+		t.Fatalf("expected current status PENDING first, got %s", first.Status)
+	}
+
+	bus.Publish(models.JobEvent{JobID: "job-1", Status: models.StatusRunning})
+	bus.Publish(models.JobEvent{JobID: "job-1", Status: models.StatusRunning})
+	bus.Publish(models.JobEvent{JobID: "job-1", Status: models.StatusDone})
+
+	var statuses []models.JobStatus
+	for event := range events {
+		statuses = append(statuses, event.Status)
+	}
+
+	if len(statuses) != 2 || statuses[0] != models.StatusRunning || statuses[1] != models.StatusDone {
+		t.Errorf("expected [RUNNING DONE] then close, got %v", statuses)
+	}
+}
+
+func TestJobService_WatchJob_PollsForExternalTransitions(t *testing.T) {
+	repo := newMockRepository()
+	repo.jobs["job-1"] = &models.Job{ID: "job-1", TenantID: "tenant-1", Status: models.StatusRunning}
+
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+	service.eventPollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := service.WatchJob(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	<-events
+
+	// Simulate another process moving the job to the dead letter queue
+	repo.MoveToDeadLetterQueue(ctx, repo.jobs["job-1"], "failed elsewhere")
+
+	select {
+	case event := <-events:
+		if event.Status != models.StatusFailed {
+			t.Errorf("expected FAILED once the job left the jobs table, got %s", event.Status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected polled transition")
+	}
+}
+
+func TestJobService_WatchJob_NotFound(t *testing.T) {
+	service := NewJobService(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics())
+
+	_, err := service.WatchJob(context.Background(), "missing")
+	if err != ErrJobNotFound {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobService_CreateJob_PayloadTooLarge(t *testing.T) {
+	repo := newMockRepository()
+	service := NewJobServiceWithConfig(repo, NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{MaxPayloadBytes: 8})
+
+	_, _, err := service.CreateJob(context.Background(), &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("123456789")})
+
+	var sizeErr *ErrPayloadTooLarge
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("expected ErrPayloadTooLarge, got %v", err)
+	}
+	if sizeErr.Size != 9 || sizeErr.MaxBytes != 8 {
+		t.Errorf("expected size 9 and max 8, got %d and %d", sizeErr.Size, sizeErr.MaxBytes)
+	}
+
+	if len(repo.jobs) != 0 {
+		t.Errorf("expected no job to be stored, got %d", len(repo.jobs))
+	}
+
+	if _, _, err := service.CreateJob(context.Background(), &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("12345678")}); err != nil {
+		t.Errorf("expected payload at the limit to be accepted, got %v", err)
+	}
+}
+
+func TestJobService_CreateJobsBatch_PayloadTooLarge(t *testing.T) {
+	repo := newMockRepository()
+	service := NewJobServiceWithConfig(repo, NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{MaxPayloadBytes: 8})
+
+	reqs := []*models.CreateJobRequest{
+		{TenantID: "tenant-1", Payload: models.StringPayload("small")},
+		{TenantID: "tenant-1", Payload: models.StringPayload("much too large")},
+	}
+
+	results, err := service.CreateJobsBatch(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if results[0].ID == "" || results[0].Error != "" {
+		t.Errorf("expected first item to be created, got %+v", results[0])
+	}
+	if results[1].ID != "" || results[1].Error == "" {
+		t.Errorf("expected second item to be rejected, got %+v", results[1])
+	}
+}
+
+func TestJobService_CreateJob_MaxPendingJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	repo.jobs["done-1"] = &models.Job{ID: "done-1", TenantID: "tenant-1", Status: models.StatusDone}
+	service := NewJobServiceWithConfig(repo, NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{MaxPendingJobs: 2})
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("work")}); err != nil {
+			t.Fatalf("expected job %d to fit in the queue, got %v", i+1, err)
+		}
+	}
+	if _, _, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-2", Payload: models.StringPayload("work")}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull for any tenant once the limit is reached, got %v", err)
+	}
+
+	// A batch only takes the room that is left
+	for _, job := range repo.jobs {
+		if job.ID != "done-1" {
+			job.Status = models.StatusRunning
+			break
+		}
+	}
+	results, err := service.CreateJobsBatch(ctx, []*models.CreateJobRequest{
+		{TenantID: "tenant-1", Payload: models.StringPayload("a")},
+		{TenantID: "tenant-1", Payload: models.StringPayload("b")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results[0].ID == "" || results[1].Error != ErrQueueFull.Error() {
+		t.Errorf("expected the first item created and the second rejected as queue full, got %+v and %+v", results[0], results[1])
+	}
+}
+
+func TestJobService_SearchJobs_QueryTooShort(t *testing.T) {
+	service := NewJobService(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics())
+
+	if _, err := service.SearchJobs(context.Background(), "", "ab", 10); err != ErrSearchQueryTooShort {
+		t.Errorf("expected ErrSearchQueryTooShort, got %v", err)
+	}
+}
+
+func TestJobService_CreateJob_InvalidTags(t *testing.T) {
+	service := NewJobService(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics())
+
+	tooMany := make([]string, MaxTagsPerJob+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+
+	for name, tags := range map[string][]string{
+		"too many":  tooMany,
+		"empty":     {"email", ""},
+		"too long":  {strings.Repeat("x", MaxTagLength+1)},
+		"duplicate": {"email", "email"},
+	} {
+		req := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("test"), Tags: tags}
+		if _, _, err := service.CreateJob(context.Background(), req); !errors.Is(err, ErrInvalidTags) {
+			t.Errorf("%s: expected ErrInvalidTags, got %v", name, err)
+		}
+	}
+
+	req := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("test"), Tags: []string{"email", "nightly"}}
+	job, _, err := service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected valid tags to be accepted, got %v", err)
+	}
+	if len(job.Tags) != 2 {
+		t.Errorf("expected tags to be kept, got %v", job.Tags)
+	}
+}
+
+func TestJobService_GetStats(t *testing.T) {
+	repo := newMockRepository()
+	repo.jobs["job-1"] = &models.Job{ID: "job-1", Status: models.StatusPending}
+	repo.jobs["job-2"] = &models.Job{ID: "job-2", Status: models.StatusPending}
+	repo.jobs["job-3"] = &models.Job{ID: "job-3", Status: models.StatusDone}
+	repo.dlqJobs = append(repo.dlqJobs, &models.DeadLetterJob{ID: "dlq-1"})
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+
+	stats, err := service.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := models.JobStats{"PENDING": 2, "RUNNING": 0, "DONE": 1, "FAILED": 0, "CANCELLED": 0, "DLQ": 1, "PAUSED": 0}
+	if len(stats) != len(expected) {
+		t.Errorf("expected keys %v, got %v", expected, stats)
+	}
+	for key, want := range expected {
+		if got, ok := stats[key]; !ok || got != want {
+			t.Errorf("expected %s=%d, got %d (present %v)", key, want, got, ok)
+		}
+	}
+
+	if err := service.SetPaused(context.Background(), true); err != nil {
+		t.Fatalf("failed to pause: %v", err)
+	}
+	stats, err = service.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stats[models.StatsKeyPaused] != 1 {
+		t.Errorf("expected PAUSED=1 while paused, got %d", stats[models.StatsKeyPaused])
+	}
+}
+
+func TestJobService_CancelJob(t *testing.T) {
+	repo := newMockRepository()
+	repo.jobs["pending"] = &models.Job{ID: "pending", Status: models.StatusPending}
+	repo.jobs["done"] = &models.Job{ID: "done", Status: models.StatusDone}
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+	ctx := context.Background()
+
+	job, err := service.CancelJob(ctx, "pending")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job.Status != models.StatusCancelled {
+		t.Errorf("expected status CANCELLED, got %s", job.Status)
+	}
+
+	if _, err := service.CancelJob(ctx, "done"); err != ErrJobNotCancellable {
+		t.Errorf("expected ErrJobNotCancellable for a finished job, got %v", err)
+	}
+	if _, err := service.CancelJob(ctx, "missing"); err != ErrJobNotFound {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobService_UpdateJobStatusBatch_DeadLetteredJob(t *testing.T) {
+	repo := newMockRepository()
+	repo.jobs["failed"] = &models.Job{ID: "failed", Status: models.StatusFailed}
+	repo.jobs["dead"] = &models.Job{ID: "dead", Status: models.StatusFailed, DeadLetterID: "dlq-1"}
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+
+	results, err := service.UpdateJobStatusBatch(context.Background(), "", []string{"failed", "dead"}, models.StatusPending)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !results[0].Updated {
+		t.Errorf("expected the FAILED job to be requeued, got %+v", results[0])
+	}
+	if results[1].Updated || results[1].Error != "job is in the dead letter queue" {
+		t.Errorf("expected the dead-lettered job to be rejected, got %+v", results[1])
+	}
+	if status := repo.jobs["dead"].Status; status != models.StatusFailed {
+		t.Errorf("expected the dead-lettered job to stay FAILED, got %s", status)
+	}
+}
+
+func TestJobService_UpdateMaxRetries(t *testing.T) {
+	repo := newMockRepository()
+	repo.jobs["pending"] = &models.Job{ID: "pending", Status: models.StatusPending, MaxRetries: 3}
+	repo.jobs["running"] = &models.Job{ID: "running", Status: models.StatusRunning, MaxRetries: 3}
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+	ctx := context.Background()
+
+	job, err := service.UpdateMaxRetries(ctx, "pending", 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job.MaxRetries != 10 {
+		t.Errorf("expected max_retries 10, got %d", job.MaxRetries)
+	}
+
+	if _, err := service.UpdateMaxRetries(ctx, "pending", -1); err != ErrInvalidMaxRetries {
+		t.Errorf("expected ErrInvalidMaxRetries, got %v", err)
+	}
+	if _, err := service.UpdateMaxRetries(ctx, "running", 10); err != ErrJobNotPending {
+		t.Errorf("expected ErrJobNotPending for a running job, got %v", err)
+	}
+	if repo.jobs["running"].MaxRetries != 3 {
+		t.Errorf("expected the running job to keep max_retries 3, got %d", repo.jobs["running"].MaxRetries)
+	}
+	if _, err := service.UpdateMaxRetries(ctx, "missing", 10); err != ErrJobNotFound {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobService_UpdatePayload(t *testing.T) {
+	repo := newMockRepository()
+	repo.jobs["pending"] = &models.Job{ID: "pending", Payload: "typo", Status: models.StatusPending}
+	repo.jobs["binary"] = &models.Job{ID: "binary", Payload: "AAE=", PayloadEncoding: models.PayloadEncodingBase64, Status: models.StatusPending}
+	repo.jobs["running"] = &models.Job{ID: "running", Payload: "work", Status: models.StatusRunning}
+	service := NewJobServiceWithConfig(repo, NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{MaxPayloadBytes: 16})
+	ctx := context.Background()
+
+	job, err := service.UpdatePayload(ctx, "pending", models.StringPayload("fixed"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job.ID != "pending" || job.Payload != "fixed" {
+		t.Errorf("expected job pending with payload fixed, got %s with %q", job.ID, job.Payload)
+	}
+
+	if _, err := service.UpdatePayload(ctx, "pending", models.StringPayload("")); err != ErrPayloadRequired {
+		t.Errorf("expected ErrPayloadRequired for an empty payload, got %v", err)
+	}
+	if repo.jobs["pending"].Payload != "fixed" {
+		t.Errorf("expected the job to keep its payload, got %q", repo.jobs["pending"].Payload)
+	}
+
+	var sizeErr *ErrPayloadTooLarge
+	if _, err := service.UpdatePayload(ctx, "pending", models.StringPayload("far too large a payload")); !errors.As(err, &sizeErr) {
+		t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+	}
+	if _, err := service.UpdatePayload(ctx, "binary", models.StringPayload("not base64!")); !errors.Is(err, ErrInvalidBase64) {
+		t.Errorf("expected ErrInvalidBase64 for a base64 job, got %v", err)
+	}
+	if _, err := service.UpdatePayload(ctx, "running", models.StringPayload("fixed")); err != ErrJobNotPending {
+		t.Errorf("expected ErrJobNotPending for a running job, got %v", err)
+	}
+	if repo.jobs["running"].Payload != "work" {
+		t.Errorf("expected the running job to keep its payload, got %q", repo.jobs["running"].Payload)
+	}
+	if _, err := service.UpdatePayload(ctx, "missing", models.StringPayload("fixed")); err != ErrJobNotFound {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobService_UpdatePayload_DedupWindow(t *testing.T) {
+	repo := newMockRepository()
+	service := NewJobService(repo, NewRateLimiter(100), metrics.NewMetrics())
+	ctx := context.Background()
+
+	original := &models.Job{ID: "original", TenantID: "tenant-1", Queue: models.DefaultQueue, Payload: "typo", PayloadEncoding: models.PayloadEncodingUTF8, Status: models.StatusPending, CreatedAt: time.Now().Add(-2 * time.Minute)}
+	original.DedupHash = dedupHash(original)
+	repo.jobs[original.ID] = original
+
+	if _, err := service.UpdatePayload(ctx, original.ID, models.StringPayload("fixed")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The patched job is found by its new payload, and no longer by its old one
+	job, created, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("fixed"), DedupWindow: "5m"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if created || job.ID != original.ID {
+		t.Errorf("expected the patched job for its new payload, got %s (created %t)", job.ID, created)
+	}
+	job, created, err = service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("typo"), DedupWindow: "5m"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !created || job.ID == original.ID {
+		t.Errorf("expected a new job for the old payload, got %s (created %t)", job.ID, created)
+	}
+}
+
+func TestJobService_CreateJob_Metadata(t *testing.T) {
+	service := NewJobService(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics())
+
+	tooMany := make(map[string]string, MaxMetadataKeys+1)
+	for i := 0; i <= MaxMetadataKeys; i++ {
+		tooMany[fmt.Sprintf("key-%d", i)] = "value"
+	}
+
+	for name, metadata := range map[string]map[string]string{
+		"too many":       tooMany,
+		"empty key":      {"": "web"},
+		"key too long":   {strings.Repeat("k", MaxMetadataKeyLength+1): "web"},
+		"value too long": {"source": strings.Repeat("v", MaxMetadataValueLength+1)},
+	} {
+		req := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("test"), Metadata: metadata}
+		if _, _, err := service.CreateJob(context.Background(), req); !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("%s: expected ErrInvalidMetadata, got %v", name, err)
+		}
+		if errs := service.ValidateCreateJobRequest(req); len(errs) == 0 || errs[0].Field != "metadata" {
+			t.Errorf("%s: expected a metadata field error, got %+v", name, errs)
+		}
+	}
+
+	req := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("test"), Metadata: map[string]string{"user_id": "42", "source": ""}}
+	job, _, err := service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job.Metadata["user_id"] != "42" {
+		t.Errorf("expected metadata on the job, got %v", job.Metadata)
+	}
+}
+
+func TestJobService_ValidateCreateJobRequest(t *testing.T) {
+	service := NewJobServiceWithConfig(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{MaxPayloadBytes: 4})
+
+	if errs := service.ValidateCreateJobRequest(&models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("ok")}); len(errs) != 0 {
+		t.Errorf("expected a valid request, got %+v", errs)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	errs := service.ValidateCreateJobRequest(&models.CreateJobRequest{Payload: models.StringPayload("too large"), Tags: []string{"", "a", "a"}, ExpiresAt: &expired})
+	var fields []string
+	for _, fieldErr := range errs {
+		fields = append(fields, fieldErr.Field)
+	}
+	want := []string{"tenant_id", "payload", "expires_at", "tags", "tags"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("expected errors for %v, got %+v", want, errs)
+	}
+}
+
+func TestJobService_CreateJob_ValidateJSONPayloads(t *testing.T) {
+	ctx := context.Background()
+	malformed := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload(`{"to": "a@example.com"`), ContentType: "application/json"}
+
+	// Validation is off by default
+	service := NewJobService(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics())
+	if _, _, err := service.CreateJob(ctx, malformed); err != nil {
+		t.Fatalf("expected the payload to be accepted without validation, got %v", err)
+	}
+
+	service = NewJobServiceWithConfig(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{ValidateJSONPayloads: true})
+	if _, _, err := service.CreateJob(ctx, malformed); !errors.Is(err, ErrMalformedJSON) {
+		t.Errorf("expected ErrMalformedJSON, got %v", err)
+	}
+	if errs := service.ValidateCreateJobRequest(malformed); len(errs) != 1 || errs[0].Field != "payload" || !strings.Contains(errs[0].Message, "offset") {
+		t.Errorf("expected a payload error with the parse position, got %+v", errs)
+	}
+
+	for _, req := range []*models.CreateJobRequest{
+		{TenantID: "tenant-1", Payload: models.StringPayload(`{"to": "a@example.com"}`), ContentType: "application/json; charset=utf-8"},
+		{TenantID: "tenant-1", Payload: models.StringPayload(`{"@id": "x"}`), ContentType: "application/ld+json"},
+		{TenantID: "tenant-1", Payload: models.StringPayload("{not json"), ContentType: "text/plain"},
+		{TenantID: "tenant-1", Payload: models.StringPayload("{not json")},
+		{TenantID: "tenant-1", Payload: json.RawMessage(`{"structured": true}`), ContentType: "application/json"},
+	} {
+		if _, _, err := service.CreateJob(ctx, req); err != nil {
+			t.Errorf("expected payload %s with content type %q to be accepted, got %v", req.Payload, req.ContentType, err)
+		}
+	}
+}
+
+func TestJobService_CreateJob_PayloadEncoding(t *testing.T) {
+	ctx := context.Background()
+	service := NewJobService(newMockRepository(), NewRateLimiter(10), metrics.NewMetrics())
+
+	job, _, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("AAEC/w=="), PayloadEncoding: "base64"})
+	if err != nil {
+		t.Fatalf("expected a base64 payload to be accepted, got %v", err)
+	}
+	if job.PayloadEncoding != models.PayloadEncodingBase64 || job.Payload != "AAEC/w==" {
+		t.Errorf("expected the base64 text to be stored as given, got %q (%s)", job.Payload, job.PayloadEncoding)
+	}
+
+	plain, _, err := service.CreateJob(ctx, &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("hello")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if plain.PayloadEncoding != models.PayloadEncodingUTF8 {
+		t.Errorf("expected payload_encoding to default to utf8, got %q", plain.PayloadEncoding)
+	}
+
+	for _, tc := range []struct {
+		req   *models.CreateJobRequest
+		err   error
+		field string
+	}{
+		{&models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("not base64!"), PayloadEncoding: "base64"}, ErrInvalidBase64, "payload"},
+		{&models.CreateJobRequest{TenantID: "tenant-1", Payload: json.RawMessage(`{"a":1}`), PayloadEncoding: "base64"}, ErrInvalidBase64, "payload"},
+		{&models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("hello"), PayloadEncoding: "hex"}, ErrInvalidEncoding, "payload_encoding"},
+	} {
+		if _, _, err := service.CreateJob(ctx, tc.req); !errors.Is(err, tc.err) {
+			t.Errorf("expected %v for payload %s (%s), got %v", tc.err, tc.req.Payload, tc.req.PayloadEncoding, err)
+		}
+		if errs := service.ValidateCreateJobRequest(tc.req); len(errs) != 1 || errs[0].Field != tc.field {
+			t.Errorf("expected one %s error for payload %s (%s), got %+v", tc.field, tc.req.Payload, tc.req.PayloadEncoding, errs)
+		}
+	}
+}
+
+func TestJobService_CreateJob_IdempotencyTTL(t *testing.T) {
+	repo := newMockRepository()
+	repo.idempotencyJob = &models.Job{ID: "old-job", TenantID: "tenant-1", IdempotencyKey: "key-1", CreatedAt: time.Now().Add(-48 * time.Hour)}
+
+	req := &models.CreateJobRequest{TenantID: "tenant-1", Payload: models.StringPayload("work"), IdempotencyKey: "key-1"}
+
+	// Without a TTL the key maps to its job forever
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+	job, created, err := service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if created || job.ID != "old-job" {
+		t.Errorf("expected the existing job, got %s (created=%v)", job.ID, created)
+	}
+
+	// Past the TTL the key is reused for a new job
+	service = NewJobServiceWithConfig(repo, NewRateLimiter(10), metrics.NewMetrics(), JobServiceConfig{IdempotencyTTL: 24 * time.Hour})
+	job, created, err = service.CreateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !created || job.ID == "old-job" {
+		t.Errorf("expected a new job once the key expired, got %s (created=%v)", job.ID, created)
+	}
+}
+
+func TestJobService_GetJobAttempts(t *testing.T) {
+	repo := newMockRepository()
+	at := time.Now()
+	repo.jobs["job-1"] = &models.Job{ID: "job-1", TenantID: "tenant-1", Status: models.StatusPending}
+	repo.attempts = map[string][]models.JobAttempt{"job-1": {{Attempt: 1, Reason: "timeout", At: at}}}
+	repo.dlqJobs = []*models.DeadLetterJob{{ID: "dlq-1", JobID: "job-2", TenantID: "tenant-2", Attempts: []models.JobAttempt{
+		{Attempt: 1, Reason: "first", At: at},
+		{Attempt: 2, Reason: "second", At: at},
+	}}}
+	service := NewJobService(repo, NewRateLimiter(10), metrics.NewMetrics())
+
+	tenantID, attempts, err := service.GetJobAttempts(context.Background(), "job-1")
+	if err != nil || tenantID != "tenant-1" || len(attempts) != 1 || attempts[0].Reason != "timeout" {
+		t.Errorf("expected the live job's attempt, got %s %+v (err %v)", tenantID, attempts, err)
+	}
+
+	tenantID, attempts, err = service.GetJobAttempts(context.Background(), "job-2")
+	if err != nil || tenantID != "tenant-2" || len(attempts) != 2 || attempts[1].Reason != "second" {
+		t.Errorf("expected the attempts from the DLQ entry, got %s %+v (err %v)", tenantID, attempts, err)
+	}
+
+	if _, _, err := service.GetJobAttempts(context.Background(), "missing"); err != ErrJobNotFound {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"job-queue/internal/repository"
+	"log"
+	"time"
+)
+
+// MetricsService builds metrics snapshots and keeps their periodic history
+type MetricsService struct {
+	repo      repository.JobRepository
+	snapshots repository.MetricsSnapshotRepository
+	metrics   *metrics.Metrics
+}
+
+// NewMetricsService creates a new metrics service
+func NewMetricsService(repo repository.JobRepository, snapshots repository.MetricsSnapshotRepository, metrics *metrics.Metrics) *MetricsService {
+	return &MetricsService{
+		repo:      repo,
+		snapshots: snapshots,
+		metrics:   metrics,
+	}
+}
+
+// Snapshot returns the current metrics counters
+func (s *MetricsService) Snapshot(ctx context.Context) map[string]int64 {
+	// Get actual counts from database (more accurate than in-memory metrics)
+	totalJobs, err := s.repo.GetTotalJobsCount(ctx)
+	if err != nil {
+		log.Printf("error getting total jobs count: %v", err)
+		totalJobs = 0
+	}
+
+	completedJobs, err := s.repo.GetCompletedJobsCount(ctx)
+	if err != nil {
+		log.Printf("error getting completed jobs count: %v", err)
+		completedJobs = 0
+	}
+
+	failedJobs, err := s.repo.GetFailedJobsCount(ctx)
+	if err != nil {
+		log.Printf("error getting failed jobs count: %v", err)
+		failedJobs = 0
+	}
+
+	// Together with completed_jobs this gives the share of jobs that ended in the DLQ
+	dlqJobs, err := s.repo.GetDeadLetterQueueCount(ctx)
+	if err != nil {
+		log.Printf("error getting dead letter jobs count: %v", err)
+		dlqJobs = 0
+	}
+
+	// Backlog depth and age are queried live so they survive restarts
+	pendingJobs, err := s.repo.CountJobsByStatus(ctx, models.StatusPending)
+	if err != nil {
+		log.Printf("error getting pending jobs count: %v", err)
+		pendingJobs = 0
+	}
+
+	// RUNNING is read live too, so the gauge is right after a restart and drops once
+	// orphaned jobs are reclaimed
+	runningJobs, err := s.repo.CountJobsByStatus(ctx, models.StatusRunning)
+	if err != nil {
+		log.Printf("error getting running jobs count: %v", err)
+		runningJobs = 0
+	}
+
+	oldestPending, err := s.repo.OldestPendingJobAge(ctx)
+	if err != nil {
+		log.Printf("error getting oldest pending job age: %v", err)
+		oldestPending = 0
+	}
+
+	// Percentiles size workers better than the single oldest job
+	pendingAges, err := s.repo.PendingJobAgePercentiles(ctx, []float64{0.5, 0.95})
+	if err != nil {
+		log.Printf("error getting pending job age percentiles: %v", err)
+		pendingAges = make([]time.Duration, 2)
+	}
+
+	// Get retried jobs from in-memory metrics (this is tracked separately)
+	inMemoryMetrics := s.metrics.GetSnapshot()
+	retriedJobs := inMemoryMetrics["retried_jobs"]
+
+	return map[string]int64{
+		"total_jobs":              int64(totalJobs),
+		"completed_jobs":          int64(completedJobs),
+		"failed_jobs":             int64(failedJobs),
+		"dlq_jobs":                int64(dlqJobs),
+		"retried_jobs":            retriedJobs,
+		"pending_jobs":            int64(pendingJobs),
+		"running_jobs":            int64(runningJobs),
+		"oldest_pending_seconds":  int64(oldestPending / time.Second),
+		"pending_age_p50_seconds": int64(pendingAges[0] / time.Second),
+		"pending_age_p95_seconds": int64(pendingAges[1] / time.Second),
+		// Writes this process could not make because the database was read-only or full
+		"db_write_errors": inMemoryMetrics["db_write_errors"],
+		// Liveness of this process; last_processed_at only counts jobs it processed itself
+		"start_time":        inMemoryMetrics["start_time"],
+		"uptime_seconds":    inMemoryMetrics["uptime_seconds"],
+		"last_processed_at": inMemoryMetrics["last_processed_at"],
+	}
+}
+
+// Reset zeroes the in-memory counters. Counts read from the database are unaffected.
+func (s *MetricsService) Reset() {
+	s.metrics.Reset()
+}
+
+// RecordSnapshot persists the current metrics counters
+func (s *MetricsService) RecordSnapshot(ctx context.Context) error {
+	snapshot := &models.MetricsSnapshot{
+		TakenAt:  time.Now(),
+		Counters: s.Snapshot(ctx),
+	}
+
+	if err := s.snapshots.RecordMetricsSnapshot(ctx, snapshot); err != nil {
+		return fmt.Errorf("failed to record metrics snapshot: %w", err)
+	}
+	return nil
+}
+
+// Run records a metrics snapshot on every tick until the context is cancelled
+func (s *MetricsService) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := s.RecordSnapshot(ctx); err != nil {
+				log.Printf("error recording metrics snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// ListSnapshots retrieves the stored metrics history
+func (s *MetricsService) ListSnapshots(ctx context.Context) ([]*models.MetricsSnapshot, error) {
+	snapshots, err := s.snapshots.ListMetricsSnapshots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metrics snapshots: %w", err)
+	}
+	return snapshots, nil
+}
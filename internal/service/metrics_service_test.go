@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMetricsService_Snapshot_Backlog(t *testing.T) {
+	repo := newMockRepository()
+	now := time.Now()
+	repo.jobs["job-1"] = &models.Job{ID: "job-1", Status: models.StatusPending, CreatedAt: now.Add(-90 * time.Second)}
+	repo.jobs["job-2"] = &models.Job{ID: "job-2", Status: models.StatusPending, CreatedAt: now.Add(-10 * time.Second)}
+	repo.jobs["job-3"] = &models.Job{ID: "job-3", Status: models.StatusRunning, CreatedAt: now.Add(-time.Hour)}
+
+	service := NewMetricsService(repo, nil, metrics.NewMetrics())
+	snapshot := service.Snapshot(context.Background())
+
+	if snapshot["pending_jobs"] != 2 {
+		t.Errorf("expected 2 pending jobs, got %d", snapshot["pending_jobs"])
+	}
+
+	if snapshot["running_jobs"] != 1 {
+		t.Errorf("expected 1 running job, got %d", snapshot["running_jobs"])
+	}
+
+	if age := snapshot["oldest_pending_seconds"]; age < 90 || age > 95 {
+		t.Errorf("expected oldest pending age of about 90s, got %d", age)
+	}
+
+	if p50 := snapshot["pending_age_p50_seconds"]; p50 < 10 || p50 > 15 {
+		t.Errorf("expected a median pending age of about 10s, got %d", p50)
+	}
+}
+
+func TestMetricsService_Snapshot_NoBacklog(t *testing.T) {
+	service := NewMetricsService(newMockRepository(), nil, metrics.NewMetrics())
+	snapshot := service.Snapshot(context.Background())
+
+	if snapshot["pending_jobs"] != 0 || snapshot["oldest_pending_seconds"] != 0 {
+		t.Errorf("expected an empty backlog, got %d pending and %ds oldest", snapshot["pending_jobs"], snapshot["oldest_pending_seconds"])
+	}
+
+	if snapshot["pending_age_p50_seconds"] != 0 || snapshot["pending_age_p95_seconds"] != 0 {
+		t.Errorf("expected zero pending age percentiles, got %d and %d", snapshot["pending_age_p50_seconds"], snapshot["pending_age_p95_seconds"])
+	}
+}
+
+func TestMetricsService_Snapshot_DLQJobs(t *testing.T) {
+	repo := newMockRepository()
+	repo.dlqJobs = append(repo.dlqJobs,
+		&models.DeadLetterJob{ID: "dlq-1", JobID: "job-1", FailureReason: "max retries exceeded: timeout"},
+		&models.DeadLetterJob{ID: "dlq-2", JobID: "job-2", FailureReason: "permanent failure: bad input"},
+	)
+
+	service := NewMetricsService(repo, nil, metrics.NewMetrics())
+	snapshot := service.Snapshot(context.Background())
+
+	if snapshot["dlq_jobs"] != 2 {
+		t.Errorf("expected 2 dlq jobs, got %d", snapshot["dlq_jobs"])
+	}
+}
@@ -2,20 +2,36 @@ package service
 
 import (
 	"context"
+	"log"
 	"sync"
 	"time"
 )
 
-// RateLimiter implements per-tenant rate limiting
+// RateLimiter implements per-tenant submission rate limiting.
+// The concurrent running limit is enforced by workers when they lease jobs.
 type RateLimiter struct {
 	mu sync.RWMutex
 
-	// Per-tenant concurrent running jobs limit
-	maxConcurrentRunning int
-
 	// Per-tenant submission rate limit
 	maxSubmissionsPerMinute int
 	submissionWindows       map[string]*submissionWindow
+
+	// Per-tenant overrides of the default limit
+	tenantLimits map[string]int
+}
+
+// RateLimitError is returned when a tenant exhausts its submission window.
+// It matches ErrRateLimitExceeded with errors.Is.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimitExceeded.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimitExceeded
 }
 
 type submissionWindow struct {
@@ -24,47 +40,98 @@ type submissionWindow struct {
 }
 
 // NewRateLimiter creates a new rate limiter
-func NewRateLimiter(maxConcurrentRunning, maxSubmissionsPerMinute int) *RateLimiter {
+func NewRateLimiter(maxSubmissionsPerMinute int) *RateLimiter {
 	return &RateLimiter{
-		maxConcurrentRunning:    maxConcurrentRunning,
 		maxSubmissionsPerMinute: maxSubmissionsPerMinute,
 		submissionWindows:       make(map[string]*submissionWindow),
+		tenantLimits:            make(map[string]int),
 	}
 }
 
-// CheckConcurrentLimit checks if a tenant can run more concurrent jobs
-func (rl *RateLimiter) CheckConcurrentLimit(ctx context.Context, tenantID string, currentRunning int) error {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
+// SetTenantLimit overrides the default submission limit for a single tenant
+func (rl *RateLimiter) SetTenantLimit(tenantID string, maxSubmissionsPerMinute int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 
-	if currentRunning >= rl.maxConcurrentRunning {
-		return ErrRateLimitExceeded
-	}
+	rl.tenantLimits[tenantID] = maxSubmissionsPerMinute
+}
 
-	return nil
+// limitFor returns the tenant's override if one is set, otherwise the default. Callers must hold rl.mu.
+func (rl *RateLimiter) limitFor(tenantID string) int {
+	if limit, ok := rl.tenantLimits[tenantID]; ok {
+		return limit
+	}
+	return rl.maxSubmissionsPerMinute
 }
 
 // CheckSubmissionRate checks if a tenant can submit more jobs
 func (rl *RateLimiter) CheckSubmissionRate(ctx context.Context, tenantID string) error {
+	return rl.CheckSubmissionRateN(ctx, tenantID, 1)
+}
+
+// CheckSubmissionRateN checks if a tenant can submit n more jobs at once.
+// Either all n submissions are counted against the window or none are.
+// When the window is full the returned *RateLimitError says how long until it resets.
+func (rl *RateLimiter) CheckSubmissionRateN(ctx context.Context, tenantID string, n int) error {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
+	maxSubmissions := rl.limitFor(tenantID)
+	if n > maxSubmissions {
+		return ErrRateLimitExceeded
+	}
+
 	now := time.Now()
 	window, exists := rl.submissionWindows[tenantID]
 
 	if !exists || now.After(window.windowEnd) {
 		// New window or expired window
 		rl.submissionWindows[tenantID] = &submissionWindow{
-			count:     1,
+			count:     n,
 			windowEnd: now.Add(1 * time.Minute),
 		}
 		return nil
 	}
 
-	if window.count >= rl.maxSubmissionsPerMinute {
-		return ErrRateLimitExceeded
+	if window.count+n > maxSubmissions {
+		return &RateLimitError{RetryAfter: window.windowEnd.Sub(now)}
 	}
 
-	window.count++
+	window.count += n
 	return nil
 }
+
+// RunSweeper removes idle tenant windows on every tick until the context is cancelled
+func (rl *RateLimiter) RunSweeper(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if removed := rl.SweepIdleWindows(); removed > 0 {
+				log.Printf("rate limiter: removed %d idle tenant windows", removed)
+			}
+		}
+	}
+}
+
+// SweepIdleWindows removes the windows of tenants whose window has ended and returns how many
+// were removed. An ended window is replaced on the tenant's next submission anyway, so removing
+// it does not change any limit.
+func (rl *RateLimiter) SweepIdleWindows() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for tenantID, window := range rl.submissionWindows {
+		if now.After(window.windowEnd) {
+			delete(rl.submissionWindows, tenantID)
+			removed++
+		}
+	}
+	return removed
+}
@@ -2,12 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
 
 func TestRateLimiter_CheckSubmissionRate_WithinLimit(t *testing.T) {
-	rl := NewRateLimiter(5, 10)
+	rl := NewRateLimiter(10)
 
 	err := rl.CheckSubmissionRate(context.Background(), "tenant-1")
 	if err != nil {
@@ -16,7 +17,7 @@ func TestRateLimiter_CheckSubmissionRate_WithinLimit(t *testing.T) {
 }
 
 func TestRateLimiter_CheckSubmissionRate_ExceedsLimit(t *testing.T) {
-	rl := NewRateLimiter(5, 2) // Max 2 per minute
+	rl := NewRateLimiter(2) // Max 2 per minute
 
 	// Submit 2 jobs - should succeed
 	for i := 0; i < 2; i++ {
@@ -28,13 +29,13 @@ func TestRateLimiter_CheckSubmissionRate_ExceedsLimit(t *testing.T) {
 
 	// Third submission should fail
 	err := rl.CheckSubmissionRate(context.Background(), "tenant-1")
-	if err != ErrRateLimitExceeded {
+	if !errors.Is(err, ErrRateLimitExceeded) {
 		t.Errorf("expected rate limit error, got %v", err)
 	}
 }
 
 func TestRateLimiter_CheckSubmissionRate_WindowExpiry(t *testing.T) {
-	rl := NewRateLimiter(5, 2)
+	rl := NewRateLimiter(2)
 
 	// Exhaust limit
 	rl.CheckSubmissionRate(context.Background(), "tenant-1")
@@ -42,7 +43,7 @@ func TestRateLimiter_CheckSubmissionRate_WindowExpiry(t *testing.T) {
 
 	// Should be rate limited
 	err := rl.CheckSubmissionRate(context.Background(), "tenant-1")
-	if err != ErrRateLimitExceeded {
+	if !errors.Is(err, ErrRateLimitExceeded) {
 		t.Errorf("expected rate limit error, got %v", err)
 	}
 
@@ -60,49 +61,114 @@ func TestRateLimiter_CheckSubmissionRate_WindowExpiry(t *testing.T) {
 	}
 }
 
-func TestRateLimiter_CheckConcurrentLimit_WithinLimit(t *testing.T) {
-	rl := NewRateLimiter(5, 10)
+func TestRateLimiter_MultipleTenants(t *testing.T) {
+	rl := NewRateLimiter(2)
 
-	err := rl.CheckConcurrentLimit(context.Background(), "tenant-1", 3)
+	// Tenant 1 exhausts limit
+	rl.CheckSubmissionRate(context.Background(), "tenant-1")
+	rl.CheckSubmissionRate(context.Background(), "tenant-1")
+
+	// Tenant 2 should still be able to submit
+	err := rl.CheckSubmissionRate(context.Background(), "tenant-2")
 	if err != nil {
-		t.Errorf("expected no error, got %v", err)
+		t.Errorf("expected no error for tenant-2, got %v", err)
+	}
+
+	// Tenant 1 should be rate limited
+	err = rl.CheckSubmissionRate(context.Background(), "tenant-1")
+	if !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("expected rate limit error for tenant-1, got %v", err)
 	}
 }
 
-func TestRateLimiter_CheckConcurrentLimit_AtLimit(t *testing.T) {
-	rl := NewRateLimiter(5, 10)
+func TestRateLimiter_CheckSubmissionRateN(t *testing.T) {
+	rl := NewRateLimiter(5)
+
+	if err := rl.CheckSubmissionRateN(context.Background(), "tenant-1", 3); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
-	err := rl.CheckConcurrentLimit(context.Background(), "tenant-1", 5)
-	if err != ErrRateLimitExceeded {
+	// 3 + 3 exceeds the limit and should not consume any of the window
+	if err := rl.CheckSubmissionRateN(context.Background(), "tenant-1", 3); !errors.Is(err, ErrRateLimitExceeded) {
 		t.Errorf("expected rate limit error, got %v", err)
 	}
+
+	if err := rl.CheckSubmissionRateN(context.Background(), "tenant-1", 2); err != nil {
+		t.Errorf("expected remaining capacity of 2, got %v", err)
+	}
 }
 
-func TestRateLimiter_CheckConcurrentLimit_ExceedsLimit(t *testing.T) {
-	rl := NewRateLimiter(5, 10)
+func TestRateLimiter_SetTenantLimit(t *testing.T) {
+	rl := NewRateLimiter(2)
+	rl.SetTenantLimit("premium", 5)
+	ctx := context.Background()
 
-	err := rl.CheckConcurrentLimit(context.Background(), "tenant-1", 6)
-	if err != ErrRateLimitExceeded {
-		t.Errorf("expected rate limit error, got %v", err)
+	for i := 0; i < 5; i++ {
+		if err := rl.CheckSubmissionRate(ctx, "premium"); err != nil {
+			t.Fatalf("submission %d: expected premium tenant to be within its limit, got %v", i+1, err)
+		}
+	}
+	if err := rl.CheckSubmissionRate(ctx, "premium"); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("expected premium tenant to hit its own limit, got %v", err)
+	}
+
+	// Tenants without an override keep the default
+	for i := 0; i < 2; i++ {
+		rl.CheckSubmissionRate(ctx, "basic")
+	}
+	if err := rl.CheckSubmissionRate(ctx, "basic"); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("expected default submission limit for basic tenant, got %v", err)
 	}
 }
 
-func TestRateLimiter_MultipleTenants(t *testing.T) {
-	rl := NewRateLimiter(5, 2)
+func TestRateLimiter_CheckSubmissionRate_RetryAfter(t *testing.T) {
+	rl := NewRateLimiter(1)
+	ctx := context.Background()
 
-	// Tenant 1 exhausts limit
-	rl.CheckSubmissionRate(context.Background(), "tenant-1")
-	rl.CheckSubmissionRate(context.Background(), "tenant-1")
+	if err := rl.CheckSubmissionRate(ctx, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
-	// Tenant 2 should still be able to submit
-	err := rl.CheckSubmissionRate(context.Background(), "tenant-2")
-	if err != nil {
-		t.Errorf("expected no error for tenant-2, got %v", err)
+	err := rl.CheckSubmissionRate(ctx, "tenant-1")
+
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected RateLimitError, got %v", err)
 	}
+	if limitErr.RetryAfter <= 0 || limitErr.RetryAfter > time.Minute {
+		t.Errorf("expected retry after within the 1 minute window, got %s", limitErr.RetryAfter)
+	}
+}
 
-	// Tenant 1 should be rate limited
-	err = rl.CheckSubmissionRate(context.Background(), "tenant-1")
-	if err != ErrRateLimitExceeded {
-		t.Errorf("expected rate limit error for tenant-1, got %v", err)
+func TestRateLimiter_SweepIdleWindows(t *testing.T) {
+	rl := NewRateLimiter(2)
+	ctx := context.Background()
+
+	rl.CheckSubmissionRate(ctx, "idle")
+	rl.CheckSubmissionRate(ctx, "active")
+	rl.CheckSubmissionRate(ctx, "active")
+
+	rl.mu.Lock()
+	rl.submissionWindows["idle"].windowEnd = time.Now().Add(-time.Minute)
+	rl.mu.Unlock()
+
+	if removed := rl.SweepIdleWindows(); removed != 1 {
+		t.Errorf("expected 1 window removed, got %d", removed)
+	}
+
+	rl.mu.RLock()
+	_, idleKept := rl.submissionWindows["idle"]
+	_, activeKept := rl.submissionWindows["active"]
+	rl.mu.RUnlock()
+	if idleKept || !activeKept {
+		t.Errorf("expected only the idle window to be removed, idle kept=%t active kept=%t", idleKept, activeKept)
+	}
+
+	// The active tenant's window still counts its submissions
+	if err := rl.CheckSubmissionRate(ctx, "active"); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("expected the active tenant to stay rate limited, got %v", err)
+	}
+	if err := rl.CheckSubmissionRate(ctx, "idle"); err != nil {
+		t.Errorf("expected the idle tenant to start a new window, got %v", err)
 	}
 }
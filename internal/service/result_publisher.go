@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"job-queue/internal/models"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultPublishTimeout is how long a BufferedPublisher blocks a worker on a full buffer
+const DefaultPublishTimeout = 5 * time.Second
+
+// ErrPublishTimeout is returned when a publisher's buffer stayed full for the whole publish timeout
+var ErrPublishTimeout = errors.New("result publisher buffer is full")
+
+// ResultPublisher sends the outcome of finished jobs somewhere else, such as a Kafka topic
+// or a NATS subject. Publish may block; the worker waits for it before taking more work.
+type ResultPublisher interface {
+	Publish(ctx context.Context, outcome models.JobOutcome) error
+}
+
+// BufferedPublisher queues outcomes for another publisher and delivers them, in order, from
+// a background goroutine, so a slow broker only holds up workers once the buffer is full.
+// Publish then blocks for up to the timeout instead of dropping the outcome.
+type BufferedPublisher struct {
+	next    ResultPublisher
+	timeout time.Duration
+	buffer  chan models.JobOutcome
+	done    chan struct{}
+}
+
+// NewBufferedPublisher starts delivering to next with room for size outcomes. A size of
+// zero or less makes every Publish wait for next, and a timeout of zero or less uses
+// DefaultPublishTimeout.
+func NewBufferedPublisher(next ResultPublisher, size int, timeout time.Duration) *BufferedPublisher {
+	if size < 0 {
+		size = 0
+	}
+	if timeout <= 0 {
+		timeout = DefaultPublishTimeout
+	}
+
+	p := &BufferedPublisher{
+		next:    next,
+		timeout: timeout,
+		buffer:  make(chan models.JobOutcome, size),
+		done:    make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues an outcome, blocking while the buffer is full until there is room, the
+// timeout passes (ErrPublishTimeout) or ctx is done
+func (p *BufferedPublisher) Publish(ctx context.Context, outcome models.JobOutcome) error {
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	select {
+	case p.buffer <- outcome:
+		return nil
+	case <-timer.C:
+		return ErrPublishTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run delivers queued outcomes until Close
+func (p *BufferedPublisher) run() {
+	defer close(p.done)
+	for outcome := range p.buffer {
+		if err := p.next.Publish(context.Background(), outcome); err != nil {
+			log.Printf("job_id=%s: error delivering outcome: %v", outcome.JobID, err)
+		}
+	}
+}
+
+// Close waits until every queued outcome is delivered. It must be called once no more
+// outcomes are published, after the worker has stopped.
+func (p *BufferedPublisher) Close() {
+	close(p.buffer)
+	<-p.done
+}
+
+// JSONLinesPublisher writes each outcome as a line of JSON. It stands in for a broker
+// client, such as Kafka or NATS, and shows the shape of the messages one would send.
+type JSONLinesPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesPublisher creates a publisher writing to w
+func NewJSONLinesPublisher(w io.Writer) *JSONLinesPublisher {
+	return &JSONLinesPublisher{w: w}
+}
+
+// Publish writes the outcome followed by a newline
+func (p *JSONLinesPublisher) Publish(ctx context.Context, outcome models.JobOutcome) error {
+	line, err := json.Marshal(outcome)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
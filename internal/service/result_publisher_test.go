@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingPublisher keeps every outcome it is given, first waiting on release if it is set
+type recordingPublisher struct {
+	mu       sync.Mutex
+	release  chan struct{}
+	outcomes []models.JobOutcome
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, outcome models.JobOutcome) error {
+	if p.release != nil {
+		<-p.release
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outcomes = append(p.outcomes, outcome)
+	return nil
+}
+
+func (p *recordingPublisher) jobIDs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ids []string
+	for _, outcome := range p.outcomes {
+		ids = append(ids, outcome.JobID)
+	}
+	return ids
+}
+
+func TestBufferedPublisher_Backpressure(t *testing.T) {
+	next := &recordingPublisher{release: make(chan struct{})}
+	publisher := NewBufferedPublisher(next, 1, 20*time.Millisecond)
+	ctx := context.Background()
+
+	// The first outcome is taken by the delivery goroutine and the second fills the buffer
+	if err := publisher.Publish(ctx, models.JobOutcome{JobID: "job-1"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(publisher.buffer) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := publisher.Publish(ctx, models.JobOutcome{JobID: "job-2"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// A full buffer blocks until the timeout rather than dropping the outcome silently
+	start := time.Now()
+	if err := publisher.Publish(ctx, models.JobOutcome{JobID: "job-3"}); !errors.Is(err, ErrPublishTimeout) {
+		t.Fatalf("expected ErrPublishTimeout, got %v", err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("expected Publish to block for the timeout, it returned after %s", waited)
+	}
+
+	// Once the broker catches up, a blocked Publish goes through
+	published := make(chan error, 1)
+	go func() {
+		published <- publisher.Publish(ctx, models.JobOutcome{JobID: "job-4"})
+	}()
+	close(next.release)
+	if err := <-published; err != nil {
+		t.Fatalf("expected no error once there is room, got %v", err)
+	}
+
+	publisher.Close()
+	if ids := next.jobIDs(); len(ids) != 3 || ids[0] != "job-1" || ids[1] != "job-2" || ids[2] != "job-4" {
+		t.Errorf("expected job-1, job-2 and job-4 delivered in order, got %v", ids)
+	}
+}
+
+func TestJSONLinesPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewJSONLinesPublisher(&buf)
+
+	if err := publisher.Publish(context.Background(), models.JobOutcome{JobID: "job-1", Status: models.StatusDone, Result: "ok"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var outcome models.JobOutcome
+	if err := json.Unmarshal(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), &outcome); err != nil {
+		t.Fatalf("expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if outcome.JobID != "job-1" || outcome.Status != models.StatusDone || outcome.Result != "ok" {
+		t.Errorf("unexpected outcome %+v", outcome)
+	}
+}
+
+func TestWorkerService_PublishesOutcomes(t *testing.T) {
+	repo := newMockWorkerRepository()
+	publisher := &recordingPublisher{}
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			if job.Payload == "bad" {
+				return "", errors.New("exited with code 3")
+			}
+			return "processed " + job.Payload, nil
+		}),
+		Publisher: publisher,
+	})
+
+	ok := &models.Job{ID: "job-1", TenantID: "tenant-1", Payload: "good", Status: models.StatusRunning, MaxRetries: 3}
+	bad := &models.Job{ID: "job-2", TenantID: "tenant-1", Payload: "bad", Status: models.StatusRunning, MaxRetries: 1}
+	repo.jobs[ok.ID] = ok
+	repo.jobs[bad.ID] = bad
+
+	service.processJob(context.Background(), ok)
+	// The first failure is retried, which is not an outcome
+	service.processJob(context.Background(), bad)
+	if len(publisher.outcomes) != 1 {
+		t.Fatalf("expected only the completed job to be published, got %+v", publisher.outcomes)
+	}
+	bad.Status = models.StatusRunning
+	service.processJob(context.Background(), bad)
+
+	if len(publisher.outcomes) != 2 {
+		t.Fatalf("expected 2 outcomes, got %+v", publisher.outcomes)
+	}
+	done, failed := publisher.outcomes[0], publisher.outcomes[1]
+	if done.JobID != ok.ID || done.Status != models.StatusDone || done.Result != "processed good" || done.TenantID != "tenant-1" {
+		t.Errorf("unexpected DONE outcome %+v", done)
+	}
+	if failed.JobID != bad.ID || failed.Status != models.StatusFailed || failed.FailureReason != "max retries exceeded: exited with code 3" {
+		t.Errorf("unexpected FAILED outcome %+v", failed)
+	}
+}
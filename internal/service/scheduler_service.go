@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"job-queue/internal/repository"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	ErrInvalidCronExpr = errors.New("invalid cron expression")
+)
+
+// SchedulerService handles recurring schedules and fires their jobs
+type SchedulerService struct {
+	repo    repository.ScheduleRepository
+	metrics *metrics.Metrics
+}
+
+// NewSchedulerService creates a new scheduler service
+func NewSchedulerService(repo repository.ScheduleRepository, metrics *metrics.Metrics) *SchedulerService {
+	return &SchedulerService{
+		repo:    repo,
+		metrics: metrics,
+	}
+}
+
+// CreateSchedule validates the cron expression and creates a new schedule
+func (s *SchedulerService) CreateSchedule(ctx context.Context, req *models.CreateScheduleRequest) (*models.Schedule, error) {
+	sched, err := cron.ParseStandard(req.CronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCronExpr, err)
+	}
+
+	maxRetries := 3
+	if req.MaxRetries != nil {
+		maxRetries = *req.MaxRetries
+	}
+
+	schedule := &models.Schedule{
+		ID:         uuid.New().String(),
+		TenantID:   req.TenantID,
+		CronExpr:   req.CronExpr,
+		Payload:    req.Payload,
+		MaxRetries: maxRetries,
+		NextFireAt: sched.Next(time.Now()),
+	}
+
+	if err := s.repo.CreateSchedule(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	log.Printf("schedule_id=%s: schedule created, tenant_id=%s, cron=%q, next_fire_at=%s",
+		schedule.ID, schedule.TenantID, schedule.CronExpr, schedule.NextFireAt.Format(time.RFC3339))
+
+	return schedule, nil
+}
+
+// ListSchedules retrieves all schedules, or only tenantID's when it is not empty
+func (s *SchedulerService) ListSchedules(ctx context.Context, tenantID string) ([]*models.Schedule, error) {
+	schedules, err := s.repo.ListSchedules(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// Run fires due schedules on every tick until the context is cancelled
+func (s *SchedulerService) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := s.FireDueSchedules(ctx); err != nil {
+				log.Printf("error firing schedules: %v", err)
+			}
+		}
+	}
+}
+
+// FireDueSchedules creates a PENDING job for every schedule that is due and returns how many fired
+func (s *SchedulerService) FireDueSchedules(ctx context.Context) (int, error) {
+	now := time.Now()
+	schedules, err := s.repo.ListDueSchedules(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due schedules: %w", err)
+	}
+
+	fired := 0
+	for _, schedule := range schedules {
+		sched, err := cron.ParseStandard(schedule.CronExpr)
+		if err != nil {
+			log.Printf("schedule_id=%s: invalid cron expression %q: %v", schedule.ID, schedule.CronExpr, err)
+			continue
+		}
+
+		job := &models.Job{
+			ID:         uuid.New().String(),
+			TenantID:   schedule.TenantID,
+			Queue:      models.DefaultQueue,
+			Payload:    schedule.Payload,
+			Status:     models.StatusPending,
+			MaxRetries: schedule.MaxRetries,
+			RetryCount: 0,
+		}
+
+		// Missed occurrences are skipped rather than replayed
+		ok, err := s.repo.FireSchedule(ctx, schedule, job, sched.Next(now))
+		if err != nil {
+			log.Printf("schedule_id=%s: error firing schedule: %v", schedule.ID, err)
+			continue
+		}
+		if !ok {
+			// Another worker already fired this occurrence
+			continue
+		}
+
+		fired++
+		s.metrics.IncrementTotalJobs()
+		log.Printf("job_id=%s: job submitted by schedule_id=%s, tenant_id=%s, payload=%s", job.ID, schedule.ID, job.TenantID, job.Payload)
+	}
+
+	return fired, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"job-queue/internal/metrics"
+	"job-queue/internal/models"
+	"testing"
+	"time"
+)
+
+// mockScheduleRepository is a mock implementation of ScheduleRepository
+type mockScheduleRepository struct {
+	schedules []*models.Schedule
+	jobs      []*models.Job
+	fireOK    bool
+}
+
+func newMockScheduleRepository() *mockScheduleRepository {
+	return &mockScheduleRepository{fireOK: true}
+}
+
+func (m *mockScheduleRepository) CreateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	m.schedules = append(m.schedules, schedule)
+	return nil
+}
+
+func (m *mockScheduleRepository) ListSchedules(ctx context.Context, tenantID string) ([]*models.Schedule, error) {
+	var schedules []*models.Schedule
+	for _, schedule := range m.schedules {
+		if tenantID == "" || schedule.TenantID == tenantID {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+func (m *mockScheduleRepository) ListDueSchedules(ctx context.Context, now time.Time) ([]*models.Schedule, error) {
+	var due []*models.Schedule
+	for _, schedule := range m.schedules {
+		if !schedule.NextFireAt.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockScheduleRepository) FireSchedule(ctx context.Context, schedule *models.Schedule, job *models.Job, nextFireAt time.Time) (bool, error) {
+	if !m.fireOK {
+		return false, nil
+	}
+	schedule.NextFireAt = nextFireAt
+	m.jobs = append(m.jobs, job)
+	return true, nil
+}
+
+func TestSchedulerService_CreateSchedule_Success(t *testing.T) {
+	repo := newMockScheduleRepository()
+	service := NewSchedulerService(repo, metrics.NewMetrics())
+
+	req := &models.CreateScheduleRequest{
+		TenantID: "tenant-1",
+		CronExpr: "*/5 * * * *",
+		Payload:  "nightly report",
+	}
+
+	schedule, err := service.CreateSchedule(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if schedule.MaxRetries != 3 {
+		t.Errorf("expected max_retries 3, got %d", schedule.MaxRetries)
+	}
+
+	if !schedule.NextFireAt.After(time.Now()) {
+		t.Errorf("expected next_fire_at in the future, got %s", schedule.NextFireAt)
+	}
+
+	if schedule.NextFireAt.Minute()%5 != 0 {
+		t.Errorf("expected next_fire_at on a 5 minute boundary, got %s", schedule.NextFireAt)
+	}
+}
+
+func TestSchedulerService_CreateSchedule_InvalidCron(t *testing.T) {
+	repo := newMockScheduleRepository()
+	service := NewSchedulerService(repo, metrics.NewMetrics())
+
+	req := &models.CreateScheduleRequest{
+		TenantID: "tenant-1",
+		CronExpr: "not a cron",
+		Payload:  "nightly report",
+	}
+
+	_, err := service.CreateSchedule(context.Background(), req)
+	if !errors.Is(err, ErrInvalidCronExpr) {
+		t.Errorf("expected ErrInvalidCronExpr, got %v", err)
+	}
+
+	if len(repo.schedules) != 0 {
+		t.Errorf("expected no schedule to be stored, got %d", len(repo.schedules))
+	}
+}
+
+func TestSchedulerService_FireDueSchedules(t *testing.T) {
+	repo := newMockScheduleRepository()
+	due := &models.Schedule{
+		ID:         "schedule-1",
+		TenantID:   "tenant-1",
+		CronExpr:   "@hourly",
+		Payload:    "hourly",
+		MaxRetries: 2,
+		NextFireAt: time.Now().Add(-1 * time.Minute),
+	}
+	notDue := &models.Schedule{
+		ID:         "schedule-2",
+		TenantID:   "tenant-2",
+		CronExpr:   "@hourly",
+		Payload:    "later",
+		NextFireAt: time.Now().Add(1 * time.Hour),
+	}
+	repo.schedules = []*models.Schedule{due, notDue}
+
+	m := metrics.NewMetrics()
+	service := NewSchedulerService(repo, m)
+
+	fired, err := service.FireDueSchedules(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if fired != 1 {
+		t.Fatalf("expected 1 schedule to fire, got %d", fired)
+	}
+
+	job := repo.jobs[0]
+	if job.TenantID != "tenant-1" || job.Payload != "hourly" || job.MaxRetries != 2 {
+		t.Errorf("expected job from schedule template, got %+v", job)
+	}
+
+	if job.Status != models.StatusPending {
+		t.Errorf("expected status PENDING, got %s", job.Status)
+	}
+
+	if !due.NextFireAt.After(time.Now()) {
+		t.Errorf("expected next_fire_at to advance, got %s", due.NextFireAt)
+	}
+
+	if m.GetSnapshot()["total_jobs"] != 1 {
+		t.Errorf("expected total_jobs 1, got %d", m.GetSnapshot()["total_jobs"])
+	}
+}
+
+func TestSchedulerService_FireDueSchedules_AlreadyFired(t *testing.T) {
+	repo := newMockScheduleRepository()
+	repo.fireOK = false
+	repo.schedules = []*models.Schedule{{
+		ID:         "schedule-1",
+		TenantID:   "tenant-1",
+		CronExpr:   "@hourly",
+		Payload:    "hourly",
+		NextFireAt: time.Now().Add(-1 * time.Minute),
+	}}
+
+	service := NewSchedulerService(repo, metrics.NewMetrics())
+
+	fired, err := service.FireDueSchedules(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if fired != 0 {
+		t.Errorf("expected 0 schedules to fire, got %d", fired)
+	}
+}
@@ -2,103 +2,567 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"job-queue/internal/metrics"
 	"job-queue/internal/models"
 	"job-queue/internal/repository"
 	"log"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// DefaultLeaseDuration is how long a leased job is held before another worker may reclaim it
+	DefaultLeaseDuration = 30 * time.Second
+	// DefaultPollInterval is how long the worker waits before polling again when no job is available
+	DefaultPollInterval = 1 * time.Second
+	// DefaultMaxRunningPerTenant is the number of jobs a tenant may have RUNNING at once
+	DefaultMaxRunningPerTenant = 5
+	// MaxWriteBackoff caps how long the worker waits between leases while the database is
+	// read-only or its disk is full
+	MaxWriteBackoff = time.Minute
+)
+
+// WorkerConfig holds the tunable settings of a worker
+type WorkerConfig struct {
+	Queue         string
+	LeaseDuration time.Duration
+	PollInterval  time.Duration
+
+	// MaxRunningPerTenant caps each tenant's RUNNING jobs across all workers; zero means unlimited
+	MaxRunningPerTenant int
+	// TenantMaxRunning overrides MaxRunningPerTenant for individual tenants
+	TenantMaxRunning map[string]int
+	// FairScheduling round-robins leases across tenants with pending work instead of
+	// leasing strictly in submission order
+	FairScheduling bool
+
+	// Concurrency is how many jobs the worker processes at once; defaults to 1. Free
+	// slots are filled by leasing that many jobs in a single transaction.
+	Concurrency int
+	// MaxConcurrentHandlers caps how many handler calls run at once across the worker's
+	// goroutines, to protect a downstream system; zero means no cap beyond Concurrency.
+	// Unlike MaxRunningPerTenant it is local to this worker and ignores tenants. Leased
+	// jobs waiting for a handler slot use up their JobTimeout while they wait.
+	MaxConcurrentHandlers int
+
+	// Handler processes leased jobs; defaults to a NoopHandler
+	Handler Handler
+	// JobTimeout bounds each attempt; defaults to the lease duration so a job is not
+	// still running when another worker may reclaim it
+	JobTimeout time.Duration
+	// LeaseTimeout bounds each lease attempt, on top of PollInterval when long polling, so
+	// a lease stuck on the database is abandoned and retried on the next poll instead of
+	// stalling the worker; defaults to the lease duration
+	LeaseTimeout time.Duration
+
+	// WorkerID is recorded as leased_by on the jobs this worker leases; defaults to hostname:pid
+	WorkerID string
+	// TenantID makes this a dedicated worker that only leases the given tenant's jobs;
+	// empty serves every tenant
+	TenantID string
+	// LongPoll makes an empty lease wait up to PollInterval for a job to become leasable
+	// in this process, such as a scheduled job or a retry, and lease it at once instead of
+	// sleeping. Jobs added by other processes are still picked up by polling.
+	LongPoll bool
+	// Publisher receives every job this worker finishes for good, once its status is
+	// stored; nil publishes nothing. The job's slot is held until Publish returns, so a
+	// slow publisher slows the worker down instead of losing outcomes.
+	Publisher ResultPublisher
+}
+
+// withDefaults fills unset fields with their default values
+func (c WorkerConfig) withDefaults() WorkerConfig {
+	if c.Queue == "" {
+		c.Queue = models.DefaultQueue
+	}
+	if c.LeaseDuration <= 0 {
+		c.LeaseDuration = DefaultLeaseDuration
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	if c.Handler == nil {
+		c.Handler = NoopHandler{}
+	}
+	if c.JobTimeout <= 0 {
+		c.JobTimeout = c.LeaseDuration
+	}
+	if c.LeaseTimeout <= 0 {
+		c.LeaseTimeout = c.LeaseDuration
+	}
+	if c.WorkerID == "" {
+		c.WorkerID = defaultWorkerID()
+	}
+	return c
+}
+
+// defaultWorkerID identifies the worker by its host and process
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
 // WorkerService handles worker operations
 type WorkerService struct {
 	repo    repository.JobRepository
 	metrics *metrics.Metrics
+	events  *EventBus
+	cancels *CancelRegistry
+	config  WorkerConfig
+
+	// handlerSlots holds a token per running handler call when MaxConcurrentHandlers is set
+	handlerSlots chan struct{}
+
+	// active tracks the jobs being processed, for Status
+	active *ActiveJobs
+
+	drainOnce sync.Once
+	drain     chan struct{}
+
+	// writeFailures counts writes in a row that failed because the database was read-only
+	// or full, doubling the wait between leases until one succeeds
+	writeFailures atomic.Int32
 }
 
-// NewWorkerService creates a new worker service
+// NewWorkerService creates a new worker service with the default configuration
 func NewWorkerService(repo repository.JobRepository, metrics *metrics.Metrics) *WorkerService {
-	return &WorkerService{
+	return NewWorkerServiceWithConfig(repo, metrics, WorkerConfig{})
+}
+
+// NewWorkerServiceWithConfig creates a new worker service with the given configuration
+func NewWorkerServiceWithConfig(repo repository.JobRepository, metrics *metrics.Metrics, config WorkerConfig) *WorkerService {
+	s := &WorkerService{
 		repo:    repo,
 		metrics: metrics,
+		config:  config.withDefaults(),
+		drain:   make(chan struct{}),
+		active:  NewActiveJobs(),
 	}
+	if s.config.MaxConcurrentHandlers > 0 {
+		s.handlerSlots = make(chan struct{}, s.config.MaxConcurrentHandlers)
+	}
+	return s
+}
+
+// Status reports the worker's identity and the jobs it is processing, longest running first
+func (s *WorkerService) Status() models.WorkerStatus {
+	return models.WorkerStatus{
+		WorkerID:   s.config.WorkerID,
+		Queue:      s.config.Queue,
+		Draining:   s.Draining(),
+		ActiveJobs: s.active.List(time.Now()),
+	}
+}
+
+// SetEventBus sets the bus that job status transitions are published to
+func (s *WorkerService) SetEventBus(events *EventBus) {
+	s.events = events
+}
+
+// SetCancelRegistry sets the registry through which cancelling a job stops its running handler
+func (s *WorkerService) SetCancelRegistry(cancels *CancelRegistry) {
+	s.cancels = cancels
 }
 
-// ProcessJobs continuously processes jobs
-func (s *WorkerService) ProcessJobs(ctx context.Context, leaseDuration time.Duration) error {
+// publishStatus publishes a job status transition
+func (s *WorkerService) publishStatus(jobID string, status models.JobStatus) {
+	s.events.Publish(models.JobEvent{JobID: jobID, Status: status, At: time.Now()})
+}
+
+// Drain puts the worker into the DRAINING state: ProcessJobs stops leasing new jobs and lets
+// the jobs in progress finish, but keeps running until its context is cancelled
+func (s *WorkerService) Drain() {
+	s.drainOnce.Do(func() { close(s.drain) })
+}
+
+// Draining reports whether Drain has been called
+func (s *WorkerService) Draining() bool {
+	select {
+	case <-s.drain:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProcessJobs continuously processes jobs from the configured queue. Whenever processing
+// slots are free it leases up to that many jobs at once and runs each in its own goroutine.
+// It returns once the context is cancelled and every job in progress has finished.
+func (s *WorkerService) ProcessJobs(ctx context.Context) error {
+	slots := make(chan struct{}, s.config.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
 	for {
+		if s.Draining() {
+			return s.waitDrained(ctx, &wg)
+		}
+
+		// Wait for a free slot, then claim every other free slot as well
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		default:
-			job, err := s.repo.LeaseJob(ctx, leaseDuration)
-			if err != nil {
-				log.Printf("error leasing job: %v", err)
-				time.Sleep(1 * time.Second)
-				continue
+		case <-s.drain:
+			return s.waitDrained(ctx, &wg)
+		case slots <- struct{}{}:
+		}
+		free := 1
+	claim:
+		for free < s.config.Concurrency {
+			select {
+			case slots <- struct{}{}:
+				free++
+			default:
+				break claim
+			}
+		}
+
+		jobs, err := s.leaseJobs(ctx, free)
+		s.noteWrite(err)
+		if err != nil {
+			// A long-poll lease is interrupted by shutdown, which is not a lease error
+			if ctx.Err() == nil {
+				s.metrics.IncrementLeaseErrors()
+				log.Printf("error leasing jobs: %v", err)
 			}
+		} else if len(jobs) == 0 {
+			// No jobs available
+			s.metrics.IncrementEmptyLeases()
+		}
 
-			if job == nil {
-				// No jobs available
-				time.Sleep(1 * time.Second)
-				continue
+		// Give back the slots no job was leased for
+		for i := len(jobs); i < free; i++ {
+			<-slots
+		}
+		if len(jobs) == 0 {
+			// A long-poll lease has already waited unless it failed
+			if !s.config.LongPoll || err != nil {
+				s.wait(ctx)
 			}
+			continue
+		}
 
-			log.Printf("job_id=%s: job leased, tenant_id=%s, payload=%s", job.ID, job.TenantID, job.Payload)
+		for _, job := range jobs {
+			s.publishStatus(job.ID, models.StatusRunning)
+			log.Printf("job_id=%s: job leased, tenant_id=%s, queue=%s, payload=%s", job.ID, job.TenantID, job.Queue, job.Payload)
 
-			// Process the job
-			s.processJob(ctx, job)
+			wg.Add(1)
+			go func(job *models.Job) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				s.processJob(ctx, job)
+			}(job)
 		}
 	}
 }
 
-// processJob processes a single job
+// leaseJobs leases up to n jobs with the configured limits, long-polling if enabled
+func (s *WorkerService) leaseJobs(ctx context.Context, n int) ([]*models.Job, error) {
+	opts := repository.LeaseOptions{
+		MaxRunningPerTenant: s.config.MaxRunningPerTenant,
+		TenantMaxRunning:    s.config.TenantMaxRunning,
+		FairScheduling:      s.config.FairScheduling,
+		WorkerID:            s.config.WorkerID,
+		TenantID:            s.config.TenantID,
+	}
+
+	timeout := s.config.LeaseTimeout
+	if s.config.LongPoll {
+		timeout += s.config.PollInterval
+	}
+	leaseCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var jobs []*models.Job
+	var err error
+	if s.config.LongPoll {
+		jobs, err = s.repo.LeaseJobsWait(leaseCtx, s.config.Queue, n, s.config.LeaseDuration, opts, s.config.PollInterval)
+	} else {
+		jobs, err = s.repo.LeaseJobs(leaseCtx, s.config.Queue, n, s.config.LeaseDuration, opts)
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("lease timed out after %s: %w", timeout, err)
+	}
+	return jobs, err
+}
+
+// waitDrained waits for the jobs in progress to finish, then idles until the context is cancelled
+func (s *WorkerService) waitDrained(ctx context.Context, wg *sync.WaitGroup) error {
+	log.Printf("worker draining, no new jobs will be leased")
+	wg.Wait()
+	log.Printf("worker drained, all jobs in progress have finished")
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// RunReclaimer returns expired leases to PENDING on every tick until the context is cancelled
+func (s *WorkerService) RunReclaimer(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := s.ReclaimExpiredLeases(ctx); err != nil {
+				log.Printf("error reclaiming expired leases: %v", err)
+			}
+		}
+	}
+}
+
+// ReclaimExpiredLeases returns RUNNING jobs whose lease has expired to PENDING so they can be leased again
+func (s *WorkerService) ReclaimExpiredLeases(ctx context.Context) (int64, error) {
+	reclaimed, err := s.repo.ReclaimExpiredLeases(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reclaim expired leases: %w", err)
+	}
+
+	if reclaimed > 0 {
+		s.metrics.AddReclaimedJobs(reclaimed)
+		log.Printf("reclaimed %d jobs with expired leases", reclaimed)
+	}
+
+	return reclaimed, nil
+}
+
+// wait sleeps for the poll interval, or longer while writes fail, or until the context is
+// cancelled or the worker starts draining
+func (s *WorkerService) wait(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-s.drain:
+	case <-time.After(s.pollDelay()):
+	}
+}
+
+// noteWrite tracks writes failing because the database is read-only or its disk is full,
+// which retrying every poll interval cannot fix
+func (s *WorkerService) noteWrite(err error) {
+	if repository.IsWriteUnavailable(err) {
+		s.writeFailures.Add(1)
+		log.Printf("database is not writable, backing off %s: %v", s.pollDelay(), err)
+	} else if err == nil {
+		s.writeFailures.Store(0)
+	}
+}
+
+// pollDelay returns the poll interval, doubled for every write in a row that failed because
+// the database was not writable, up to MaxWriteBackoff
+func (s *WorkerService) pollDelay() time.Duration {
+	delay := s.config.PollInterval
+	for i := int32(0); i < s.writeFailures.Load() && delay < MaxWriteBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxWriteBackoff)
+}
+
+// processJob runs a single job through the configured handler. The handler's context is
+// cancelled when the attempt times out or the job is cancelled through the cancel registry.
 func (s *WorkerService) processJob(ctx context.Context, job *models.Job) {
-	// Simulate processing
-	time.Sleep(2 * time.Second)
+	cancelCtx, cancelJob := context.WithCancelCause(ctx)
+	defer cancelJob(nil)
+	unregister := s.cancels.Register(job.ID, cancelJob)
+	defer unregister()
+	finished := s.active.Start(job)
+	defer finished()
+
+	handlerCtx, cancel := context.WithTimeout(cancelCtx, s.config.JobTimeout)
+	defer cancel()
 
-	// Check if job should fail
-	if job.Payload == "fail" {
-		s.handleJobFailure(ctx, job, "payload is 'fail'")
+	handlerJob, err := decodeForHandler(job)
+	var result string
+	if err == nil {
+		result, err = s.callHandler(handlerCtx, handlerJob)
+	}
+	if errors.Is(context.Cause(handlerCtx), ErrJobCancelled) {
+		// The job is already CANCELLED, so there is nothing to retry or complete
+		log.Printf("job_id=%s: handler stopped, job was cancelled", job.ID)
+		return
+	}
+	var reschedule *RescheduleError
+	if errors.As(err, &reschedule) {
+		s.rescheduleJob(ctx, job, reschedule.After)
+		return
+	}
+	if err != nil {
+		if errors.Is(handlerCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", s.config.JobTimeout, err)
+		}
+		s.handleJobFailure(ctx, job, err)
 		return
 	}
 
-	// Job succeeded
-	if err := s.repo.UpdateJobStatus(ctx, job.ID, models.StatusDone); err != nil {
+	if job.PayloadEncoding == models.PayloadEncodingBase64 {
+		result = base64.StdEncoding.EncodeToString([]byte(result))
+	}
+	s.completeJob(ctx, job, result)
+}
+
+// decodeForHandler returns the job as its handler sees it: a base64 payload is replaced
+// with the bytes it encodes. A payload that does not decode fails the job for good.
+func decodeForHandler(job *models.Job) (*models.Job, error) {
+	if job.PayloadEncoding != models.PayloadEncodingBase64 {
+		return job, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(job.Payload)
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("invalid base64 payload: %w", err))
+	}
+	decoded := *job
+	decoded.Payload = string(data)
+	return &decoded, nil
+}
+
+// callHandler runs the configured handler once a handler slot is free, turning a panic into
+// an error that carries the stack so one bad job fails its attempt instead of taking the
+// worker down
+func (s *WorkerService) callHandler(ctx context.Context, job *models.Job) (result string, err error) {
+	if s.handlerSlots != nil {
+		select {
+		case s.handlerSlots <- struct{}{}:
+			defer func() { <-s.handlerSlots }()
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("job_id=%s: handler panicked: %v", job.ID, r)
+			err = fmt.Errorf("handler panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return s.config.Handler.Handle(ctx, job)
+}
+
+// completeJob marks a RUNNING job as DONE and stores the handler's result
+func (s *WorkerService) completeJob(ctx context.Context, job *models.Job, result string) {
+	// Only complete the job if it is still RUNNING, so a late worker cannot clobber a terminal status
+	ok, err := s.repo.CompleteJob(ctx, job.ID, result)
+	s.noteWrite(err)
+	if err != nil {
 		log.Printf("job_id=%s: error updating job status to DONE: %v", job.ID, err)
 		return
 	}
+	if !ok {
+		log.Printf("job_id=%s: job is no longer RUNNING, discarding result", job.ID)
+		return
+	}
 
-	s.metrics.IncrementCompletedJobs()
+	s.publishStatus(job.ID, models.StatusDone)
+	s.publishOutcome(ctx, job, models.StatusDone, result, "")
+	s.metrics.IncrementCompletedJobs(job.Queue)
+	s.metrics.SetLastProcessed(time.Now())
 	log.Printf("job_id=%s: job completed successfully", job.ID)
 }
 
-// handleJobFailure handles a failed job
-func (s *WorkerService) handleJobFailure(ctx context.Context, job *models.Job, failureReason string) {
+// rescheduleJob returns a RUNNING job to PENDING to be leased again once after has passed,
+// leaving its retry count alone
+func (s *WorkerService) rescheduleJob(ctx context.Context, job *models.Job, after time.Duration) {
+	ok, err := s.repo.RescheduleJob(ctx, job.ID, time.Now().Add(after))
+	if err != nil {
+		log.Printf("job_id=%s: error rescheduling job: %v", job.ID, err)
+		return
+	}
+	if !ok {
+		log.Printf("job_id=%s: job is no longer RUNNING, not rescheduling", job.ID)
+		return
+	}
+
+	s.publishStatus(job.ID, models.StatusPending)
+	log.Printf("job_id=%s: job rescheduled to run in %s", job.ID, after)
+}
+
+// handleJobFailure retries a failed job or, once its retries are used up or the error is
+// permanent, moves it to the dead letter queue
+func (s *WorkerService) handleJobFailure(ctx context.Context, job *models.Job, jobErr error) {
+	failureReason := jobErr.Error()
+	permanent := IsPermanent(jobErr)
+
+	attempt := &models.JobAttempt{
+		Attempt: job.RetryCount + 1,
+		Reason:  failureReason,
+		At:      time.Now(),
+	}
+
 	// Check if we should retry
-	if job.RetryCount < job.MaxRetries {
-		// Reset to PENDING for retry
-		if err := s.repo.IncrementRetryCount(ctx, job.ID); err != nil {
-			log.Printf("job_id=%s: error incrementing retry count: %v", job.ID, err)
+	if job.RetryCount < job.MaxRetries && !permanent {
+		// Back to PENDING with the attempt counted in one step, unless the job has left RUNNING
+		// in the meantime; a worker leasing it again must not see the old retry count
+		ok, err := s.repo.RetryJob(ctx, job.ID, attempt)
+		s.noteWrite(err)
+		if err != nil {
+			log.Printf("job_id=%s: error resetting job status to PENDING: %v", job.ID, err)
 			return
 		}
-
-		if err := s.repo.UpdateJobStatus(ctx, job.ID, models.StatusPending); err != nil {
-			log.Printf("job_id=%s: error resetting job status to PENDING: %v", job.ID, err)
+		if !ok {
+			log.Printf("job_id=%s: job is no longer RUNNING, not retrying", job.ID)
 			return
 		}
 
+		s.publishStatus(job.ID, models.StatusPending)
 		s.metrics.IncrementRetriedJobs()
 		log.Printf("job_id=%s: job failed, retrying (attempt %d/%d), reason: %s", job.ID, job.RetryCount+1, job.MaxRetries, failureReason)
 		return
 	}
 
-	// Max retries exceeded, move to DLQ
-	if err := s.repo.MoveToDeadLetterQueue(ctx, job, fmt.Sprintf("max retries exceeded: %s", failureReason)); err != nil {
+	dlqReason := fmt.Sprintf("max retries exceeded: %s", failureReason)
+	if permanent {
+		dlqReason = fmt.Sprintf("permanent failure: %s", failureReason)
+	}
+
+	// Max retries exceeded or permanent failure: FAILED and into the DLQ in one transaction, so
+	// a failed move leaves the job RUNNING for its lease to expire and be retried
+	ok, err := s.repo.FailRunningJob(ctx, job, attempt, dlqReason)
+	s.noteWrite(err)
+	if err != nil {
 		log.Printf("job_id=%s: error moving job to DLQ: %v", job.ID, err)
 		return
 	}
+	if !ok {
+		log.Printf("job_id=%s: job is no longer RUNNING, not moving to DLQ", job.ID)
+		return
+	}
+
+	s.publishStatus(job.ID, models.StatusFailed)
+	s.publishOutcome(ctx, job, models.StatusFailed, "", dlqReason)
+	s.metrics.IncrementFailedJobs(job.Queue)
+	s.metrics.IncrementDLQJobs()
+	log.Printf("job_id=%s: job moved to dead letter queue, reason: %s", job.ID, dlqReason)
+}
+
+// publishOutcome hands a finished job to the configured publisher, if any
+func (s *WorkerService) publishOutcome(ctx context.Context, job *models.Job, status models.JobStatus, result, failureReason string) {
+	if s.config.Publisher == nil {
+		return
+	}
 
-	s.metrics.IncrementFailedJobs()
-	log.Printf("job_id=%s: job moved to dead letter queue, reason: %s", job.ID, failureReason)
+	outcome := models.JobOutcome{
+		JobID:         job.ID,
+		TenantID:      job.TenantID,
+		Queue:         job.Queue,
+		Status:        status,
+		Result:        result,
+		FailureReason: failureReason,
+		FinishedAt:    time.Now(),
+	}
+	if err := s.config.Publisher.Publish(ctx, outcome); err != nil {
+		log.Printf("job_id=%s: error publishing outcome: %v", job.ID, err)
+	}
 }
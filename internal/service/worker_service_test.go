@@ -2,8 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"job-queue/internal/metrics"
 	"job-queue/internal/models"
+	"job-queue/internal/repository"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -15,11 +23,19 @@ type mockWorkerRepository struct {
 	updateStatusError error
 	incrementError    error
 	moveToDLQError    error
+	expiredLeases     int64
+	attempts          map[string][]*models.JobAttempt
+	dlqReasons        map[string]string
+	leaseError        error
+	// leaseHangs is how many lease calls block until their context is done
+	leaseHangs        int
 }
 
 func newMockWorkerRepository() *mockWorkerRepository {
 	return &mockWorkerRepository{
-		jobs: make(map[string]*models.Job),
+		jobs:     make(map[string]*models.Job),
+		attempts:   make(map[string][]*models.JobAttempt),
+		dlqReasons: make(map[string]string),
 	}
 }
 
@@ -27,25 +43,75 @@ func (m *mockWorkerRepository) CreateJob(ctx context.Context, job *models.Job) e
 	return nil
 }
 
+func (m *mockWorkerRepository) CreateJobsBatch(ctx context.Context, jobs []*models.Job) ([]error, error) {
+	return make([]error, len(jobs)), nil
+}
+
 func (m *mockWorkerRepository) GetJobByID(ctx context.Context, id string) (*models.Job, error) {
 	return m.jobs[id], nil
 }
 
-func (m *mockWorkerRepository) GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string) (*models.Job, error) {
+func (m *mockWorkerRepository) GetJobByTenantAndIdempotencyKey(ctx context.Context, tenantID, idempotencyKey string, since time.Time) (*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockWorkerRepository) GetJobByTenantAndDedupHash(ctx context.Context, tenantID, dedupHash string, since time.Time) (*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockWorkerRepository) ListJobsByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.Job, error) {
 	return nil, nil
 }
 
-func (m *mockWorkerRepository) ListJobsByStatus(ctx context.Context, status models.JobStatus) ([]*models.Job, error) {
+func (m *mockWorkerRepository) ListJobsByStatusPage(ctx context.Context, page repository.JobPage, statuses ...models.JobStatus) ([]*models.Job, *repository.JobCursor, error) {
+	return nil, nil, nil
+}
+
+func (m *mockWorkerRepository) ListJobsByTag(ctx context.Context, tenantID, tag string, statuses ...models.JobStatus) ([]*models.Job, error) {
 	return nil, nil
 }
 
-func (m *mockWorkerRepository) LeaseJob(ctx context.Context, leaseDuration time.Duration) (*models.Job, error) {
+func (m *mockWorkerRepository) SearchJobs(ctx context.Context, tenantID, query string, limit int) ([]*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockWorkerRepository) ListIdempotencyKeysByTenant(ctx context.Context, tenantID string, since time.Time) ([]*models.IdempotencyKeyEntry, error) {
+	return nil, nil
+}
+
+func (m *mockWorkerRepository) LeaseJob(ctx context.Context, queue string, leaseDuration time.Duration, opts repository.LeaseOptions) (*models.Job, error) {
+	if m.leaseHangs > 0 {
+		m.leaseHangs--
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if m.leaseError != nil {
+		return nil, m.leaseError
+	}
 	if m.leasedJob != nil {
 		return m.leasedJob, nil
 	}
 	return nil, nil
 }
 
+func (m *mockWorkerRepository) LeaseJobs(ctx context.Context, queue string, n int, leaseDuration time.Duration, opts repository.LeaseOptions) ([]*models.Job, error) {
+	job, err := m.LeaseJob(ctx, queue, leaseDuration, opts)
+	if job == nil {
+		return nil, err
+	}
+	return []*models.Job{job}, nil
+}
+
+func (m *mockWorkerRepository) LeaseJobsWait(ctx context.Context, queue string, n int, leaseDuration time.Duration, opts repository.LeaseOptions, wait time.Duration) ([]*models.Job, error) {
+	return m.LeaseJobs(ctx, queue, n, leaseDuration, opts)
+}
+
+func (m *mockWorkerRepository) ReclaimExpiredLeases(ctx context.Context) (int64, error) {
+	reclaimed := m.expiredLeases
+	m.expiredLeases = 0
+	return reclaimed, nil
+}
+
 func (m *mockWorkerRepository) UpdateJobStatus(ctx context.Context, id string, status models.JobStatus) error {
 	if m.updateStatusError != nil {
 		return m.updateStatusError
@@ -56,6 +122,73 @@ func (m *mockWorkerRepository) UpdateJobStatus(ctx context.Context, id string, s
 	return nil
 }
 
+func (m *mockWorkerRepository) UpdateJobStatusIf(ctx context.Context, id string, from, to models.JobStatus) (bool, error) {
+	if m.updateStatusError != nil {
+		return false, m.updateStatusError
+	}
+	job, exists := m.jobs[id]
+	if !exists || job.Status != from {
+		return false, nil
+	}
+	job.Status = to
+	return true, nil
+}
+
+func (m *mockWorkerRepository) CompleteJob(ctx context.Context, id, result string) (bool, error) {
+	if m.updateStatusError != nil {
+		return false, m.updateStatusError
+	}
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusRunning {
+		return false, nil
+	}
+	job.Status = models.StatusDone
+	job.Result = result
+	return true, nil
+}
+
+func (m *mockWorkerRepository) RescheduleJob(ctx context.Context, id string, at time.Time) (bool, error) {
+	if m.updateStatusError != nil {
+		return false, m.updateStatusError
+	}
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusRunning {
+		return false, nil
+	}
+	job.Status = models.StatusPending
+	job.NextRetryAt = &at
+	return true, nil
+}
+
+func (m *mockWorkerRepository) CancelJob(ctx context.Context, id string) (bool, error) {
+	job, exists := m.jobs[id]
+	if !exists || (job.Status != models.StatusPending && job.Status != models.StatusRunning) {
+		return false, nil
+	}
+	job.Status = models.StatusCancelled
+	return true, nil
+}
+
+func (m *mockWorkerRepository) UpdateJobStatusBatch(ctx context.Context, ids []string, from []models.JobStatus, to models.JobStatus) ([]bool, error) {
+	updated := make([]bool, len(ids))
+	for i, id := range ids {
+		if job, exists := m.jobs[id]; exists && slices.Contains(from, job.Status) && job.DeadLetterID == "" {
+			job.Status = to
+			updated[i] = true
+		}
+	}
+	return updated, nil
+}
+
+func (m *mockWorkerRepository) UpdateMaxRetries(ctx context.Context, id string, maxRetries int) (bool, error) {
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusPending {
+		return false, nil
+	}
+	job.MaxRetries = maxRetries
+	return true, nil
+}
+
 func (m *mockWorkerRepository) IncrementRetryCount(ctx context.Context, id string) error {
 	if m.incrementError != nil {
 		return m.incrementError
@@ -66,22 +199,158 @@ func (m *mockWorkerRepository) IncrementRetryCount(ctx context.Context, id strin
 	return nil
 }
 
+func (m *mockWorkerRepository) RetryJob(ctx context.Context, id string, attempt *models.JobAttempt) (bool, error) {
+	if m.updateStatusError != nil {
+		return false, m.updateStatusError
+	}
+	if m.incrementError != nil {
+		return false, m.incrementError
+	}
+	job, exists := m.jobs[id]
+	if !exists || job.Status != models.StatusRunning {
+		return false, nil
+	}
+	job.Status = models.StatusPending
+	job.RetryCount++
+	m.attempts[id] = append(m.attempts[id], attempt)
+	return true, nil
+}
+
 func (m *mockWorkerRepository) GetRunningJobsCountByTenant(ctx context.Context, tenantID string) (int, error) {
 	return 0, nil
 }
 
+func (m *mockWorkerRepository) RecordJobAttempt(ctx context.Context, jobID string, attempt *models.JobAttempt) error {
+	m.attempts[jobID] = append(m.attempts[jobID], attempt)
+	return nil
+}
+
 func (m *mockWorkerRepository) MoveToDeadLetterQueue(ctx context.Context, job *models.Job, failureReason string) error {
 	if m.moveToDLQError != nil {
 		return m.moveToDLQError
 	}
+	m.dlqReasons[job.ID] = failureReason
 	delete(m.jobs, job.ID)
 	return nil
 }
 
+func (m *mockWorkerRepository) FailRunningJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, failureReason string) (bool, error) {
+	if m.updateStatusError != nil {
+		return false, m.updateStatusError
+	}
+	if m.moveToDLQError != nil {
+		return false, m.moveToDLQError
+	}
+	stored, exists := m.jobs[job.ID]
+	if !exists || stored.Status != models.StatusRunning {
+		return false, nil
+	}
+	m.attempts[job.ID] = append(m.attempts[job.ID], attempt)
+	m.dlqReasons[job.ID] = failureReason
+	delete(m.jobs, job.ID)
+	return true, nil
+}
+
+func (m *mockWorkerRepository) ListJobTransitions(ctx context.Context, jobID string) ([]models.JobTransition, error) {
+	return []models.JobTransition{}, nil
+}
+
+func (m *mockWorkerRepository) ListJobAttempts(ctx context.Context, jobID string) ([]models.JobAttempt, error) {
+	var attempts []models.JobAttempt
+	for _, attempt := range m.attempts[jobID] {
+		attempts = append(attempts, *attempt)
+	}
+	return attempts, nil
+}
+
+func (m *mockWorkerRepository) GetDeadLetterJobByJobID(ctx context.Context, jobID string) (*models.DeadLetterJob, error) {
+	return nil, nil
+}
+
 func (m *mockWorkerRepository) ListDeadLetterJobs(ctx context.Context) ([]*models.DeadLetterJob, error) {
 	return nil, nil
 }
 
+func (m *mockWorkerRepository) GetTotalJobsCount(ctx context.Context) (int, error) {
+	return len(m.jobs), nil
+}
+
+func (m *mockWorkerRepository) GetCompletedJobsCount(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWorkerRepository) GetFailedJobsCount(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWorkerRepository) ListDeadLetterJobsFiltered(ctx context.Context, tenantID string, limit, offset int) ([]*models.DeadLetterJob, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockWorkerRepository) DeleteJobsOlderThan(ctx context.Context, status models.JobStatus, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockWorkerRepository) ListDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) ([]*models.DeadLetterJob, error) {
+	return nil, nil
+}
+
+func (m *mockWorkerRepository) DeleteDeadLetterJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockWorkerRepository) CountDeadLetterByReason(ctx context.Context, tenantID string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (m *mockWorkerRepository) ListExpiredJobs(ctx context.Context, now time.Time) ([]*models.Job, error) {
+	return nil, nil
+}
+
+func (m *mockWorkerRepository) FailExpiredJob(ctx context.Context, job *models.Job, attempt *models.JobAttempt, deadLetter bool) (bool, error) {
+	return false, nil
+}
+
+func (m *mockWorkerRepository) SetPaused(ctx context.Context, paused bool) error {
+	return nil
+}
+
+func (m *mockWorkerRepository) IsPaused(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func (m *mockWorkerRepository) CountJobsGroupedByStatus(ctx context.Context) (map[models.JobStatus]int, error) {
+	return map[models.JobStatus]int{}, nil
+}
+
+func (m *mockWorkerRepository) CountJobsByStatus(ctx context.Context, status models.JobStatus) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWorkerRepository) CountJobsAheadOf(ctx context.Context, job *models.Job) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWorkerRepository) OldestPendingJobAge(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
+func (m *mockWorkerRepository) PendingJobAgePercentiles(ctx context.Context, percentiles []float64) ([]time.Duration, error) {
+	return make([]time.Duration, len(percentiles)), nil
+}
+
+func (m *mockWorkerRepository) UpdatePayload(ctx context.Context, id string, payload string, payloadJSON bool, dedupHash string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockWorkerRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockWorkerRepository) GetDeadLetterQueueCount(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
 func TestWorkerService_ProcessJob_Success(t *testing.T) {
 	repo := newMockWorkerRepository()
 	job := &models.Job{
@@ -101,7 +370,7 @@ func TestWorkerService_ProcessJob_Success(t *testing.T) {
 	// which is private. We test the behavior through integration.
 	
 	// Verify job can be leased
-	leased, err := repo.LeaseJob(context.Background(), 30*time.Second)
+	leased, err := repo.LeaseJob(context.Background(), models.DefaultQueue, 30*time.Second, repository.LeaseOptions{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -168,3 +437,719 @@ func TestWorkerService_ProcessJob_MaxRetries(t *testing.T) {
 		t.Error("job should be removed from jobs after moving to DLQ")
 	}
 }
+
+func TestWorkerService_ConfigDefaults(t *testing.T) {
+	repo := newMockWorkerRepository()
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{LeaseDuration: 5 * time.Minute})
+
+	if service.config.Queue != models.DefaultQueue {
+		t.Errorf("expected queue %q, got %q", models.DefaultQueue, service.config.Queue)
+	}
+	if service.config.LeaseDuration != 5*time.Minute {
+		t.Errorf("expected lease duration 5m, got %s", service.config.LeaseDuration)
+	}
+	if service.config.PollInterval != DefaultPollInterval {
+		t.Errorf("expected poll interval %s, got %s", DefaultPollInterval, service.config.PollInterval)
+	}
+}
+
+func TestWorkerService_ReclaimExpiredLeases(t *testing.T) {
+	repo := newMockWorkerRepository()
+	repo.expiredLeases = 3
+	metrics := metrics.NewMetrics()
+	service := NewWorkerService(repo, metrics)
+
+	reclaimed, err := service.ReclaimExpiredLeases(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reclaimed != 3 {
+		t.Errorf("expected 3 reclaimed jobs, got %d", reclaimed)
+	}
+
+	if _, err := service.ReclaimExpiredLeases(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := metrics.GetSnapshot()["reclaimed_jobs"]; got != 3 {
+		t.Errorf("expected reclaimed_jobs 3, got %d", got)
+	}
+}
+
+func TestWorkerService_ProcessJobs_CountsLeaseOutcomes(t *testing.T) {
+	tests := []struct {
+		name       string
+		leaseError error
+		counter    string
+	}{
+		{name: "empty queue", counter: "empty_leases"},
+		{name: "lease error", leaseError: errors.New("database is locked"), counter: "lease_errors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockWorkerRepository()
+			repo.leaseError = tt.leaseError
+			metrics := metrics.NewMetrics()
+			service := NewWorkerServiceWithConfig(repo, metrics, WorkerConfig{PollInterval: time.Millisecond})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			service.ProcessJobs(ctx)
+
+			snapshot := metrics.GetSnapshot()
+			if snapshot[tt.counter] == 0 {
+				t.Errorf("expected %s to be counted", tt.counter)
+			}
+			if snapshot["empty_leases"]+snapshot["lease_errors"] != snapshot[tt.counter] {
+				t.Errorf("expected only %s to be counted, got %v", tt.counter, snapshot)
+			}
+		})
+	}
+}
+
+func TestWorkerService_ProcessJobs_RecoversFromHungLease(t *testing.T) {
+	repo := newMockWorkerRepository()
+	repo.leaseHangs = 1
+	job := &models.Job{ID: "job-1", Payload: "good", Status: models.StatusRunning, MaxRetries: 3}
+	repo.jobs[job.ID] = job
+	repo.leasedJob = job
+	metrics := metrics.NewMetrics()
+	service := NewWorkerServiceWithConfig(repo, metrics, WorkerConfig{
+		PollInterval: time.Millisecond,
+		LeaseTimeout: 20 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil && metrics.GetSnapshot()["last_processed_at"] == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	service.ProcessJobs(ctx)
+
+	if metrics.GetSnapshot()["lease_errors"] != 1 {
+		t.Errorf("expected the hung lease to be counted as a lease error, got %v", metrics.GetSnapshot())
+	}
+	if job.Status != models.StatusDone {
+		t.Errorf("expected the worker to lease again after the hung lease timed out, job is %s", job.Status)
+	}
+}
+
+func TestWorkerService_CompleteJob_DoesNotClobberTerminalStatus(t *testing.T) {
+	repo := newMockWorkerRepository()
+	metrics := metrics.NewMetrics()
+	service := NewWorkerService(repo, metrics)
+
+	running := &models.Job{ID: "job-1", Status: models.StatusRunning}
+	failed := &models.Job{ID: "job-2", Status: models.StatusFailed}
+	repo.jobs["job-1"] = running
+	repo.jobs["job-2"] = failed
+
+	service.completeJob(context.Background(), running, "")
+	service.completeJob(context.Background(), failed, "")
+
+	if running.Status != models.StatusDone {
+		t.Errorf("expected RUNNING job to complete, got %s", running.Status)
+	}
+	if failed.Status != models.StatusFailed {
+		t.Errorf("expected FAILED job to stay FAILED, got %s", failed.Status)
+	}
+	if got := metrics.GetSnapshot()["completed_jobs"]; got != 1 {
+		t.Errorf("expected 1 completed job, got %d", got)
+	}
+}
+
+func TestWorkerService_HandleJobFailure_SkipsJobsNoLongerRunning(t *testing.T) {
+	repo := newMockWorkerRepository()
+	metrics := metrics.NewMetrics()
+	service := NewWorkerService(repo, metrics)
+
+	done := &models.Job{ID: "job-1", Status: models.StatusDone, MaxRetries: 3}
+	exhausted := &models.Job{ID: "job-2", Status: models.StatusDone, MaxRetries: 1, RetryCount: 1}
+	repo.jobs["job-1"] = done
+	repo.jobs["job-2"] = exhausted
+
+	service.handleJobFailure(context.Background(), done, errors.New("late failure"))
+	service.handleJobFailure(context.Background(), exhausted, errors.New("late failure"))
+
+	if done.Status != models.StatusDone || done.RetryCount != 0 {
+		t.Errorf("expected DONE job to be left alone, got %s with %d retries", done.Status, done.RetryCount)
+	}
+	if _, exists := repo.jobs["job-2"]; !exists {
+		t.Error("expected DONE job not to be moved to the DLQ")
+	}
+}
+
+func TestWorkerService_HandleJobFailure_RetriesRunningJob(t *testing.T) {
+	repo := newMockWorkerRepository()
+	service := NewWorkerService(repo, metrics.NewMetrics())
+
+	job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 3}
+	repo.jobs["job-1"] = job
+
+	service.handleJobFailure(context.Background(), job, errors.New("boom"))
+
+	if job.Status != models.StatusPending || job.RetryCount != 1 {
+		t.Errorf("expected job to be PENDING with 1 retry, got %s with %d", job.Status, job.RetryCount)
+	}
+}
+
+func TestWorkerService_HandleJobFailure_DLQErrorLeavesJobRunning(t *testing.T) {
+	repo := newMockWorkerRepository()
+	repo.moveToDLQError = errors.New("database is locked")
+	service := NewWorkerService(repo, metrics.NewMetrics())
+
+	job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 0}
+	repo.jobs["job-1"] = job
+
+	service.handleJobFailure(context.Background(), job, errors.New("boom"))
+
+	// The FAILED claim and the DLQ move share a transaction, so neither happens
+	if job.Status != models.StatusRunning {
+		t.Errorf("expected job to stay RUNNING, got %s", job.Status)
+	}
+	if attempts := repo.attempts["job-1"]; len(attempts) != 0 {
+		t.Errorf("expected no recorded attempt, got %d", len(attempts))
+	}
+}
+
+func TestWorkerService_HandleJobFailure_PermanentError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus models.JobStatus
+		wantDLQ    string
+	}{
+		{name: "transient", err: errors.New("connection reset"), wantStatus: models.StatusPending},
+		{name: "permanent", err: Permanent(errors.New("bad payload")), wantDLQ: "permanent failure: bad payload"},
+		{name: "wrapped permanent", err: fmt.Errorf("timed out: %w", Permanent(errors.New("bad payload"))), wantDLQ: "permanent failure: timed out: bad payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockWorkerRepository()
+			service := NewWorkerService(repo, metrics.NewMetrics())
+
+			job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 3}
+			repo.jobs["job-1"] = job
+
+			service.handleJobFailure(context.Background(), job, tt.err)
+
+			if got := repo.dlqReasons["job-1"]; got != tt.wantDLQ {
+				t.Errorf("expected DLQ reason %q, got %q", tt.wantDLQ, got)
+			}
+			if tt.wantDLQ == "" && (job.Status != tt.wantStatus || job.RetryCount != 1) {
+				t.Errorf("expected job to be %s with 1 retry, got %s with %d", tt.wantStatus, job.Status, job.RetryCount)
+			}
+			if attempts := repo.attempts["job-1"]; len(attempts) != 1 {
+				t.Errorf("expected 1 recorded attempt, got %d", len(attempts))
+			}
+		})
+	}
+}
+
+func TestWorkerService_CountsOutcomesByQueue(t *testing.T) {
+	repo := newMockWorkerRepository()
+	metrics := metrics.NewMetrics()
+	service := NewWorkerService(repo, metrics)
+
+	done := &models.Job{ID: "job-1", Queue: "emails", Status: models.StatusRunning}
+	failed := &models.Job{ID: "job-2", Queue: "reports", Status: models.StatusRunning}
+	repo.jobs["job-1"] = done
+	repo.jobs["job-2"] = failed
+
+	service.completeJob(context.Background(), done, "")
+	service.handleJobFailure(context.Background(), failed, errors.New("boom"))
+
+	snapshot := metrics.GetSnapshot()
+	if got := snapshot["by_queue.emails.completed_jobs"]; got != 1 {
+		t.Errorf("expected 1 completed job in emails, got %d", got)
+	}
+	if got := snapshot["by_queue.reports.failed_jobs"]; got != 1 {
+		t.Errorf("expected 1 failed job in reports, got %d", got)
+	}
+}
+
+func TestWorkerService_HandleJobFailure_RecordsAttempts(t *testing.T) {
+	repo := newMockWorkerRepository()
+	service := NewWorkerService(repo, metrics.NewMetrics())
+
+	job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 1}
+	repo.jobs["job-1"] = job
+
+	service.handleJobFailure(context.Background(), job, errors.New("first"))
+
+	job.Status = models.StatusRunning
+	service.handleJobFailure(context.Background(), job, errors.New("second"))
+
+	attempts := repo.attempts["job-1"]
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 recorded attempts, got %d", len(attempts))
+	}
+	for i, reason := range []string{"first", "second"} {
+		if attempts[i].Attempt != i+1 || attempts[i].Reason != reason {
+			t.Errorf("attempt %d: expected %q, got %d %q", i+1, reason, attempts[i].Attempt, attempts[i].Reason)
+		}
+	}
+}
+
+func TestWorkerService_ProcessJob_UsesConfiguredHandler(t *testing.T) {
+	repo := newMockWorkerRepository()
+	metrics := metrics.NewMetrics()
+	service := NewWorkerServiceWithConfig(repo, metrics, WorkerConfig{
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			if job.Payload == "bad" {
+				return "", errors.New("exited with code 3")
+			}
+			return "processed " + job.Payload, nil
+		}),
+	})
+
+	ok := &models.Job{ID: "job-1", Payload: "good", Status: models.StatusRunning, MaxRetries: 3}
+	bad := &models.Job{ID: "job-2", Payload: "bad", Status: models.StatusRunning, MaxRetries: 0}
+	repo.jobs[ok.ID] = ok
+	repo.jobs[bad.ID] = bad
+
+	service.processJob(context.Background(), bad)
+	if processed := metrics.GetSnapshot()["last_processed_at"]; processed != 0 {
+		t.Errorf("expected a failed job not to count as processed, got %d", processed)
+	}
+	service.processJob(context.Background(), ok)
+
+	if ok.Status != models.StatusDone || ok.Result != "processed good" {
+		t.Errorf("expected DONE with the handler result, got %s %q", ok.Status, ok.Result)
+	}
+	if processed := metrics.GetSnapshot()["last_processed_at"]; processed == 0 {
+		t.Error("expected last_processed_at to be set by the completed job")
+	}
+	if reason := repo.dlqReasons[bad.ID]; !strings.Contains(reason, "exited with code 3") {
+		t.Errorf("expected the handler error in the DLQ reason, got %q", reason)
+	}
+}
+
+func TestWorkerService_Status_ReportsActiveJobs(t *testing.T) {
+	repo := newMockWorkerRepository()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		WorkerID: "worker-a",
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			close(started)
+			<-release
+			return "done", nil
+		}),
+	})
+
+	job := &models.Job{ID: "job-1", TenantID: "tenant-1", Queue: models.DefaultQueue, Payload: "good", Status: models.StatusRunning, RetryCount: 1, MaxRetries: 3}
+	repo.jobs[job.ID] = job
+
+	done := make(chan struct{})
+	go func() {
+		service.processJob(context.Background(), job)
+		close(done)
+	}()
+	<-started
+
+	status := service.Status()
+	if status.WorkerID != "worker-a" || status.Queue != models.DefaultQueue || status.Draining {
+		t.Errorf("unexpected worker identity %+v", status)
+	}
+	if len(status.ActiveJobs) != 1 {
+		t.Fatalf("expected 1 active job, got %+v", status.ActiveJobs)
+	}
+	active := status.ActiveJobs[0]
+	if active.JobID != "job-1" || active.TenantID != "tenant-1" || active.Attempt != 2 || active.RunningSeconds < 0 {
+		t.Errorf("unexpected active job %+v", active)
+	}
+
+	close(release)
+	<-done
+	if status := service.Status(); len(status.ActiveJobs) != 0 {
+		t.Errorf("expected no active jobs once the job finished, got %+v", status.ActiveJobs)
+	}
+}
+
+func TestWorkerService_ProcessJob_Base64Payload(t *testing.T) {
+	repo := newMockWorkerRepository()
+	var received string
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			received = job.Payload
+			return "\x00ok", nil
+		}),
+	})
+
+	job := &models.Job{ID: "job-1", Payload: "AAEC/w==", PayloadEncoding: models.PayloadEncodingBase64, Status: models.StatusRunning, MaxRetries: 3}
+	repo.jobs[job.ID] = job
+	service.processJob(context.Background(), job)
+
+	if received != "\x00\x01\x02\xff" {
+		t.Errorf("expected the handler to receive the decoded bytes, got %q", received)
+	}
+	if job.Status != models.StatusDone || job.Result != "AG9r" {
+		t.Errorf("expected DONE with a base64 encoded result, got %s %q", job.Status, job.Result)
+	}
+	if job.Payload != "AAEC/w==" {
+		t.Errorf("expected the stored payload to stay encoded, got %q", job.Payload)
+	}
+
+	// A payload that does not decode can never succeed, so it skips the retries
+	bad := &models.Job{ID: "job-2", Payload: "not base64!", PayloadEncoding: models.PayloadEncodingBase64, Status: models.StatusRunning, MaxRetries: 3}
+	repo.jobs[bad.ID] = bad
+	service.processJob(context.Background(), bad)
+
+	if reason := repo.dlqReasons[bad.ID]; !strings.Contains(reason, "invalid base64 payload") {
+		t.Errorf("expected the job in the DLQ with the decode error, got %q", reason)
+	}
+}
+
+func TestWorkerService_ProcessJob_Timeout(t *testing.T) {
+	repo := newMockWorkerRepository()
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		JobTimeout: 10 * time.Millisecond,
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}),
+	})
+
+	job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 3}
+	repo.jobs[job.ID] = job
+
+	service.processJob(context.Background(), job)
+
+	if job.Status != models.StatusPending {
+		t.Errorf("expected the timed out job to be retried, got %s", job.Status)
+	}
+	if attempts := repo.attempts[job.ID]; len(attempts) != 1 || !strings.Contains(attempts[0].Reason, "timed out") {
+		t.Errorf("expected a timed out attempt, got %+v", attempts)
+	}
+}
+
+func TestWorkerService_ProcessJob_RecoversPanic(t *testing.T) {
+	repo := newMockWorkerRepository()
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			var payload map[string]string
+			payload["key"] = job.Payload
+			return "", nil
+		}),
+	})
+
+	job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 1}
+	repo.jobs[job.ID] = job
+
+	// A panic that escaped processJob would crash the test binary here
+	service.processJob(context.Background(), job)
+
+	if job.Status != models.StatusPending || job.RetryCount != 1 {
+		t.Fatalf("expected the panicked job to be retried, got %s with %d retries", job.Status, job.RetryCount)
+	}
+	attempts := repo.attempts[job.ID]
+	if len(attempts) != 1 || !strings.Contains(attempts[0].Reason, "handler panicked: assignment to entry in nil map") {
+		t.Fatalf("expected a panicked attempt, got %+v", attempts)
+	}
+	if !strings.Contains(attempts[0].Reason, "TestWorkerService_ProcessJob_RecoversPanic") {
+		t.Errorf("expected the stack in the failure reason, got %q", attempts[0].Reason)
+	}
+
+	job.Status = models.StatusRunning
+	service.processJob(context.Background(), job)
+
+	if reason := repo.dlqReasons[job.ID]; !strings.HasPrefix(reason, "max retries exceeded: handler panicked") {
+		t.Errorf("expected the job in the DLQ once its retries are used up, got %q", reason)
+	}
+}
+
+func TestWorkerService_ProcessJob_Reschedule(t *testing.T) {
+	repo := newMockWorkerRepository()
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			return "", fmt.Errorf("rate limited: %w", &RescheduleError{After: time.Minute})
+		}),
+	})
+
+	job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 0}
+	repo.jobs[job.ID] = job
+
+	before := time.Now()
+	service.processJob(context.Background(), job)
+
+	if job.Status != models.StatusPending || job.RetryCount != 0 {
+		t.Errorf("expected PENDING with no retry used, got %s with %d", job.Status, job.RetryCount)
+	}
+	if job.NextRetryAt == nil || job.NextRetryAt.Before(before.Add(time.Minute)) {
+		t.Errorf("expected next_retry_at a minute out, got %v", job.NextRetryAt)
+	}
+	if attempts := repo.attempts[job.ID]; len(attempts) != 0 {
+		t.Errorf("expected no failed attempt, got %+v", attempts)
+	}
+	if _, ok := repo.dlqReasons[job.ID]; ok {
+		t.Error("expected the job to stay out of the DLQ even with no retries")
+	}
+}
+
+func TestWorkerService_ProcessJob_MaxConcurrentHandlers(t *testing.T) {
+	var running, peak int32
+	release := make(chan struct{})
+
+	repo := newMockWorkerRepository()
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		Concurrency:           4,
+		MaxConcurrentHandlers: 2,
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			<-release
+			return "", nil
+		}),
+	})
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 4; i++ {
+		job := &models.Job{ID: fmt.Sprintf("job-%d", i), Status: models.StatusRunning}
+		repo.jobs[job.ID] = job
+	}
+	for _, job := range repo.jobs {
+		wg.Add(1)
+		go func(job *models.Job) {
+			defer wg.Done()
+			service.processJob(context.Background(), job)
+		}(job)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&running) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// Give the jobs beyond the cap a chance to start if the cap were not enforced
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&peak); got != 2 {
+		t.Errorf("expected at most 2 handlers running at once, got %d", got)
+	}
+	for id, job := range repo.jobs {
+		if job.Status != models.StatusDone {
+			t.Errorf("expected %s to be DONE once a slot freed up, got %s", id, job.Status)
+		}
+	}
+}
+
+func TestWorkerService_ProcessJob_StopsCancelledJob(t *testing.T) {
+	repo := newMockWorkerRepository()
+	cancels := NewCancelRegistry()
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			close(started)
+			<-ctx.Done()
+			stopped <- context.Cause(ctx)
+			return "", ctx.Err()
+		}),
+	})
+	service.SetCancelRegistry(cancels)
+
+	job := &models.Job{ID: "job-1", Status: models.StatusRunning, MaxRetries: 3}
+	repo.jobs[job.ID] = job
+
+	done := make(chan struct{})
+	go func() {
+		service.processJob(context.Background(), job)
+		close(done)
+	}()
+
+	<-started
+	// What JobService.CancelJob does: mark the job CANCELLED, then signal the registry
+	job.Status = models.StatusCancelled
+	if !cancels.Cancel(job.ID) {
+		t.Fatal("expected the running job to be registered")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected processJob to return after cancellation")
+	}
+
+	if cause := <-stopped; !errors.Is(cause, ErrJobCancelled) {
+		t.Errorf("expected the handler context to be cancelled with ErrJobCancelled, got %v", cause)
+	}
+	if job.Status != models.StatusCancelled {
+		t.Errorf("expected the job to stay CANCELLED, got %s", job.Status)
+	}
+	if len(repo.attempts[job.ID]) != 0 {
+		t.Errorf("expected a cancelled job not to record a failed attempt")
+	}
+	if cancels.Cancel(job.ID) {
+		t.Errorf("expected the job to be unregistered once its handler returned")
+	}
+}
+
+func TestWorkerService_ProcessJobs_Concurrency(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const total = 6
+	for i := 0; i < total; i++ {
+		if err := repo.CreateJob(ctx, &models.Job{ID: fmt.Sprintf("job-%d", i), TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	// Each handler waits until three run at once, so the test only passes if jobs are processed concurrently
+	var mu sync.Mutex
+	inFlight := 0
+	started := make(chan struct{})
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		Concurrency:  3,
+		PollInterval: 10 * time.Millisecond,
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			mu.Lock()
+			inFlight++
+			if inFlight == 3 {
+				close(started)
+			}
+			mu.Unlock()
+
+			select {
+			case <-started:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+
+			return "", nil
+		}),
+	})
+
+	stopped := make(chan struct{})
+	go func() {
+		service.ProcessJobs(ctx)
+		close(stopped)
+	}()
+
+	for {
+		completed, err := repo.CountJobsByStatus(ctx, models.StatusDone)
+		if err != nil {
+			t.Fatalf("expected %d DONE jobs before the timeout, got %d (err %v)", total, completed, err)
+		}
+		if completed == total {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-stopped
+}
+
+func TestWorkerService_ProcessJobs_Drain(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if err := repo.CreateJob(ctx, &models.Job{ID: fmt.Sprintf("job-%d", i), TenantID: "tenant-1", Payload: "work", Status: models.StatusPending, MaxRetries: 3}); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	service := NewWorkerServiceWithConfig(repo, metrics.NewMetrics(), WorkerConfig{
+		PollInterval: 10 * time.Millisecond,
+		Handler: HandlerFunc(func(ctx context.Context, job *models.Job) (string, error) {
+			started <- struct{}{}
+			<-release
+			return "", nil
+		}),
+	})
+
+	stopped := make(chan struct{})
+	go func() {
+		service.ProcessJobs(ctx)
+		close(stopped)
+	}()
+
+	// Drain while the first job is in progress, then let it finish
+	<-started
+	service.Drain()
+	if !service.Draining() {
+		t.Error("expected the worker to be draining")
+	}
+	close(release)
+
+	for {
+		completed, err := repo.CountJobsByStatus(ctx, models.StatusDone)
+		if err != nil {
+			t.Fatalf("expected the job in progress to finish before the timeout (err %v)", err)
+		}
+		if completed == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The worker keeps running but leases nothing new
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-stopped:
+		t.Fatal("expected ProcessJobs to keep running until the context is cancelled")
+	default:
+	}
+	pending, err := repo.CountJobsByStatus(ctx, models.StatusPending)
+	if err != nil {
+		t.Fatalf("failed to count jobs: %v", err)
+	}
+	if pending != 1 {
+		t.Errorf("expected the second job to stay PENDING, got %d pending", pending)
+	}
+
+	cancel()
+	<-stopped
+}
+
+func TestWorkerService_WriteBackoff(t *testing.T) {
+	worker := NewWorkerServiceWithConfig(newMockWorkerRepository(), metrics.NewMetrics(), WorkerConfig{PollInterval: 10 * time.Second})
+
+	unwritable := fmt.Errorf("failed to lease job: %w", repository.ErrWriteUnavailable)
+	for _, want := range []time.Duration{20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		worker.noteWrite(unwritable)
+		if got := worker.pollDelay(); got != want {
+			t.Errorf("expected a poll delay of %s, got %s", want, got)
+		}
+	}
+
+	// Other errors leave the backoff alone, and a successful write ends it
+	worker.noteWrite(errors.New("job not found"))
+	if got := worker.pollDelay(); got != time.Minute {
+		t.Errorf("expected an unrelated error to keep the backoff, got %s", got)
+	}
+	worker.noteWrite(nil)
+	if got := worker.pollDelay(); got != 10*time.Second {
+		t.Errorf("expected the poll interval after a successful write, got %s", got)
+	}
+}
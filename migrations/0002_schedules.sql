@@ -0,0 +1,14 @@
+-- Recurring schedules table
+CREATE TABLE IF NOT EXISTS schedules (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    cron_expr TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    max_retries INTEGER NOT NULL DEFAULT 3,
+    next_fire_at INTEGER NOT NULL,
+    last_fired_at INTEGER,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_next_fire ON schedules(next_fire_at);
@@ -0,0 +1,2 @@
+-- Lets the janitor find old jobs of a status without scanning the table
+CREATE INDEX IF NOT EXISTS idx_jobs_status_updated_at ON jobs(status, updated_at);
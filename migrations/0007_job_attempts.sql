@@ -0,0 +1,10 @@
+-- Failed attempts of jobs still in the jobs table
+CREATE TABLE IF NOT EXISTS job_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_attempts_job_id ON job_attempts(job_id);
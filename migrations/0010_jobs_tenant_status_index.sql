@@ -0,0 +1,2 @@
+-- Lets LeaseJob count a tenant's RUNNING jobs without scanning the table
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant_status ON jobs(tenant_id, status);
@@ -0,0 +1,2 @@
+-- Output of the handler that completed the job
+ALTER TABLE jobs ADD COLUMN result TEXT NOT NULL DEFAULT '';
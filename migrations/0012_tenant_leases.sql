@@ -0,0 +1,8 @@
+-- When each tenant was last served a lease in each queue, for fair scheduling.
+-- lease_seq increases with every fair lease, so the lowest value is the least recently served tenant.
+CREATE TABLE IF NOT EXISTS tenant_leases (
+    queue TEXT NOT NULL,
+    tenant_id TEXT NOT NULL,
+    lease_seq INTEGER NOT NULL,
+    PRIMARY KEY (queue, tenant_id)
+);
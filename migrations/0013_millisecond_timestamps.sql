@@ -0,0 +1,13 @@
+-- Job timestamps move from Unix seconds to Unix milliseconds so jobs created within the
+-- same second keep their order. Schedules and metrics snapshots stay in seconds.
+UPDATE jobs SET
+    created_at = created_at * 1000,
+    updated_at = updated_at * 1000,
+    leased_at = leased_at * 1000,
+    lease_expires_at = lease_expires_at * 1000,
+    started_at = started_at * 1000,
+    finished_at = finished_at * 1000;
+
+UPDATE job_attempts SET at = at * 1000;
+
+UPDATE dead_letter_jobs SET failed_at = failed_at * 1000;
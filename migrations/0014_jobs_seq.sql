@@ -0,0 +1,9 @@
+-- Insertion sequence used to break created_at ties, so jobs are leased and listed in
+-- submission order. Existing jobs are numbered in their current order.
+ALTER TABLE jobs ADD COLUMN seq INTEGER;
+
+UPDATE jobs SET seq = ordered.n
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, rowid) AS n FROM jobs) AS ordered
+WHERE ordered.id = jobs.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_seq ON jobs(seq);
@@ -0,0 +1,40 @@
+-- Idempotency keys may be reused once they are older than the configured window, so the
+-- UNIQUE(tenant_id, idempotency_key) constraint is dropped and duplicates are detected by
+-- the service. SQLite cannot drop a table constraint, so the table is rebuilt.
+CREATE TABLE jobs_new (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    idempotency_key TEXT,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    max_retries INTEGER NOT NULL DEFAULT 3,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    leased_at INTEGER,
+    lease_expires_at INTEGER,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    queue TEXT NOT NULL DEFAULT 'default',
+    started_at INTEGER,
+    finished_at INTEGER,
+    tags TEXT NOT NULL DEFAULT '[]',
+    result TEXT NOT NULL DEFAULT '',
+    seq INTEGER
+);
+
+INSERT INTO jobs_new (id, tenant_id, idempotency_key, payload, status, max_retries, retry_count,
+    leased_at, lease_expires_at, created_at, updated_at, queue, started_at, finished_at, tags, result, seq)
+SELECT id, tenant_id, idempotency_key, payload, status, max_retries, retry_count,
+    leased_at, lease_expires_at, created_at, updated_at, queue, started_at, finished_at, tags, result, seq
+FROM jobs;
+
+DROP TABLE jobs;
+ALTER TABLE jobs_new RENAME TO jobs;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant_id ON jobs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_lease_expires ON jobs(lease_expires_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_queue_status ON jobs(queue, status);
+CREATE INDEX IF NOT EXISTS idx_jobs_status_updated_at ON jobs(status, updated_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant_status ON jobs(tenant_id, status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_seq ON jobs(seq);
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant_idempotency_key ON jobs(tenant_id, idempotency_key, created_at);
@@ -0,0 +1,3 @@
+-- Marks payloads submitted as JSON other than a string, so they are returned as structured JSON
+ALTER TABLE jobs ADD COLUMN payload_json INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE dead_letter_jobs ADD COLUMN payload_json INTEGER NOT NULL DEFAULT 0;
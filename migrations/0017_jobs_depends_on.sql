@@ -0,0 +1,6 @@
+-- depends_on holds the IDs of the jobs that must be DONE before a job can be leased,
+-- as a JSON array. Dead letter entries are looked up by job_id to tell whether a
+-- dependency failed.
+ALTER TABLE jobs ADD COLUMN depends_on TEXT NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_dlq_job_id ON dead_letter_jobs(job_id);
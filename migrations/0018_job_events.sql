@@ -0,0 +1,40 @@
+-- job_events is an append-only audit trail of job status changes. Triggers write it in
+-- the same statement, and so the same transaction, as the change itself; worker_id is
+-- the worker holding the job when it enters or leaves RUNNING.
+ALTER TABLE jobs ADD COLUMN worker_id TEXT;
+
+CREATE TABLE IF NOT EXISTS job_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id TEXT NOT NULL,
+    tenant_id TEXT NOT NULL,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    at INTEGER NOT NULL,
+    worker_id TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_events_job_id ON job_events(job_id, id);
+
+CREATE TRIGGER IF NOT EXISTS job_events_insert AFTER INSERT ON jobs
+BEGIN
+    INSERT INTO job_events (job_id, tenant_id, from_status, to_status, at)
+    VALUES (NEW.id, NEW.tenant_id, NULL, NEW.status, NEW.created_at);
+END;
+
+CREATE TRIGGER IF NOT EXISTS job_events_status_update AFTER UPDATE OF status ON jobs
+WHEN OLD.status != NEW.status
+BEGIN
+    INSERT INTO job_events (job_id, tenant_id, from_status, to_status, at, worker_id)
+    VALUES (NEW.id, NEW.tenant_id, OLD.status, NEW.status, NEW.updated_at,
+            CASE WHEN OLD.status = 'RUNNING' OR NEW.status = 'RUNNING' THEN NEW.worker_id END);
+END;
+
+CREATE TRIGGER IF NOT EXISTS job_events_no_update BEFORE UPDATE ON job_events
+BEGIN
+    SELECT RAISE(ABORT, 'job_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS job_events_no_delete BEFORE DELETE ON job_events
+BEGIN
+    SELECT RAISE(ABORT, 'job_events is append-only');
+END;
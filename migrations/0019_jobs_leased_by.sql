@@ -0,0 +1,4 @@
+-- leased_by names the worker that last leased a job, so stuck leases can be traced to
+-- a worker. It takes over the worker_id column the job_events trigger already reads;
+-- SQLite rewrites the trigger to use the new name.
+ALTER TABLE jobs RENAME COLUMN worker_id TO leased_by;
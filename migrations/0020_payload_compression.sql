@@ -0,0 +1,4 @@
+-- compressed is 1 when payload holds gzip-compressed bytes instead of text. Dead letter
+-- entries copy the stored bytes of the job they were moved from.
+ALTER TABLE jobs ADD COLUMN compressed INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE dead_letter_jobs ADD COLUMN compressed INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- dead_letter_id links a FAILED job kept in jobs to the dead letter entry it was copied
+-- to. It stays NULL unless the repository runs with KeepFailedJobs.
+ALTER TABLE jobs ADD COLUMN dead_letter_id TEXT;
@@ -0,0 +1,3 @@
+-- Serves job listings by status in creation order, so a page continuing from a cursor
+-- reads only the rows it returns.
+CREATE INDEX IF NOT EXISTS idx_jobs_status_created_seq ON jobs(status, created_at, seq);
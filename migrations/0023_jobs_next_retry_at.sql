@@ -0,0 +1,3 @@
+-- next_retry_at holds back a PENDING job a handler asked to run later. Leasing skips
+-- the job until then and clears the column when it picks the job up.
+ALTER TABLE jobs ADD COLUMN next_retry_at INTEGER;
@@ -0,0 +1,6 @@
+-- settings holds operational switches shared by every API server and worker on the
+-- database, such as whether leasing is paused for maintenance.
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
@@ -0,0 +1,4 @@
+-- deleted_at tombstones a job that moved to the dead letter queue but was kept in jobs,
+-- so references to its ID keep resolving. It stays NULL unless the repository runs with
+-- KeepFailedJobs.
+ALTER TABLE jobs ADD COLUMN deleted_at INTEGER;
@@ -0,0 +1,6 @@
+-- expires_at is when a job stops being worth running. Leasing skips expired jobs and
+-- the janitor fails PENDING ones; the partial index keeps its sweep cheap since most
+-- jobs never expire.
+ALTER TABLE jobs ADD COLUMN expires_at INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_expires_at ON jobs(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Records how a payload's text is encoded: utf8 text as given, or base64 for binary
+-- payloads that workers decode before handing them to handlers
+ALTER TABLE jobs ADD COLUMN payload_encoding TEXT NOT NULL DEFAULT 'utf8';
+ALTER TABLE dead_letter_jobs ADD COLUMN payload_encoding TEXT NOT NULL DEFAULT 'utf8';
@@ -0,0 +1,6 @@
+-- dedup_hash identifies a job's content (tenant, queue, payload encoding and payload), so a
+-- submission with dedup_window can find an identical job created within the window. Jobs
+-- created before this migration have none and never match.
+ALTER TABLE jobs ADD COLUMN dedup_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant_dedup_hash ON jobs(tenant_id, dedup_hash, created_at) WHERE dedup_hash IS NOT NULL;
@@ -0,0 +1,3 @@
+-- bypass_limits marks an urgent job submitted with a privileged API key: it is leased even
+-- while its tenant is at its concurrent running limit.
+ALTER TABLE jobs ADD COLUMN bypass_limits INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- metadata holds a job's client-supplied key/value pairs, such as user_id or source, as a
+-- JSON object of strings. Listings filter on it with json_each, so it is not indexed.
+ALTER TABLE jobs ADD COLUMN metadata TEXT NOT NULL DEFAULT '{}';
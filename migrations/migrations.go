@@ -0,0 +1,9 @@
+// Package migrations holds the versioned SQL schema migrations applied on startup
+package migrations
+
+import "embed"
+
+// FS contains the numbered .sql migration files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -50,7 +50,7 @@ function setupJobForm() {
 
             if (!response.ok) {
                 const errorText = await response.text();
-                throw new Error(errorText || `HTTP ${response.status}`);
+                throw new Error(validationMessage(errorText) || errorText || `HTTP ${response.status}`);
             }
 
             const job = await response.json();
@@ -69,6 +69,19 @@ function setupJobForm() {
     });
 }
 
+// Join the messages of a 400 validation response, or return null for any other body
+function validationMessage(body) {
+    try {
+        const parsed = JSON.parse(body);
+        if (parsed && Array.isArray(parsed.errors)) {
+            return parsed.errors.map(e => e.message).join('; ');
+        }
+    } catch (e) {
+        // Not JSON: plain text error
+    }
+    return null;
+}
+
 // Show message
 function showMessage(element, message, type) {
     element.textContent = message;
@@ -105,52 +118,34 @@ async function loadMetrics() {
             console.error('Error loading metrics endpoint:', error);
         }
 
-        // Load current status counts from API (shows current state)
+        // Load current status counts in one call (shows current state)
         const statusCounts = {
             PENDING: 0,
             RUNNING: 0,
             DONE: 0,
             FAILED: 0
         };
-
-        const statusPromises = [
-            { status: 'PENDING', elementId: 'metricPending' },
-            { status: 'RUNNING', elementId: 'metricRunning' },
-            { status: 'DONE', elementId: 'metricCompleted' },
-            { status: 'FAILED', elementId: 'metricFailed' }
-        ].map(async ({ status, elementId }) => {
-            try {
-                const response = await fetch(`${API_BASE}/jobs?status=${status}`);
-                if (response.ok) {
-                    const jobs = await response.json();
-                    const count = Array.isArray(jobs) ? jobs.length : 0;
-                    statusCounts[status] = count;
-                    document.getElementById(elementId).textContent = count;
-                } else {
-                    document.getElementById(elementId).textContent = '0';
-                }
-            } catch (error) {
-                document.getElementById(elementId).textContent = '0';
-            }
-        });
-
-        await Promise.all(statusPromises);
-
-        // Load DLQ count
         let dlqCount = 0;
+
         try {
-            const dlqResponse = await fetch(`${API_BASE}/dlq`);
-            if (dlqResponse.ok) {
-                const dlqJobs = await dlqResponse.json();
-                dlqCount = Array.isArray(dlqJobs) ? dlqJobs.length : 0;
-                document.getElementById('metricDLQ').textContent = dlqCount;
-            } else {
-                document.getElementById('metricDLQ').textContent = '0';
+            const statsResponse = await fetch(`${API_BASE}/stats`);
+            if (statsResponse.ok) {
+                const stats = await statsResponse.json();
+                Object.keys(statusCounts).forEach(status => {
+                    statusCounts[status] = stats[status] || 0;
+                });
+                dlqCount = stats.DLQ || 0;
             }
         } catch (error) {
-            document.getElementById('metricDLQ').textContent = '0';
+            console.error('Error loading stats endpoint:', error);
         }
 
+        document.getElementById('metricPending').textContent = statusCounts.PENDING;
+        document.getElementById('metricRunning').textContent = statusCounts.RUNNING;
+        document.getElementById('metricCompleted').textContent = statusCounts.DONE;
+        document.getElementById('metricFailed').textContent = statusCounts.FAILED;
+        document.getElementById('metricDLQ').textContent = dlqCount;
+
         // If metrics API didn't work, use fallback calculation
         if (totalJobsFromAPI === null) {
             const totalJobs = statusCounts.PENDING + statusCounts.RUNNING + statusCounts.DONE + statusCounts.FAILED + dlqCount;